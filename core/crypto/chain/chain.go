@@ -0,0 +1,161 @@
+// Package chain reads the global configuration of a LIBR network from its
+// LibrRegistry contract (src/contracts/LibrRegistry.sol): the moderator set
+// of every epoch, the relay list and the protocol parameters. Registry reads
+// them through the abigen bindings in librregistry.go, and Watch follows the
+// contract's change events.
+//
+// Regenerate the ABI, the bytecode and the bindings after changing the
+// contract, with solc 0.8.30 and abigen from the go-ethereum version in
+// go.mod:
+//
+//	solc --optimize --optimize-runs 200 --abi --bin --overwrite -o src/contracts src/contracts/LibrRegistry.sol
+//	abigen --abi src/contracts/LibrRegistry.abi --bin src/contracts/LibrRegistry.bin --pkg chain --type LibrRegistry --out core/crypto/chain/librregistry.go
+package chain
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/libr-forum/Libr/core/crypto/modset"
+)
+
+// bpsScale is what the contract's basis point thresholds are out of.
+const bpsScale = 10000
+
+// Params are the protocol parameters the registry holds. Majority is the
+// share of answering moderators that must approve a message, MinApproval
+// the share of the whole moderator set.
+type Params struct {
+	K           int
+	Alpha       int
+	Majority    float64
+	MinApproval float64
+}
+
+// Backend is what Registry needs from a node: contract calls and log
+// filtering, as provided by ethclient.Client and the simulated backend's
+// client.
+type Backend interface {
+	bind.ContractBackend
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Registry reads one LibrRegistry contract.
+type Registry struct {
+	address  common.Address
+	backend  Backend
+	contract *LibrRegistry
+	close    func()
+}
+
+// NewRegistry binds the contract at address on backend.
+func NewRegistry(address common.Address, backend Backend) (*Registry, error) {
+	contract, err := NewLibrRegistry(address, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind registry contract: %w", err)
+	}
+	return &Registry{address: address, backend: backend, contract: contract}, nil
+}
+
+// Dial connects to the JSON-RPC endpoint at url and binds the registry
+// contract at the hex address.
+func Dial(ctx context.Context, url, address string) (*Registry, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid registry address %q", address)
+	}
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	r, err := NewRegistry(common.HexToAddress(address), client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	r.close = client.Close
+	return r, nil
+}
+
+// Close releases the connection Dial opened.
+func (r *Registry) Close() error {
+	if r.close != nil {
+		r.close()
+	}
+	return nil
+}
+
+// Address is the registry contract's address.
+func (r *Registry) Address() common.Address {
+	return r.address
+}
+
+// ModSet reads the moderator set of epoch. Sets read from the chain carry
+// no signatures: the contract only takes them from its governance address,
+// so the chain vouches for them instead of a root key. modset.Registry has
+// no way to take such a vouch yet and rejects them as untrusted, so nothing
+// feeds these sets into moderator checks: db nodes and clients still take
+// their signed sets from the directory.
+func (r *Registry) ModSet(ctx context.Context, epoch uint64) (modset.Set, error) {
+	out, err := r.contract.ModSet(&bind.CallOpts{Context: ctx}, epoch)
+	if err != nil {
+		return modset.Set{}, fmt.Errorf("failed to read moderator set %d: %w", epoch, err)
+	}
+	set := modset.Set{Epoch: epoch, ActivatesAt: int64(out.ActivatesAt)}
+	for _, key := range out.Members {
+		set.Members = append(set.Members, base64.StdEncoding.EncodeToString(key[:]))
+	}
+	return set, nil
+}
+
+// ModSets reads the moderator sets of every epoch, by epoch.
+func (r *Registry) ModSets(ctx context.Context) ([]modset.Set, error) {
+	count, err := r.contract.EpochCount(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read epoch count: %w", err)
+	}
+	sets := make([]modset.Set, 0, count)
+	for epoch := uint64(0); epoch < count; epoch++ {
+		set, err := r.ModSet(ctx, epoch)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// Relays reads the relay multiaddresses, skipping entries that do not look
+// like one.
+func (r *Registry) Relays(ctx context.Context) ([]string, error) {
+	list, err := r.contract.Relays(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relays: %w", err)
+	}
+	var relays []string
+	for _, addr := range list {
+		addr = strings.TrimSpace(addr)
+		if strings.HasPrefix(addr, "/") {
+			relays = append(relays, addr)
+		}
+	}
+	return relays, nil
+}
+
+// Params reads the protocol parameters.
+func (r *Registry) Params(ctx context.Context) (Params, error) {
+	k, alpha, majority, minApproval, err := r.contract.Params(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return Params{}, fmt.Errorf("failed to read params: %w", err)
+	}
+	return Params{
+		K:           int(k),
+		Alpha:       int(alpha),
+		Majority:    float64(majority) / bpsScale,
+		MinApproval: float64(minApproval) / bpsScale,
+	}, nil
+}
@@ -0,0 +1,321 @@
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testChain is a registry deployed on a simulated chain, with governance
+// held by gov.
+type testChain struct {
+	sim      *simulated.Backend
+	registry *Registry
+	gov      *bind.TransactOpts
+	other    *bind.TransactOpts
+}
+
+func transactor(t *testing.T, key *ecdsa.PrivateKey) *bind.TransactOpts {
+	t.Helper()
+	auth, err := bind.NewKeyedTransactorWithChainID(key, params.AllDevChainProtocolChanges.ChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func newTestChain(t *testing.T) *testChain {
+	t.Helper()
+	govKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	gov, other := transactor(t, govKey), transactor(t, otherKey)
+
+	funds := new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))
+	sim := simulated.NewBackend(types.GenesisAlloc{
+		gov.From:   {Balance: funds},
+		other.From: {Balance: funds},
+	})
+	t.Cleanup(func() { sim.Close() })
+
+	address, _, _, err := DeployLibrRegistry(gov, sim.Client(), 20, 3, 5000, 3000)
+	if err != nil {
+		t.Fatalf("deploy failed: %v", err)
+	}
+	sim.Commit()
+
+	registry, err := NewRegistry(address, sim.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testChain{sim: sim, registry: registry, gov: gov, other: other}
+}
+
+// send mines the transaction tx returns and fails the test if it reverted.
+func (tc *testChain) send(t *testing.T, tx *types.Transaction, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.sim.Commit()
+	receipt, err := tc.sim.Client().TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %s reverted", tx.Hash())
+	}
+}
+
+func member(name string) [32]byte {
+	return crypto.Keccak256Hash([]byte(name))
+}
+
+func TestModSets(t *testing.T) {
+	tc := newTestChain(t)
+	ctx := context.Background()
+
+	sets, err := tc.registry.ModSets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 0 {
+		t.Fatalf("new registry lists %d sets", len(sets))
+	}
+
+	epochs := [][][32]byte{
+		{member("mod-a"), member("mod-b"), member("mod-c")},
+		{member("mod-b"), member("mod-d")},
+	}
+	for i, members := range epochs {
+		tx, err := tc.registry.contract.PublishModSet(tc.gov, uint64(1000+i*100), members)
+		tc.send(t, tx, err)
+	}
+
+	sets, err = tc.registry.ModSets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != len(epochs) {
+		t.Fatalf("got %d sets, want %d", len(sets), len(epochs))
+	}
+	for i, set := range sets {
+		if set.Epoch != uint64(i) || set.ActivatesAt != int64(1000+i*100) || len(set.Signatures) != 0 {
+			t.Fatalf("set %d is %+v", i, set)
+		}
+		for j, m := range epochs[i] {
+			if set.Members[j] != base64.StdEncoding.EncodeToString(m[:]) {
+				t.Fatalf("set %d member %d is %s", i, j, set.Members[j])
+			}
+		}
+	}
+	if _, err := tc.registry.ModSet(ctx, 2); err == nil {
+		t.Fatal("read an epoch that was never published")
+	}
+}
+
+func TestPublishModSetRejects(t *testing.T) {
+	tc := newTestChain(t)
+	tx, err := tc.registry.contract.PublishModSet(tc.gov, 1000, [][32]byte{member("mod-a")})
+	tc.send(t, tx, err)
+
+	cases := []struct {
+		name        string
+		from        *bind.TransactOpts
+		activatesAt uint64
+		members     [][32]byte
+	}{
+		{"not governance", tc.other, 2000, [][32]byte{member("mod-a")}},
+		{"empty set", tc.gov, 2000, nil},
+		{"activation before the previous epoch", tc.gov, 1000, [][32]byte{member("mod-a")}},
+	}
+	for _, c := range cases {
+		if _, err := tc.registry.contract.PublishModSet(c.from, c.activatesAt, c.members); err == nil {
+			t.Errorf("%s: publish went through", c.name)
+		}
+	}
+}
+
+func TestRelays(t *testing.T) {
+	tc := newTestChain(t)
+	ctx := context.Background()
+
+	relay := "/dns4/relay.example.org/tcp/443/wss/p2p/12D3KooWExample"
+	tx, err := tc.registry.contract.SetRelays(tc.gov, []string{" " + relay + " ", "not a multiaddress"})
+	tc.send(t, tx, err)
+
+	relays, err := tc.registry.Relays(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relays) != 1 || relays[0] != relay {
+		t.Fatalf("relays are %q, want only %q", relays, relay)
+	}
+
+	// A new list replaces the old one
+	tx, err = tc.registry.contract.SetRelays(tc.gov, nil)
+	tc.send(t, tx, err)
+	if relays, err = tc.registry.Relays(ctx); err != nil || len(relays) != 0 {
+		t.Fatalf("relays after clearing are %q (%v)", relays, err)
+	}
+
+	if _, err := tc.registry.contract.SetRelays(tc.other, []string{relay}); err == nil {
+		t.Fatal("relays set by an address other than governance")
+	}
+}
+
+func TestParams(t *testing.T) {
+	tc := newTestChain(t)
+	ctx := context.Background()
+
+	p, err := tc.registry.Params(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Params{K: 20, Alpha: 3, Majority: 0.5, MinApproval: 0.3}); p != want {
+		t.Fatalf("params are %+v, want %+v", p, want)
+	}
+
+	tx, err := tc.registry.contract.SetParams(tc.gov, 8, 4, 6667, 2500)
+	tc.send(t, tx, err)
+	if p, err = tc.registry.Params(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Params{K: 8, Alpha: 4, Majority: 0.6667, MinApproval: 0.25}); p != want {
+		t.Fatalf("params are %+v, want %+v", p, want)
+	}
+
+	for name, call := range map[string]func() error{
+		"not governance":     func() error { _, err := tc.registry.contract.SetParams(tc.other, 8, 4, 5000, 3000); return err },
+		"zero k":             func() error { _, err := tc.registry.contract.SetParams(tc.gov, 0, 4, 5000, 3000); return err },
+		"threshold past 100": func() error { _, err := tc.registry.contract.SetParams(tc.gov, 8, 4, 10001, 3000); return err },
+	} {
+		if call() == nil {
+			t.Errorf("%s: params changed", name)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tc := newTestChain(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 16)
+	done := make(chan error, 1)
+	go func() { done <- tc.registry.Watch(ctx, 0, 10*time.Millisecond, events) }()
+
+	tx, err := tc.registry.contract.PublishModSet(tc.gov, 1000, [][32]byte{member("mod-a")})
+	tc.send(t, tx, err)
+	tx, err = tc.registry.contract.PublishModSet(tc.gov, 2000, [][32]byte{member("mod-b")})
+	tc.send(t, tx, err)
+	tx, err = tc.registry.contract.SetRelays(tc.gov, []string{"/dns4/relay.example.org/tcp/443"})
+	tc.send(t, tx, err)
+	tx, err = tc.registry.contract.SetParams(tc.gov, 8, 4, 5000, 3000)
+	tc.send(t, tx, err)
+	tx, err = tc.registry.contract.TransferGovernance(tc.gov, tc.other.From)
+	tc.send(t, tx, err)
+
+	want := []Event{
+		{Kind: ParamsUpdated}, // from the constructor
+		{Kind: ModSetPublished, Epoch: 0},
+		{Kind: ModSetPublished, Epoch: 1},
+		{Kind: RelaysUpdated},
+		{Kind: ParamsUpdated},
+		{Kind: GovernanceTransferred},
+	}
+	var last uint64
+	for i, w := range want {
+		select {
+		case got := <-events:
+			if got.Kind != w.Kind || got.Epoch != w.Epoch {
+				t.Fatalf("event %d is %+v, want %+v", i, got, w)
+			}
+			if got.Block <= last {
+				t.Fatalf("event %d is from block %d, not after %d", i, got.Block, last)
+			}
+			last = got.Block
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d (%s)", i, w.Kind)
+		}
+	}
+
+	// The old governance address has no say any more
+	if _, err := tc.registry.contract.SetParams(tc.gov, 8, 4, 5000, 3000); err == nil {
+		t.Fatal("former governance changed the params")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Watch returned %v, want context.Canceled", err)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+// windowBackend records the block span of every log query.
+type windowBackend struct {
+	Backend
+	mu    sync.Mutex
+	spans []uint64
+}
+
+func (b *windowBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	b.mu.Lock()
+	b.spans = append(b.spans, q.ToBlock.Uint64()-q.FromBlock.Uint64()+1)
+	b.mu.Unlock()
+	return b.Backend.FilterLogs(ctx, q)
+}
+
+func TestWatchPagesLogQueries(t *testing.T) {
+	tc := newTestChain(t)
+	for i := 0; i < 6; i++ {
+		tx, err := tc.registry.contract.SetRelays(tc.gov, []string{fmt.Sprintf("/dns4/relay-%d.example.org/tcp/443", i)})
+		tc.send(t, tx, err)
+	}
+
+	prev := filterWindow
+	filterWindow = 2
+	t.Cleanup(func() { filterWindow = prev })
+
+	backend := &windowBackend{Backend: tc.sim.Client()}
+	registry, err := NewRegistry(tc.registry.Address(), backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan Event, 16)
+	next, err := registry.poll(context.Background(), 0, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, _ := tc.sim.Client().BlockNumber(context.Background())
+	if next != head+1 {
+		t.Fatalf("poll stopped at block %d, want %d", next, head+1)
+	}
+	if len(events) != 7 { // ParamsUpdated from the constructor, then the relays
+		t.Fatalf("got %d events, want 7", len(events))
+	}
+	if want := int(head/filterWindow) + 1; len(backend.spans) != want {
+		t.Fatalf("ran %d queries over %d blocks, want %d", len(backend.spans), head+1, want)
+	}
+	for _, span := range backend.spans {
+		if span > filterWindow {
+			t.Fatalf("a query spans %d blocks, more than %d", span, filterWindow)
+		}
+	}
+}
@@ -0,0 +1,1029 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package chain
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// LibrRegistryMetaData contains all meta data concerning the LibrRegistry contract.
+var LibrRegistryMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"uint32\",\"name\":\"k_\",\"type\":\"uint32\"},{\"internalType\":\"uint32\",\"name\":\"alpha_\",\"type\":\"uint32\"},{\"internalType\":\"uint16\",\"name\":\"majorityBps_\",\"type\":\"uint16\"},{\"internalType\":\"uint16\",\"name\":\"minApprovalBps_\",\"type\":\"uint16\"}],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"previous\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"next\",\"type\":\"address\"}],\"name\":\"GovernanceTransferred\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint64\",\"name\":\"epoch\",\"type\":\"uint64\"},{\"indexed\":false,\"internalType\":\"uint64\",\"name\":\"activatesAt\",\"type\":\"uint64\"},{\"indexed\":false,\"internalType\":\"bytes32[]\",\"name\":\"members\",\"type\":\"bytes32[]\"}],\"name\":\"ModSetPublished\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"uint32\",\"name\":\"k\",\"type\":\"uint32\"},{\"indexed\":false,\"internalType\":\"uint32\",\"name\":\"alpha\",\"type\":\"uint32\"},{\"indexed\":false,\"internalType\":\"uint16\",\"name\":\"majorityBps\",\"type\":\"uint16\"},{\"indexed\":false,\"internalType\":\"uint16\",\"name\":\"minApprovalBps\",\"type\":\"uint16\"}],\"name\":\"ParamsUpdated\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"string[]\",\"name\":\"relays\",\"type\":\"string[]\"}],\"name\":\"RelaysUpdated\",\"type\":\"event\"},{\"inputs\":[],\"name\":\"epochCount\",\"outputs\":[{\"internalType\":\"uint64\",\"name\":\"\",\"type\":\"uint64\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"governance\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint64\",\"name\":\"epoch\",\"type\":\"uint64\"}],\"name\":\"modSet\",\"outputs\":[{\"internalType\":\"uint64\",\"name\":\"activatesAt\",\"type\":\"uint64\"},{\"internalType\":\"bytes32[]\",\"name\":\"members\",\"type\":\"bytes32[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"params\",\"outputs\":[{\"internalType\":\"uint32\",\"name\":\"\",\"type\":\"uint32\"},{\"internalType\":\"uint32\",\"name\":\"\",\"type\":\"uint32\"},{\"internalType\":\"uint16\",\"name\":\"\",\"type\":\"uint16\"},{\"internalType\":\"uint16\",\"name\":\"\",\"type\":\"uint16\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint64\",\"name\":\"activatesAt\",\"type\":\"uint64\"},{\"internalType\":\"bytes32[]\",\"name\":\"members\",\"type\":\"bytes32[]\"}],\"name\":\"publishModSet\",\"outputs\":[{\"internalType\":\"uint64\",\"name\":\"epoch\",\"type\":\"uint64\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"relays\",\"outputs\":[{\"internalType\":\"string[]\",\"name\":\"\",\"type\":\"string[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint32\",\"name\":\"k_\",\"type\":\"uint32\"},{\"internalType\":\"uint32\",\"name\":\"alpha_\",\"type\":\"uint32\"},{\"internalType\":\"uint16\",\"name\":\"majorityBps_\",\"type\":\"uint16\"},{\"internalType\":\"uint16\",\"name\":\"minApprovalBps_\",\"type\":\"uint16\"}],\"name\":\"setParams\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string[]\",\"name\":\"relays_\",\"type\":\"string[]\"}],\"name\":\"setRelays\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"next\",\"type\":\"address\"}],\"name\":\"transferGovernance\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]",
+	Bin: "0x608060405234801561000f575f5ffd5b506040516111e93803806111e983398101604081905261002e91610212565b5f80546001600160a01b0319163317905561004b84848484610054565b50505050610263565b5f8463ffffffff1611801561006e57505f8363ffffffff16115b6100bf5760405162461bcd60e51b815260206004820152601d60248201527f4c69627252656769737472793a207a65726f206b206f7220616c70686100000060448201526064015b60405180910390fd5b6127108261ffff16111580156100db57506127108161ffff1611155b6101325760405162461bcd60e51b815260206004820152602260248201527f4c69627252656769737472793a207468726573686f6c642061626f7665203130604482015261302560f01b60648201526084016100b6565b6003805463ffffffff8681166001600160401b031990921682176401000000009187169182021763ffffffff60401b19166801000000000000000061ffff87811691820261ffff60501b1916929092176a0100000000000000000000928716928302179094556040805193845260208401929092529082019290925260608101919091527f536c1d4f2944a651207b3bea9fd165786969806d2834106c0c72d2cb7d15d3d99060800160405180910390a150505050565b805163ffffffff811681146101fc575f5ffd5b919050565b805161ffff811681146101fc575f5ffd5b5f5f5f5f60808587031215610225575f5ffd5b61022e856101e9565b935061023c602086016101e9565b925061024a60408601610201565b915061025860608601610201565b905092959194509250565b610f79806102705f395ff3fe608060405234801561000f575f5ffd5b5060043610610090575f3560e01c80639869861c116100635780639869861c1461010b5780639f8f5cdd14610120578063cff0ab9614610141578063d38bfff414610189578063f2a230be1461019c575f5ffd5b80632af70db4146100945780635aa6e675146100c45780636cbb60c9146100ee578063829965cc14610103575b5f5ffd5b6100a76100a2366004610a11565b6101af565b6040516001600160401b0390911681526020015b60405180910390f35b5f546100d6906001600160a01b031681565b6040516001600160a01b0390911681526020016100bb565b6100f66103af565b6040516100bb9190610a5f565b6001546100a7565b61011e610119366004610ae3565b610483565b005b61013361012e366004610b21565b61054d565b6040516100bb929190610b41565b6003546040805163ffffffff8084168252640100000000840416602082015261ffff600160401b8404811692820192909252600160501b9092041660608201526080016100bb565b61011e610197366004610b97565b610644565b61011e6101aa366004610be1565b61071c565b5f80546001600160a01b031633146101e25760405162461bcd60e51b81526004016101d990610c32565b60405180910390fd5b816102395760405162461bcd60e51b815260206004820152602160248201527f4c69627252656769737472793a20656d707479206d6f64657261746f722073656044820152601d60fa1b60648201526084016101d9565b600154156102e25760018054610250908290610c69565b8154811061026057610260610c8e565b5f9182526020909120600290910201546001600160401b03908116908516116102e25760405162461bcd60e51b815260206004820152602e60248201527f4c69627252656769737472793a2061637469766174696f6e206265666f72652060448201526d0e0e4caecd2deeae640cae0dec6d60931b60648201526084016101d9565b506001805480820182555f919091527fb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf660028202908101805467ffffffffffffffff19166001600160401b03871617815590610361907fb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf70185856108e1565b50816001600160401b03167f774b378d9c9bebb7e816e29fa52dc7f907bac4d7153c85561226c44a9570d1f886868660405161039f93929190610ca2565b60405180910390a2509392505050565b60606002805480602002602001604051908101604052809291908181526020015f905b8282101561047a578382905f5260205f200180546103ef90610cea565b80601f016020809104026020016040519081016040528092919081815260200182805461041b90610cea565b80156104665780601f1061043d57610100808354040283529160200191610466565b820191905f5260205f20905b81548152906001019060200180831161044957829003601f168201915b5050505050815260200190600101906103d2565b50505050905090565b5f546001600160a01b031633146104ac5760405162461bcd60e51b81526004016101d990610c32565b6104b760025f61092a565b5f5b8181101561050f5760028383838181106104d5576104d5610c8e565b90506020028101906104e79190610d22565b82546001810184555f9384526020909320909201916105069183610dc4565b506001016104b9565b507f75bfa6864ce5e5570b0e3091a19bc1166dc7e1b6abe1047c77f7e109556f05378282604051610541929190610ea5565b60405180910390a15050565b6001545f906060906001600160401b038416106105ac5760405162461bcd60e51b815260206004820152601b60248201527f4c69627252656769737472793a20756e6b6e6f776e2065706f6368000000000060448201526064016101d9565b5f6001846001600160401b0316815481106105c9576105c9610c8e565b5f918252602091829020600290910201805460018201805460408051828702810187019091528181529395506001600160401b0390921693909291839183018282801561063357602002820191905f5260205f20905b81548152602001906001019080831161061f575b505050505090509250925050915091565b5f546001600160a01b0316331461066d5760405162461bcd60e51b81526004016101d990610c32565b6001600160a01b0381166106c35760405162461bcd60e51b815260206004820152601a60248201527f4c69627252656769737472793a207a65726f206164647265737300000000000060448201526064016101d9565b5f80546040516001600160a01b03808516939216917f5f56bee8cffbe9a78652a74a60705edede02af10b0bbb888ca44b79a0d42ce8091a35f80546001600160a01b0319166001600160a01b0392909216919091179055565b5f546001600160a01b031633146107455760405162461bcd60e51b81526004016101d990610c32565b61075184848484610757565b50505050565b5f8463ffffffff1611801561077157505f8363ffffffff16115b6107bd5760405162461bcd60e51b815260206004820152601d60248201527f4c69627252656769737472793a207a65726f206b206f7220616c70686100000060448201526064016101d9565b6127108261ffff16111580156107d957506127108161ffff1611155b6108305760405162461bcd60e51b815260206004820152602260248201527f4c69627252656769737472793a207468726573686f6c642061626f7665203130604482015261302560f01b60648201526084016101d9565b6003805463ffffffff86811667ffffffffffffffff199092168217640100000000918716918202176bffffffff00000000000000001916600160401b61ffff87811691820261ffff60501b191692909217600160501b928716928302179094556040805193845260208401929092529082019290925260608101919091527f536c1d4f2944a651207b3bea9fd165786969806d2834106c0c72d2cb7d15d3d99060800160405180910390a150505050565b828054828255905f5260205f2090810192821561091a579160200282015b8281111561091a5782358255916020019190600101906108ff565b50610926929150610948565b5090565b5080545f8255905f5260205f2090810190610945919061095c565b50565b5b80821115610926575f8155600101610949565b80821115610926575f61096f8282610978565b5060010161095c565b50805461098490610cea565b5f825580601f10610993575050565b601f0160209004905f5260205f20908101906109459190610948565b80356001600160401b03811681146109c5575f5ffd5b919050565b5f5f83601f8401126109da575f5ffd5b5081356001600160401b038111156109f0575f5ffd5b6020830191508360208260051b8501011115610a0a575f5ffd5b9250929050565b5f5f5f60408486031215610a23575f5ffd5b610a2c846109af565b925060208401356001600160401b03811115610a46575f5ffd5b610a52868287016109ca565b9497909650939450505050565b5f602082016020835280845180835260408501915060408160051b8601019250602086015f5b82811015610ad757603f19878603018452815180518087528060208301602089015e5f602082890101526020601f19601f83011688010196505050602082019150602084019350600181019050610a85565b50929695505050505050565b5f5f60208385031215610af4575f5ffd5b82356001600160401b03811115610b09575f5ffd5b610b15858286016109ca565b90969095509350505050565b5f60208284031215610b31575f5ffd5b610b3a826109af565b9392505050565b5f604082016001600160401b0385168352604060208401528084518083526060850191506020860192505f5b81811015610b8b578351835260209384019390920191600101610b6d565b50909695505050505050565b5f60208284031215610ba7575f5ffd5b81356001600160a01b0381168114610b3a575f5ffd5b803563ffffffff811681146109c5575f5ffd5b803561ffff811681146109c5575f5ffd5b5f5f5f5f60808587031215610bf4575f5ffd5b610bfd85610bbd565b9350610c0b60208601610bbd565b9250610c1960408601610bd0565b9150610c2760608601610bd0565b905092959194509250565b6020808252601c908201527f4c69627252656769737472793a206e6f7420676f7665726e616e636500000000604082015260600190565b81810381811115610c8857634e487b7160e01b5f52601160045260245ffd5b92915050565b634e487b7160e01b5f52603260045260245ffd5b6001600160401b038416815260406020820181905281018290525f6001600160fb1b03831115610cd0575f5ffd5b8260051b8085606085013791909101606001949350505050565b600181811c90821680610cfe57607f821691505b602082108103610d1c57634e487b7160e01b5f52602260045260245ffd5b50919050565b5f5f8335601e19843603018112610d37575f5ffd5b8301803591506001600160401b03821115610d50575f5ffd5b602001915036819003821315610a0a575f5ffd5b634e487b7160e01b5f52604160045260245ffd5b601f821115610dbf57805f5260205f20601f840160051c81016020851015610d9d5750805b601f840160051c820191505b81811015610dbc575f8155600101610da9565b50505b505050565b6001600160401b03831115610ddb57610ddb610d64565b610def83610de98354610cea565b83610d78565b5f601f841160018114610e20575f8515610e095750838201355b5f19600387901b1c1916600186901b178355610dbc565b5f83815260208120601f198716915b82811015610e4f5786850135825560209485019460019092019101610e2f565b5086821015610e6b575f1960f88860031b161c19848701351681555b505060018560011b0183555050505050565b81835281816020850137505f828201602090810191909152601f909101601f19169091010190565b602080825281018290525f6040600584901b830181019083018583601e1936839003015b87821015610f3657868503603f190184528235818112610ee7575f5ffd5b89016020810190356001600160401b03811115610f02575f5ffd5b803603821315610f10575f5ffd5b610f1b878284610e7d565b96505050602083019250602084019350600182019150610ec9565b509297965050505050505056fea2646970667358221220f47d39c794b42c811f88a01d073c99852f0ded2387dafd2fc98bcb09b96715c464736f6c634300081e0033",
+}
+
+// LibrRegistryABI is the input ABI used to generate the binding from.
+// Deprecated: Use LibrRegistryMetaData.ABI instead.
+var LibrRegistryABI = LibrRegistryMetaData.ABI
+
+// LibrRegistryBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use LibrRegistryMetaData.Bin instead.
+var LibrRegistryBin = LibrRegistryMetaData.Bin
+
+// DeployLibrRegistry deploys a new Ethereum contract, binding an instance of LibrRegistry to it.
+func DeployLibrRegistry(auth *bind.TransactOpts, backend bind.ContractBackend, k_ uint32, alpha_ uint32, majorityBps_ uint16, minApprovalBps_ uint16) (common.Address, *types.Transaction, *LibrRegistry, error) {
+	parsed, err := LibrRegistryMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(LibrRegistryBin), backend, k_, alpha_, majorityBps_, minApprovalBps_)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &LibrRegistry{LibrRegistryCaller: LibrRegistryCaller{contract: contract}, LibrRegistryTransactor: LibrRegistryTransactor{contract: contract}, LibrRegistryFilterer: LibrRegistryFilterer{contract: contract}}, nil
+}
+
+// LibrRegistry is an auto generated Go binding around an Ethereum contract.
+type LibrRegistry struct {
+	LibrRegistryCaller     // Read-only binding to the contract
+	LibrRegistryTransactor // Write-only binding to the contract
+	LibrRegistryFilterer   // Log filterer for contract events
+}
+
+// LibrRegistryCaller is an auto generated read-only Go binding around an Ethereum contract.
+type LibrRegistryCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// LibrRegistryTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type LibrRegistryTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// LibrRegistryFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type LibrRegistryFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// LibrRegistrySession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type LibrRegistrySession struct {
+	Contract     *LibrRegistry     // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// LibrRegistryCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type LibrRegistryCallerSession struct {
+	Contract *LibrRegistryCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts       // Call options to use throughout this session
+}
+
+// LibrRegistryTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type LibrRegistryTransactorSession struct {
+	Contract     *LibrRegistryTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts       // Transaction auth options to use throughout this session
+}
+
+// LibrRegistryRaw is an auto generated low-level Go binding around an Ethereum contract.
+type LibrRegistryRaw struct {
+	Contract *LibrRegistry // Generic contract binding to access the raw methods on
+}
+
+// LibrRegistryCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type LibrRegistryCallerRaw struct {
+	Contract *LibrRegistryCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// LibrRegistryTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type LibrRegistryTransactorRaw struct {
+	Contract *LibrRegistryTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewLibrRegistry creates a new instance of LibrRegistry, bound to a specific deployed contract.
+func NewLibrRegistry(address common.Address, backend bind.ContractBackend) (*LibrRegistry, error) {
+	contract, err := bindLibrRegistry(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistry{LibrRegistryCaller: LibrRegistryCaller{contract: contract}, LibrRegistryTransactor: LibrRegistryTransactor{contract: contract}, LibrRegistryFilterer: LibrRegistryFilterer{contract: contract}}, nil
+}
+
+// NewLibrRegistryCaller creates a new read-only instance of LibrRegistry, bound to a specific deployed contract.
+func NewLibrRegistryCaller(address common.Address, caller bind.ContractCaller) (*LibrRegistryCaller, error) {
+	contract, err := bindLibrRegistry(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryCaller{contract: contract}, nil
+}
+
+// NewLibrRegistryTransactor creates a new write-only instance of LibrRegistry, bound to a specific deployed contract.
+func NewLibrRegistryTransactor(address common.Address, transactor bind.ContractTransactor) (*LibrRegistryTransactor, error) {
+	contract, err := bindLibrRegistry(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryTransactor{contract: contract}, nil
+}
+
+// NewLibrRegistryFilterer creates a new log filterer instance of LibrRegistry, bound to a specific deployed contract.
+func NewLibrRegistryFilterer(address common.Address, filterer bind.ContractFilterer) (*LibrRegistryFilterer, error) {
+	contract, err := bindLibrRegistry(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryFilterer{contract: contract}, nil
+}
+
+// bindLibrRegistry binds a generic wrapper to an already deployed contract.
+func bindLibrRegistry(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := LibrRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_LibrRegistry *LibrRegistryRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _LibrRegistry.Contract.LibrRegistryCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_LibrRegistry *LibrRegistryRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.LibrRegistryTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_LibrRegistry *LibrRegistryRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.LibrRegistryTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_LibrRegistry *LibrRegistryCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _LibrRegistry.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_LibrRegistry *LibrRegistryTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_LibrRegistry *LibrRegistryTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.contract.Transact(opts, method, params...)
+}
+
+// EpochCount is a free data retrieval call binding the contract method 0x829965cc.
+//
+// Solidity: function epochCount() view returns(uint64)
+func (_LibrRegistry *LibrRegistryCaller) EpochCount(opts *bind.CallOpts) (uint64, error) {
+	var out []interface{}
+	err := _LibrRegistry.contract.Call(opts, &out, "epochCount")
+
+	if err != nil {
+		return *new(uint64), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(uint64)).(*uint64)
+
+	return out0, err
+
+}
+
+// EpochCount is a free data retrieval call binding the contract method 0x829965cc.
+//
+// Solidity: function epochCount() view returns(uint64)
+func (_LibrRegistry *LibrRegistrySession) EpochCount() (uint64, error) {
+	return _LibrRegistry.Contract.EpochCount(&_LibrRegistry.CallOpts)
+}
+
+// EpochCount is a free data retrieval call binding the contract method 0x829965cc.
+//
+// Solidity: function epochCount() view returns(uint64)
+func (_LibrRegistry *LibrRegistryCallerSession) EpochCount() (uint64, error) {
+	return _LibrRegistry.Contract.EpochCount(&_LibrRegistry.CallOpts)
+}
+
+// Governance is a free data retrieval call binding the contract method 0x5aa6e675.
+//
+// Solidity: function governance() view returns(address)
+func (_LibrRegistry *LibrRegistryCaller) Governance(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _LibrRegistry.contract.Call(opts, &out, "governance")
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Governance is a free data retrieval call binding the contract method 0x5aa6e675.
+//
+// Solidity: function governance() view returns(address)
+func (_LibrRegistry *LibrRegistrySession) Governance() (common.Address, error) {
+	return _LibrRegistry.Contract.Governance(&_LibrRegistry.CallOpts)
+}
+
+// Governance is a free data retrieval call binding the contract method 0x5aa6e675.
+//
+// Solidity: function governance() view returns(address)
+func (_LibrRegistry *LibrRegistryCallerSession) Governance() (common.Address, error) {
+	return _LibrRegistry.Contract.Governance(&_LibrRegistry.CallOpts)
+}
+
+// ModSet is a free data retrieval call binding the contract method 0x9f8f5cdd.
+//
+// Solidity: function modSet(uint64 epoch) view returns(uint64 activatesAt, bytes32[] members)
+func (_LibrRegistry *LibrRegistryCaller) ModSet(opts *bind.CallOpts, epoch uint64) (struct {
+	ActivatesAt uint64
+	Members     [][32]byte
+}, error) {
+	var out []interface{}
+	err := _LibrRegistry.contract.Call(opts, &out, "modSet", epoch)
+
+	outstruct := new(struct {
+		ActivatesAt uint64
+		Members     [][32]byte
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.ActivatesAt = *abi.ConvertType(out[0], new(uint64)).(*uint64)
+	outstruct.Members = *abi.ConvertType(out[1], new([][32]byte)).(*[][32]byte)
+
+	return *outstruct, err
+
+}
+
+// ModSet is a free data retrieval call binding the contract method 0x9f8f5cdd.
+//
+// Solidity: function modSet(uint64 epoch) view returns(uint64 activatesAt, bytes32[] members)
+func (_LibrRegistry *LibrRegistrySession) ModSet(epoch uint64) (struct {
+	ActivatesAt uint64
+	Members     [][32]byte
+}, error) {
+	return _LibrRegistry.Contract.ModSet(&_LibrRegistry.CallOpts, epoch)
+}
+
+// ModSet is a free data retrieval call binding the contract method 0x9f8f5cdd.
+//
+// Solidity: function modSet(uint64 epoch) view returns(uint64 activatesAt, bytes32[] members)
+func (_LibrRegistry *LibrRegistryCallerSession) ModSet(epoch uint64) (struct {
+	ActivatesAt uint64
+	Members     [][32]byte
+}, error) {
+	return _LibrRegistry.Contract.ModSet(&_LibrRegistry.CallOpts, epoch)
+}
+
+// Params is a free data retrieval call binding the contract method 0xcff0ab96.
+//
+// Solidity: function params() view returns(uint32, uint32, uint16, uint16)
+func (_LibrRegistry *LibrRegistryCaller) Params(opts *bind.CallOpts) (uint32, uint32, uint16, uint16, error) {
+	var out []interface{}
+	err := _LibrRegistry.contract.Call(opts, &out, "params")
+
+	if err != nil {
+		return *new(uint32), *new(uint32), *new(uint16), *new(uint16), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(uint32)).(*uint32)
+	out1 := *abi.ConvertType(out[1], new(uint32)).(*uint32)
+	out2 := *abi.ConvertType(out[2], new(uint16)).(*uint16)
+	out3 := *abi.ConvertType(out[3], new(uint16)).(*uint16)
+
+	return out0, out1, out2, out3, err
+
+}
+
+// Params is a free data retrieval call binding the contract method 0xcff0ab96.
+//
+// Solidity: function params() view returns(uint32, uint32, uint16, uint16)
+func (_LibrRegistry *LibrRegistrySession) Params() (uint32, uint32, uint16, uint16, error) {
+	return _LibrRegistry.Contract.Params(&_LibrRegistry.CallOpts)
+}
+
+// Params is a free data retrieval call binding the contract method 0xcff0ab96.
+//
+// Solidity: function params() view returns(uint32, uint32, uint16, uint16)
+func (_LibrRegistry *LibrRegistryCallerSession) Params() (uint32, uint32, uint16, uint16, error) {
+	return _LibrRegistry.Contract.Params(&_LibrRegistry.CallOpts)
+}
+
+// Relays is a free data retrieval call binding the contract method 0x6cbb60c9.
+//
+// Solidity: function relays() view returns(string[])
+func (_LibrRegistry *LibrRegistryCaller) Relays(opts *bind.CallOpts) ([]string, error) {
+	var out []interface{}
+	err := _LibrRegistry.contract.Call(opts, &out, "relays")
+
+	if err != nil {
+		return *new([]string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([]string)).(*[]string)
+
+	return out0, err
+
+}
+
+// Relays is a free data retrieval call binding the contract method 0x6cbb60c9.
+//
+// Solidity: function relays() view returns(string[])
+func (_LibrRegistry *LibrRegistrySession) Relays() ([]string, error) {
+	return _LibrRegistry.Contract.Relays(&_LibrRegistry.CallOpts)
+}
+
+// Relays is a free data retrieval call binding the contract method 0x6cbb60c9.
+//
+// Solidity: function relays() view returns(string[])
+func (_LibrRegistry *LibrRegistryCallerSession) Relays() ([]string, error) {
+	return _LibrRegistry.Contract.Relays(&_LibrRegistry.CallOpts)
+}
+
+// PublishModSet is a paid mutator transaction binding the contract method 0x2af70db4.
+//
+// Solidity: function publishModSet(uint64 activatesAt, bytes32[] members) returns(uint64 epoch)
+func (_LibrRegistry *LibrRegistryTransactor) PublishModSet(opts *bind.TransactOpts, activatesAt uint64, members [][32]byte) (*types.Transaction, error) {
+	return _LibrRegistry.contract.Transact(opts, "publishModSet", activatesAt, members)
+}
+
+// PublishModSet is a paid mutator transaction binding the contract method 0x2af70db4.
+//
+// Solidity: function publishModSet(uint64 activatesAt, bytes32[] members) returns(uint64 epoch)
+func (_LibrRegistry *LibrRegistrySession) PublishModSet(activatesAt uint64, members [][32]byte) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.PublishModSet(&_LibrRegistry.TransactOpts, activatesAt, members)
+}
+
+// PublishModSet is a paid mutator transaction binding the contract method 0x2af70db4.
+//
+// Solidity: function publishModSet(uint64 activatesAt, bytes32[] members) returns(uint64 epoch)
+func (_LibrRegistry *LibrRegistryTransactorSession) PublishModSet(activatesAt uint64, members [][32]byte) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.PublishModSet(&_LibrRegistry.TransactOpts, activatesAt, members)
+}
+
+// SetParams is a paid mutator transaction binding the contract method 0xf2a230be.
+//
+// Solidity: function setParams(uint32 k_, uint32 alpha_, uint16 majorityBps_, uint16 minApprovalBps_) returns()
+func (_LibrRegistry *LibrRegistryTransactor) SetParams(opts *bind.TransactOpts, k_ uint32, alpha_ uint32, majorityBps_ uint16, minApprovalBps_ uint16) (*types.Transaction, error) {
+	return _LibrRegistry.contract.Transact(opts, "setParams", k_, alpha_, majorityBps_, minApprovalBps_)
+}
+
+// SetParams is a paid mutator transaction binding the contract method 0xf2a230be.
+//
+// Solidity: function setParams(uint32 k_, uint32 alpha_, uint16 majorityBps_, uint16 minApprovalBps_) returns()
+func (_LibrRegistry *LibrRegistrySession) SetParams(k_ uint32, alpha_ uint32, majorityBps_ uint16, minApprovalBps_ uint16) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.SetParams(&_LibrRegistry.TransactOpts, k_, alpha_, majorityBps_, minApprovalBps_)
+}
+
+// SetParams is a paid mutator transaction binding the contract method 0xf2a230be.
+//
+// Solidity: function setParams(uint32 k_, uint32 alpha_, uint16 majorityBps_, uint16 minApprovalBps_) returns()
+func (_LibrRegistry *LibrRegistryTransactorSession) SetParams(k_ uint32, alpha_ uint32, majorityBps_ uint16, minApprovalBps_ uint16) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.SetParams(&_LibrRegistry.TransactOpts, k_, alpha_, majorityBps_, minApprovalBps_)
+}
+
+// SetRelays is a paid mutator transaction binding the contract method 0x9869861c.
+//
+// Solidity: function setRelays(string[] relays_) returns()
+func (_LibrRegistry *LibrRegistryTransactor) SetRelays(opts *bind.TransactOpts, relays_ []string) (*types.Transaction, error) {
+	return _LibrRegistry.contract.Transact(opts, "setRelays", relays_)
+}
+
+// SetRelays is a paid mutator transaction binding the contract method 0x9869861c.
+//
+// Solidity: function setRelays(string[] relays_) returns()
+func (_LibrRegistry *LibrRegistrySession) SetRelays(relays_ []string) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.SetRelays(&_LibrRegistry.TransactOpts, relays_)
+}
+
+// SetRelays is a paid mutator transaction binding the contract method 0x9869861c.
+//
+// Solidity: function setRelays(string[] relays_) returns()
+func (_LibrRegistry *LibrRegistryTransactorSession) SetRelays(relays_ []string) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.SetRelays(&_LibrRegistry.TransactOpts, relays_)
+}
+
+// TransferGovernance is a paid mutator transaction binding the contract method 0xd38bfff4.
+//
+// Solidity: function transferGovernance(address next) returns()
+func (_LibrRegistry *LibrRegistryTransactor) TransferGovernance(opts *bind.TransactOpts, next common.Address) (*types.Transaction, error) {
+	return _LibrRegistry.contract.Transact(opts, "transferGovernance", next)
+}
+
+// TransferGovernance is a paid mutator transaction binding the contract method 0xd38bfff4.
+//
+// Solidity: function transferGovernance(address next) returns()
+func (_LibrRegistry *LibrRegistrySession) TransferGovernance(next common.Address) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.TransferGovernance(&_LibrRegistry.TransactOpts, next)
+}
+
+// TransferGovernance is a paid mutator transaction binding the contract method 0xd38bfff4.
+//
+// Solidity: function transferGovernance(address next) returns()
+func (_LibrRegistry *LibrRegistryTransactorSession) TransferGovernance(next common.Address) (*types.Transaction, error) {
+	return _LibrRegistry.Contract.TransferGovernance(&_LibrRegistry.TransactOpts, next)
+}
+
+// LibrRegistryGovernanceTransferredIterator is returned from FilterGovernanceTransferred and is used to iterate over the raw logs and unpacked data for GovernanceTransferred events raised by the LibrRegistry contract.
+type LibrRegistryGovernanceTransferredIterator struct {
+	Event *LibrRegistryGovernanceTransferred // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *LibrRegistryGovernanceTransferredIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(LibrRegistryGovernanceTransferred)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(LibrRegistryGovernanceTransferred)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *LibrRegistryGovernanceTransferredIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *LibrRegistryGovernanceTransferredIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// LibrRegistryGovernanceTransferred represents a GovernanceTransferred event raised by the LibrRegistry contract.
+type LibrRegistryGovernanceTransferred struct {
+	Previous common.Address
+	Next     common.Address
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterGovernanceTransferred is a free log retrieval operation binding the contract event 0x5f56bee8cffbe9a78652a74a60705edede02af10b0bbb888ca44b79a0d42ce80.
+//
+// Solidity: event GovernanceTransferred(address indexed previous, address indexed next)
+func (_LibrRegistry *LibrRegistryFilterer) FilterGovernanceTransferred(opts *bind.FilterOpts, previous []common.Address, next []common.Address) (*LibrRegistryGovernanceTransferredIterator, error) {
+
+	var previousRule []interface{}
+	for _, previousItem := range previous {
+		previousRule = append(previousRule, previousItem)
+	}
+	var nextRule []interface{}
+	for _, nextItem := range next {
+		nextRule = append(nextRule, nextItem)
+	}
+
+	logs, sub, err := _LibrRegistry.contract.FilterLogs(opts, "GovernanceTransferred", previousRule, nextRule)
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryGovernanceTransferredIterator{contract: _LibrRegistry.contract, event: "GovernanceTransferred", logs: logs, sub: sub}, nil
+}
+
+// WatchGovernanceTransferred is a free log subscription operation binding the contract event 0x5f56bee8cffbe9a78652a74a60705edede02af10b0bbb888ca44b79a0d42ce80.
+//
+// Solidity: event GovernanceTransferred(address indexed previous, address indexed next)
+func (_LibrRegistry *LibrRegistryFilterer) WatchGovernanceTransferred(opts *bind.WatchOpts, sink chan<- *LibrRegistryGovernanceTransferred, previous []common.Address, next []common.Address) (event.Subscription, error) {
+
+	var previousRule []interface{}
+	for _, previousItem := range previous {
+		previousRule = append(previousRule, previousItem)
+	}
+	var nextRule []interface{}
+	for _, nextItem := range next {
+		nextRule = append(nextRule, nextItem)
+	}
+
+	logs, sub, err := _LibrRegistry.contract.WatchLogs(opts, "GovernanceTransferred", previousRule, nextRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(LibrRegistryGovernanceTransferred)
+				if err := _LibrRegistry.contract.UnpackLog(event, "GovernanceTransferred", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseGovernanceTransferred is a log parse operation binding the contract event 0x5f56bee8cffbe9a78652a74a60705edede02af10b0bbb888ca44b79a0d42ce80.
+//
+// Solidity: event GovernanceTransferred(address indexed previous, address indexed next)
+func (_LibrRegistry *LibrRegistryFilterer) ParseGovernanceTransferred(log types.Log) (*LibrRegistryGovernanceTransferred, error) {
+	event := new(LibrRegistryGovernanceTransferred)
+	if err := _LibrRegistry.contract.UnpackLog(event, "GovernanceTransferred", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// LibrRegistryModSetPublishedIterator is returned from FilterModSetPublished and is used to iterate over the raw logs and unpacked data for ModSetPublished events raised by the LibrRegistry contract.
+type LibrRegistryModSetPublishedIterator struct {
+	Event *LibrRegistryModSetPublished // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *LibrRegistryModSetPublishedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(LibrRegistryModSetPublished)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(LibrRegistryModSetPublished)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *LibrRegistryModSetPublishedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *LibrRegistryModSetPublishedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// LibrRegistryModSetPublished represents a ModSetPublished event raised by the LibrRegistry contract.
+type LibrRegistryModSetPublished struct {
+	Epoch       uint64
+	ActivatesAt uint64
+	Members     [][32]byte
+	Raw         types.Log // Blockchain specific contextual infos
+}
+
+// FilterModSetPublished is a free log retrieval operation binding the contract event 0x774b378d9c9bebb7e816e29fa52dc7f907bac4d7153c85561226c44a9570d1f8.
+//
+// Solidity: event ModSetPublished(uint64 indexed epoch, uint64 activatesAt, bytes32[] members)
+func (_LibrRegistry *LibrRegistryFilterer) FilterModSetPublished(opts *bind.FilterOpts, epoch []uint64) (*LibrRegistryModSetPublishedIterator, error) {
+
+	var epochRule []interface{}
+	for _, epochItem := range epoch {
+		epochRule = append(epochRule, epochItem)
+	}
+
+	logs, sub, err := _LibrRegistry.contract.FilterLogs(opts, "ModSetPublished", epochRule)
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryModSetPublishedIterator{contract: _LibrRegistry.contract, event: "ModSetPublished", logs: logs, sub: sub}, nil
+}
+
+// WatchModSetPublished is a free log subscription operation binding the contract event 0x774b378d9c9bebb7e816e29fa52dc7f907bac4d7153c85561226c44a9570d1f8.
+//
+// Solidity: event ModSetPublished(uint64 indexed epoch, uint64 activatesAt, bytes32[] members)
+func (_LibrRegistry *LibrRegistryFilterer) WatchModSetPublished(opts *bind.WatchOpts, sink chan<- *LibrRegistryModSetPublished, epoch []uint64) (event.Subscription, error) {
+
+	var epochRule []interface{}
+	for _, epochItem := range epoch {
+		epochRule = append(epochRule, epochItem)
+	}
+
+	logs, sub, err := _LibrRegistry.contract.WatchLogs(opts, "ModSetPublished", epochRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(LibrRegistryModSetPublished)
+				if err := _LibrRegistry.contract.UnpackLog(event, "ModSetPublished", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseModSetPublished is a log parse operation binding the contract event 0x774b378d9c9bebb7e816e29fa52dc7f907bac4d7153c85561226c44a9570d1f8.
+//
+// Solidity: event ModSetPublished(uint64 indexed epoch, uint64 activatesAt, bytes32[] members)
+func (_LibrRegistry *LibrRegistryFilterer) ParseModSetPublished(log types.Log) (*LibrRegistryModSetPublished, error) {
+	event := new(LibrRegistryModSetPublished)
+	if err := _LibrRegistry.contract.UnpackLog(event, "ModSetPublished", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// LibrRegistryParamsUpdatedIterator is returned from FilterParamsUpdated and is used to iterate over the raw logs and unpacked data for ParamsUpdated events raised by the LibrRegistry contract.
+type LibrRegistryParamsUpdatedIterator struct {
+	Event *LibrRegistryParamsUpdated // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *LibrRegistryParamsUpdatedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(LibrRegistryParamsUpdated)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(LibrRegistryParamsUpdated)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *LibrRegistryParamsUpdatedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *LibrRegistryParamsUpdatedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// LibrRegistryParamsUpdated represents a ParamsUpdated event raised by the LibrRegistry contract.
+type LibrRegistryParamsUpdated struct {
+	K              uint32
+	Alpha          uint32
+	MajorityBps    uint16
+	MinApprovalBps uint16
+	Raw            types.Log // Blockchain specific contextual infos
+}
+
+// FilterParamsUpdated is a free log retrieval operation binding the contract event 0x536c1d4f2944a651207b3bea9fd165786969806d2834106c0c72d2cb7d15d3d9.
+//
+// Solidity: event ParamsUpdated(uint32 k, uint32 alpha, uint16 majorityBps, uint16 minApprovalBps)
+func (_LibrRegistry *LibrRegistryFilterer) FilterParamsUpdated(opts *bind.FilterOpts) (*LibrRegistryParamsUpdatedIterator, error) {
+
+	logs, sub, err := _LibrRegistry.contract.FilterLogs(opts, "ParamsUpdated")
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryParamsUpdatedIterator{contract: _LibrRegistry.contract, event: "ParamsUpdated", logs: logs, sub: sub}, nil
+}
+
+// WatchParamsUpdated is a free log subscription operation binding the contract event 0x536c1d4f2944a651207b3bea9fd165786969806d2834106c0c72d2cb7d15d3d9.
+//
+// Solidity: event ParamsUpdated(uint32 k, uint32 alpha, uint16 majorityBps, uint16 minApprovalBps)
+func (_LibrRegistry *LibrRegistryFilterer) WatchParamsUpdated(opts *bind.WatchOpts, sink chan<- *LibrRegistryParamsUpdated) (event.Subscription, error) {
+
+	logs, sub, err := _LibrRegistry.contract.WatchLogs(opts, "ParamsUpdated")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(LibrRegistryParamsUpdated)
+				if err := _LibrRegistry.contract.UnpackLog(event, "ParamsUpdated", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseParamsUpdated is a log parse operation binding the contract event 0x536c1d4f2944a651207b3bea9fd165786969806d2834106c0c72d2cb7d15d3d9.
+//
+// Solidity: event ParamsUpdated(uint32 k, uint32 alpha, uint16 majorityBps, uint16 minApprovalBps)
+func (_LibrRegistry *LibrRegistryFilterer) ParseParamsUpdated(log types.Log) (*LibrRegistryParamsUpdated, error) {
+	event := new(LibrRegistryParamsUpdated)
+	if err := _LibrRegistry.contract.UnpackLog(event, "ParamsUpdated", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// LibrRegistryRelaysUpdatedIterator is returned from FilterRelaysUpdated and is used to iterate over the raw logs and unpacked data for RelaysUpdated events raised by the LibrRegistry contract.
+type LibrRegistryRelaysUpdatedIterator struct {
+	Event *LibrRegistryRelaysUpdated // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *LibrRegistryRelaysUpdatedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(LibrRegistryRelaysUpdated)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(LibrRegistryRelaysUpdated)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *LibrRegistryRelaysUpdatedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *LibrRegistryRelaysUpdatedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// LibrRegistryRelaysUpdated represents a RelaysUpdated event raised by the LibrRegistry contract.
+type LibrRegistryRelaysUpdated struct {
+	Relays []string
+	Raw    types.Log // Blockchain specific contextual infos
+}
+
+// FilterRelaysUpdated is a free log retrieval operation binding the contract event 0x75bfa6864ce5e5570b0e3091a19bc1166dc7e1b6abe1047c77f7e109556f0537.
+//
+// Solidity: event RelaysUpdated(string[] relays)
+func (_LibrRegistry *LibrRegistryFilterer) FilterRelaysUpdated(opts *bind.FilterOpts) (*LibrRegistryRelaysUpdatedIterator, error) {
+
+	logs, sub, err := _LibrRegistry.contract.FilterLogs(opts, "RelaysUpdated")
+	if err != nil {
+		return nil, err
+	}
+	return &LibrRegistryRelaysUpdatedIterator{contract: _LibrRegistry.contract, event: "RelaysUpdated", logs: logs, sub: sub}, nil
+}
+
+// WatchRelaysUpdated is a free log subscription operation binding the contract event 0x75bfa6864ce5e5570b0e3091a19bc1166dc7e1b6abe1047c77f7e109556f0537.
+//
+// Solidity: event RelaysUpdated(string[] relays)
+func (_LibrRegistry *LibrRegistryFilterer) WatchRelaysUpdated(opts *bind.WatchOpts, sink chan<- *LibrRegistryRelaysUpdated) (event.Subscription, error) {
+
+	logs, sub, err := _LibrRegistry.contract.WatchLogs(opts, "RelaysUpdated")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(LibrRegistryRelaysUpdated)
+				if err := _LibrRegistry.contract.UnpackLog(event, "RelaysUpdated", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseRelaysUpdated is a log parse operation binding the contract event 0x75bfa6864ce5e5570b0e3091a19bc1166dc7e1b6abe1047c77f7e109556f0537.
+//
+// Solidity: event RelaysUpdated(string[] relays)
+func (_LibrRegistry *LibrRegistryFilterer) ParseRelaysUpdated(log types.Log) (*LibrRegistryRelaysUpdated, error) {
+	event := new(LibrRegistryRelaysUpdated)
+	if err := _LibrRegistry.contract.UnpackLog(event, "RelaysUpdated", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
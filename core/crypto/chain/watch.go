@@ -0,0 +1,130 @@
+package chain
+
+import (
+	"context"
+	"log"
+	"math/big"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventKind is which part of the registry an Event changed.
+type EventKind string
+
+const (
+	ModSetPublished       EventKind = "ModSetPublished"
+	RelaysUpdated         EventKind = "RelaysUpdated"
+	ParamsUpdated         EventKind = "ParamsUpdated"
+	GovernanceTransferred EventKind = "GovernanceTransferred"
+)
+
+// Event is a change to the registry. Epoch is set for ModSetPublished only.
+// Watchers read the new state through Registry rather than from the event.
+type Event struct {
+	Kind  EventKind
+	Block uint64
+	Epoch uint64
+}
+
+var registryABI, _ = LibrRegistryMetaData.GetAbi()
+
+// filterWindow is how many blocks one eth_getLogs query spans. Public
+// endpoints refuse or truncate queries over a few thousand blocks, and a
+// watcher catching up from an old block would otherwise ask for all of them
+// at once.
+var filterWindow uint64 = 2000
+
+// eventKinds maps the topic of every registry event to its kind.
+var eventKinds = func() map[common.Hash]EventKind {
+	kinds := make(map[common.Hash]EventKind)
+	for _, kind := range []EventKind{ModSetPublished, RelaysUpdated, ParamsUpdated, GovernanceTransferred} {
+		kinds[registryABI.Events[string(kind)].ID] = kind
+	}
+	return kinds
+}()
+
+// Watch polls the registry every interval for events in blocks from `from`
+// on and sends them to events in chain order, until ctx is done. Polling
+// works over plain HTTP endpoints, unlike the bindings' Watch* subscriptions.
+// Failed polls are logged and retried on the next tick.
+func (r *Registry) Watch(ctx context.Context, from uint64, interval time.Duration, events chan<- Event) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	next := from
+	for {
+		var err error
+		next, err = r.poll(ctx, next, events)
+		if err != nil {
+			log.Printf("⚠️ Failed to poll registry events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll sends the events of blocks next up to the head, filterWindow blocks
+// per query, and returns the first block it has not covered. A failed query
+// keeps the windows sent before it.
+func (r *Registry) poll(ctx context.Context, next uint64, events chan<- Event) (uint64, error) {
+	head, err := r.backend.BlockNumber(ctx)
+	if err != nil {
+		return next, err
+	}
+
+	for next <= head {
+		to := min(head, next+filterWindow-1)
+		logs, err := r.backend.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(next),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{r.address},
+		})
+		if err != nil {
+			return next, err
+		}
+
+		for _, l := range logs {
+			ev, ok := r.toEvent(l)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				// Resume from this block; its events may be sent again
+				return l.BlockNumber, ctx.Err()
+			}
+		}
+		next = to + 1
+	}
+	return next, nil
+}
+
+// toEvent reads the kind, and for new moderator sets the epoch, of a
+// registry log. Removed logs belong to blocks reorganised away.
+func (r *Registry) toEvent(l types.Log) (Event, bool) {
+	if l.Removed || len(l.Topics) == 0 {
+		return Event{}, false
+	}
+	kind, ok := eventKinds[l.Topics[0]]
+	if !ok {
+		return Event{}, false
+	}
+
+	ev := Event{Kind: kind, Block: l.BlockNumber}
+	if kind == ModSetPublished {
+		published, err := r.contract.ParseModSetPublished(l)
+		if err != nil {
+			return Event{}, false
+		}
+		ev.Epoch = published.Epoch
+	}
+	return ev, true
+}
@@ -0,0 +1,86 @@
+// Package directory is where db nodes and clients find each other before the
+// DHT is up: the bootstrap db nodes, the moderators, the signed moderator
+// sets and the relays. Directory
+// is implemented by a static file (StaticDirectory), MongoDB (MongoDirectory)
+// and an HTTP endpoint (HTTPDirectory); Open picks one from a URI.
+package directory
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/libr-forum/Libr/core/crypto/modset"
+)
+
+// ErrNotConfigured is returned by Open for an empty URI. There is no built-in
+// default: every network names its own directory.
+var ErrNotConfigured = errors.New("no directory configured: set LIBR_DIRECTORY to a mongodb:// URI, an http(s):// directory server or a directory file")
+
+// NodeEntry is a db node's presence record as listed in a directory. NodeID
+// is the base64 SHA-256 of the base64 PublicKey, Expires a unix time. A
+// Withdrawn record is a node's signed notice that it left. Readers skip
+// entries that fail Verify.
+type NodeEntry struct {
+	NodeID    string `json:"node_id" yaml:"node_id" bson:"node_id"`
+	PeerID    string `json:"peer_id" yaml:"peer_id" bson:"peer_id"`
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty" bson:"public_key"`
+	Version   int    `json:"version,omitempty" yaml:"version,omitempty" bson:"version"`
+	Expires   int64  `json:"expires,omitempty" yaml:"expires,omitempty" bson:"expires"`
+	Withdrawn bool   `json:"withdrawn,omitempty" yaml:"withdrawn,omitempty" bson:"withdrawn,omitempty"`
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty" bson:"signature"`
+}
+
+// ModEntry is where a moderator can be reached. Older entries carry IP and
+// Port instead of PeerID. Being listed does not make a key a moderator; the
+// signed sets from ModSets do.
+type ModEntry struct {
+	PeerID    string `json:"peer_id,omitempty" yaml:"peer_id,omitempty" bson:"peer_id"`
+	PublicKey string `json:"public_key" yaml:"public_key" bson:"public_key"`
+	IP        string `json:"ip,omitempty" yaml:"ip,omitempty" bson:"ip"`
+	Port      string `json:"port,omitempty" yaml:"port,omitempty" bson:"port"`
+}
+
+// Directory lists the bootstrap nodes, moderators, moderator sets and relay
+// multiaddresses of a network. Publish adds or replaces the presence record
+// of a node, matched by node ID, and returns ErrStale unless the record
+// expires strictly later than the one it replaces. Moderator sets are returned as stored;
+// readers verify them with a modset.Registry.
+type Directory interface {
+	Nodes(ctx context.Context) ([]NodeEntry, error)
+	Publish(ctx context.Context, e NodeEntry) error
+	Mods(ctx context.Context) ([]ModEntry, error)
+	ModSets(ctx context.Context) ([]modset.Set, error)
+	Relays(ctx context.Context) ([]string, error)
+	Close() error
+}
+
+// Open returns the directory uri points at:
+//
+//	mongodb://… or mongodb+srv://…   MongoDirectory
+//	http://… or https://…            HTTPDirectory
+//	file:///path or a plain path     StaticDirectory (YAML or JSON)
+func Open(ctx context.Context, uri string) (Directory, error) {
+	switch {
+	case uri == "":
+		return nil, ErrNotConfigured
+	case strings.HasPrefix(uri, "mongodb://"), strings.HasPrefix(uri, "mongodb+srv://"):
+		return NewMongoDirectory(ctx, uri)
+	case strings.HasPrefix(uri, "http://"), strings.HasPrefix(uri, "https://"):
+		return NewHTTPDirectory(uri), nil
+	default:
+		return NewStaticDirectory(strings.TrimPrefix(uri, "file://"))
+	}
+}
+
+// cleanRelays keeps the entries that look like multiaddresses.
+func cleanRelays(addrs []string) []string {
+	var relays []string
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		if strings.HasPrefix(addr, "/") {
+			relays = append(relays, addr)
+		}
+	}
+	return relays
+}
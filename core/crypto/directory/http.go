@@ -0,0 +1,157 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/modset"
+)
+
+// HTTPDirectory reads the directory from GET <base>/nodes, /mods, /modsets
+// and /relays, each answering a JSON array, and publishes presence records
+// with POST <base>/nodes. Handler serves that layout, so any Directory,
+// usually a static file, can stand in for the shared one on a local network.
+type HTTPDirectory struct {
+	base   string
+	client *http.Client
+}
+
+func NewHTTPDirectory(base string) *HTTPDirectory {
+	return &HTTPDirectory{
+		base:   strings.TrimRight(base, "/"),
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (d *HTTPDirectory) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func (d *HTTPDirectory) Nodes(ctx context.Context) ([]NodeEntry, error) {
+	var nodes []NodeEntry
+	if err := d.get(ctx, "/nodes", &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (d *HTTPDirectory) Publish(ctx context.Context, e NodeEntry) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.base+"/nodes", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish presence: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to publish presence: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (d *HTTPDirectory) Mods(ctx context.Context) ([]ModEntry, error) {
+	var mods []ModEntry
+	if err := d.get(ctx, "/mods", &mods); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
+
+func (d *HTTPDirectory) ModSets(ctx context.Context) ([]modset.Set, error) {
+	var sets []modset.Set
+	if err := d.get(ctx, "/modsets", &sets); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (d *HTTPDirectory) Relays(ctx context.Context) ([]string, error) {
+	var relays []string
+	if err := d.get(ctx, "/relays", &relays); err != nil {
+		return nil, err
+	}
+	return cleanRelays(relays), nil
+}
+
+func (d *HTTPDirectory) Close() error { return nil }
+
+// Handler serves dir in the layout HTTPDirectory reads.
+func Handler(dir Directory) http.Handler {
+	mux := http.NewServeMux()
+	serve := func(path string, list func(context.Context) (any, error)) {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			v, err := list(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(v)
+		})
+	}
+	mux.HandleFunc("POST /nodes", func(w http.ResponseWriter, r *http.Request) {
+		var e NodeEntry
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&e); err != nil {
+			http.Error(w, "invalid presence record", http.StatusBadRequest)
+			return
+		}
+		// Only take records a reader would accept, or live withdrawals
+		if err := e.Verify(time.Now()); err != nil && !errors.Is(err, ErrWithdrawn) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := dir.Publish(r.Context(), e); errors.Is(err, ErrStale) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve("/nodes", func(ctx context.Context) (any, error) { return nonNil(dir.Nodes(ctx)) })
+	serve("/mods", func(ctx context.Context) (any, error) { return nonNil(dir.Mods(ctx)) })
+	serve("/modsets", func(ctx context.Context) (any, error) { return nonNil(dir.ModSets(ctx)) })
+	serve("/relays", func(ctx context.Context) (any, error) { return nonNil(dir.Relays(ctx)) })
+	return mux
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil[T any](list []T, err error) (any, error) {
+	if list == nil {
+		list = []T{}
+	}
+	return list, err
+}
@@ -0,0 +1,116 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStaticDirectory(t *testing.T) *StaticDirectory {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "directory.yaml")
+	if err := os.WriteFile(path, []byte("nodes: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewStaticDirectory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestStaticPublishKeepsNewest(t *testing.T) {
+	d := newStaticDirectory(t)
+	priv := newKey(t)
+	older, err := SignPresence(priv, "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := SignPresence(priv, "peer-a", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := d.Publish(ctx, newer); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []NodeEntry{older, newer} {
+		if err := d.Publish(ctx, e); !errors.Is(err, ErrStale) {
+			t.Fatalf("record expiring at %d over one expiring at %d: got %v, want ErrStale", e.Expires, newer.Expires, err)
+		}
+	}
+	nodes, err := d.Nodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0] != newer {
+		t.Fatalf("directory holds %+v, want the newer record", nodes)
+	}
+}
+
+func TestHandlerPublish(t *testing.T) {
+	d := newStaticDirectory(t)
+	srv := httptest.NewServer(Handler(d))
+	defer srv.Close()
+
+	post := func(e NodeEntry) int {
+		t.Helper()
+		body, _ := json.Marshal(e)
+		resp, err := http.Post(srv.URL+"/nodes", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	priv := newKey(t)
+	live, err := SignPresence(priv, "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := SignPresence(priv, "peer-a", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone, err := SignWithdrawal(priv, "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := live
+	forged.Expires += 3600
+
+	steps := []struct {
+		name string
+		rec  NodeEntry
+		want int
+	}{
+		{"live record", live, http.StatusNoContent},
+		{"same record again", live, http.StatusConflict},
+		{"expired record", expired, http.StatusBadRequest},
+		{"record with a forged expiry", forged, http.StatusBadRequest},
+		{"withdrawal", gone, http.StatusNoContent},
+		{"live record after the withdrawal", live, http.StatusConflict},
+	}
+	for _, s := range steps {
+		if got := post(s.rec); got != s.want {
+			t.Fatalf("%s: status %d, want %d", s.name, got, s.want)
+		}
+	}
+
+	nodes, err := NewHTTPDirectory(srv.URL).Nodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || !errors.Is(nodes[0].Verify(time.Now()), ErrWithdrawn) {
+		t.Fatalf("directory lists %+v, want only the withdrawal", nodes)
+	}
+}
@@ -0,0 +1,131 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/modset"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoDatabase holds the nodes, mods, mod_sets and relays collections.
+const mongoDatabase = "Addrs"
+
+// MongoDirectory reads the directory from the nodes, mods, mod_sets and
+// relays collections of a MongoDB database.
+type MongoDirectory struct {
+	client *mongo.Client
+}
+
+// NewMongoDirectory connects to uri and pings it.
+func NewMongoDirectory(ctx context.Context, uri string) (*MongoDirectory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+	return &MongoDirectory{client: client}, nil
+}
+
+// findAll decodes every document of collection into out.
+func (d *MongoDirectory) findAll(ctx context.Context, collection string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := d.client.Database(mongoDatabase).Collection(collection).Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", collection, err)
+	}
+	if err := cursor.All(ctx, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", collection, err)
+	}
+	return nil
+}
+
+func (d *MongoDirectory) Nodes(ctx context.Context) ([]NodeEntry, error) {
+	var nodes []NodeEntry
+	if err := d.findAll(ctx, "nodes", &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (d *MongoDirectory) Publish(ctx context.Context, e NodeEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	nodes := d.client.Database(mongoDatabase).Collection("nodes")
+	var stored NodeEntry
+	err := nodes.FindOne(ctx, bson.M{"node_id": e.NodeID}).Decode(&stored)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		if _, err := nodes.InsertOne(ctx, e); err != nil {
+			return fmt.Errorf("failed to publish presence: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to read stored presence: %w", err)
+	case e.Expires <= stored.Expires:
+		return ErrStale
+	default:
+		// Only replace the record compared against above, so a concurrent
+		// publish is never overwritten unchecked
+		res, err := nodes.ReplaceOne(ctx, bson.M{"node_id": e.NodeID, "expires": stored.Expires}, e)
+		if err != nil {
+			return fmt.Errorf("failed to publish presence: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return ErrStale
+		}
+	}
+
+	// Expired records are skipped by readers anyway; keep the collection small
+	_, err = nodes.DeleteMany(ctx, bson.M{"expires": bson.M{"$gt": 0, "$lt": time.Now().Unix()}})
+	if err != nil {
+		return fmt.Errorf("failed to prune expired presence records: %w", err)
+	}
+	return nil
+}
+
+func (d *MongoDirectory) Mods(ctx context.Context) ([]ModEntry, error) {
+	var mods []ModEntry
+	if err := d.findAll(ctx, "mods", &mods); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
+
+func (d *MongoDirectory) ModSets(ctx context.Context) ([]modset.Set, error) {
+	var sets []modset.Set
+	if err := d.findAll(ctx, "mod_sets", &sets); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (d *MongoDirectory) Relays(ctx context.Context) ([]string, error) {
+	var docs []struct {
+		Address string `bson:"address"`
+	}
+	if err := d.findAll(ctx, "relays", &docs); err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		addrs = append(addrs, doc.Address)
+	}
+	return cleanRelays(addrs), nil
+}
+
+func (d *MongoDirectory) Close() error {
+	return d.client.Disconnect(context.Background())
+}
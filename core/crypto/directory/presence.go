@@ -0,0 +1,100 @@
+package directory
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/framing"
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+)
+
+// A NodeEntry is a presence record: db nodes sign their own entry, with an
+// expiry, and republish it on a heartbeat. Readers only use entries that
+// Verify, so nodes that stop publishing drop out of the directory by
+// themselves. Expires also orders a node's records: a directory only takes
+// one that expires later than the record it holds, so a captured older
+// record cannot be played back over a newer one. A node leaving publishes a
+// withdrawal, which is a record like any other and so also has to expire
+// later.
+
+var (
+	ErrUnsigned  = errors.New("presence record is not signed")
+	ErrExpired   = errors.New("presence record expired")
+	ErrWithdrawn = errors.New("node withdrew its presence")
+	ErrBadRecord = errors.New("presence record does not verify")
+	ErrStale     = errors.New("presence record does not expire later than the stored one")
+)
+
+func (e *NodeEntry) presenceMessage() string {
+	msg := "libr-presence|" + e.NodeID + "|" + e.PeerID + "|" + e.PublicKey + "|" +
+		strconv.Itoa(e.Version) + "|" + strconv.FormatInt(e.Expires, 10)
+	if e.Withdrawn {
+		msg += "|withdrawn"
+	}
+	return msg
+}
+
+// SignPresence returns the presence record of the node holding priv,
+// reachable as peerID, valid for ttl from now.
+func SignPresence(priv ed25519.PrivateKey, peerID string, ttl time.Duration) (NodeEntry, error) {
+	return signPresence(priv, peerID, time.Now().Add(ttl), false)
+}
+
+// SignWithdrawal returns the record that takes the node holding priv out of
+// the directory. It stands for ttl, which should be at least the TTL of the
+// node's presence records, and a second past it, so it outlasts a presence
+// record signed in the same second.
+func SignWithdrawal(priv ed25519.PrivateKey, peerID string, ttl time.Duration) (NodeEntry, error) {
+	return signPresence(priv, peerID, time.Now().Add(ttl+time.Second), true)
+}
+
+func signPresence(priv ed25519.PrivateKey, peerID string, expires time.Time, withdrawn bool) (NodeEntry, error) {
+	pubKeyB64 := base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+	nodeID := keyspace.Sum(pubKeyB64)
+
+	e := NodeEntry{
+		NodeID:    base64.StdEncoding.EncodeToString(nodeID[:]),
+		PeerID:    peerID,
+		PublicKey: pubKeyB64,
+		Version:   framing.ProtocolVersion,
+		Expires:   expires.Unix(),
+		Withdrawn: withdrawn,
+	}
+	_, sign, err := cryptoutils.SignMessage(priv, e.presenceMessage())
+	if err != nil {
+		return NodeEntry{}, err
+	}
+	e.Signature = sign
+	return e, nil
+}
+
+// Verify checks that e is signed by the key its node ID is the hash of, is
+// for this protocol version, has not expired at now and is not a
+// withdrawal.
+func (e *NodeEntry) Verify(now time.Time) error {
+	if e.Signature == "" || e.PublicKey == "" {
+		return ErrUnsigned
+	}
+	nodeID := keyspace.Sum(e.PublicKey)
+	if e.NodeID != base64.StdEncoding.EncodeToString(nodeID[:]) {
+		return fmt.Errorf("%w: node ID is not the hash of the public key", ErrBadRecord)
+	}
+	if !cryptoutils.VerifySignature(e.PublicKey, e.presenceMessage(), e.Signature) {
+		return fmt.Errorf("%w: bad signature", ErrBadRecord)
+	}
+	if e.Version != framing.ProtocolVersion {
+		return fmt.Errorf("%w: protocol version %d", ErrBadRecord, e.Version)
+	}
+	if now.Unix() >= e.Expires {
+		return ErrExpired
+	}
+	if e.Withdrawn {
+		return ErrWithdrawn
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package directory
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestPresenceVerifies(t *testing.T) {
+	rec, err := SignPresence(newKey(t), "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Verify(time.Now()); err != nil {
+		t.Fatalf("fresh record rejected: %v", err)
+	}
+}
+
+func TestPresenceRejectsTampering(t *testing.T) {
+	rec, err := SignPresence(newKey(t), "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := SignPresence(newKey(t), "peer-b", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]func(e *NodeEntry){
+		"peer ID":      func(e *NodeEntry) { e.PeerID = "peer-mallory" },
+		"expiry":       func(e *NodeEntry) { e.Expires += 3600 },
+		"withdrawal":   func(e *NodeEntry) { e.Withdrawn = true },
+		"version":      func(e *NodeEntry) { e.Version++ },
+		"node ID":      func(e *NodeEntry) { e.NodeID = other.NodeID },
+		"key":          func(e *NodeEntry) { e.PublicKey = other.PublicKey },
+		"signature":    func(e *NodeEntry) { e.Signature = other.Signature },
+		"no signature": func(e *NodeEntry) { e.Signature = "" },
+	}
+	for name, tamper := range cases {
+		e := rec
+		tamper(&e)
+		err := e.Verify(time.Now())
+		if !errors.Is(err, ErrBadRecord) && !errors.Is(err, ErrUnsigned) {
+			t.Errorf("%s changed: got %v, want a rejected record", name, err)
+		}
+	}
+}
+
+func TestPresenceExpires(t *testing.T) {
+	rec, err := SignPresence(newKey(t), "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Verify(time.Unix(rec.Expires, 0)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("at expiry: got %v, want ErrExpired", err)
+	}
+	if err := rec.Verify(time.Unix(rec.Expires-1, 0)); err != nil {
+		t.Fatalf("a second before expiry: %v", err)
+	}
+}
+
+func TestWithdrawal(t *testing.T) {
+	priv := newKey(t)
+	rec, err := SignPresence(priv, "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone, err := SignWithdrawal(priv, "peer-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gone.Verify(time.Now()); !errors.Is(err, ErrWithdrawn) {
+		t.Fatalf("got %v, want ErrWithdrawn", err)
+	}
+	if gone.Expires <= rec.Expires {
+		t.Fatalf("withdrawal expires at %d, not after the record signed before it (%d)", gone.Expires, rec.Expires)
+	}
+}
@@ -0,0 +1,150 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/modset"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of a static directory file. JSON files work as well,
+// JSON being a subset of YAML:
+//
+//	nodes:
+//	  - node_id: <base64 node ID>
+//	    peer_id: 12D3KooW…
+//	    public_key: <base64 ed25519 key>
+//	    version: 2
+//	    expires: <unix time>
+//	    signature: <base64 signature>
+//	mods:
+//	  - peer_id: 12D3KooW…
+//	    public_key: <base64 ed25519 key>
+//	mod_sets:
+//	  - epoch: 0
+//	    activates_at: <unix time>
+//	    members: [<base64 ed25519 key>, …]
+//	    signatures:
+//	      - public_key: <base64 ed25519 key>
+//	        sign: <base64 signature>
+//	relays:
+//	  - /dns4/relay.example.org/tcp/443/wss/p2p/12D3KooW…
+type File struct {
+	Nodes   []NodeEntry  `json:"nodes" yaml:"nodes"`
+	Mods    []ModEntry   `json:"mods" yaml:"mods"`
+	ModSets []modset.Set `json:"mod_sets,omitempty" yaml:"mod_sets,omitempty"`
+	Relays  []string     `json:"relays" yaml:"relays"`
+}
+
+// StaticDirectory serves a directory file. The file is read again on every
+// call, so edits apply without a restart. Publish rewrites the file, which
+// drops its comments; it is only safe against concurrent publishers within
+// one process.
+type StaticDirectory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewStaticDirectory checks that path parses and returns a directory on it.
+func NewStaticDirectory(path string) (*StaticDirectory, error) {
+	d := &StaticDirectory{path: path}
+	if _, err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *StaticDirectory) load() (*File, error) {
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory file: %w", err)
+	}
+	f, err := ParseFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse directory file %s: %w", d.path, err)
+	}
+	return f, nil
+}
+
+// ParseFile parses a directory file in YAML or JSON.
+func ParseFile(data []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (d *StaticDirectory) Nodes(ctx context.Context) ([]NodeEntry, error) {
+	f, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	return f.Nodes, nil
+}
+
+func (d *StaticDirectory) Publish(ctx context.Context, e NodeEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.load()
+	if err != nil {
+		return err
+	}
+
+	// Replace the node's previous record and prune expired ones on the way
+	now := time.Now().Unix()
+	nodes := []NodeEntry{e}
+	for _, n := range f.Nodes {
+		if n.NodeID == e.NodeID {
+			if e.Expires <= n.Expires {
+				return ErrStale
+			}
+			continue
+		}
+		if n.Expires == 0 || n.Expires > now {
+			nodes = append(nodes, n)
+		}
+	}
+	f.Nodes = nodes
+
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write directory file: %w", err)
+	}
+	return os.Rename(tmp, d.path)
+}
+
+func (d *StaticDirectory) Mods(ctx context.Context) ([]ModEntry, error) {
+	f, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	return f.Mods, nil
+}
+
+func (d *StaticDirectory) ModSets(ctx context.Context) ([]modset.Set, error) {
+	f, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	return f.ModSets, nil
+}
+
+func (d *StaticDirectory) Relays(ctx context.Context) ([]string, error) {
+	f, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	return cleanRelays(f.Relays), nil
+}
+
+func (d *StaticDirectory) Close() error { return nil }
@@ -0,0 +1,94 @@
+package framing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion is the DHT protocol spoken over the chat stream. Version 2
+// moved the keyspace from 160-bit SHA-1 to 256-bit SHA-256 IDs. Requests and
+// responses carry it, and peers refuse anything that does not match, so nodes
+// on different keyspaces never make it into each other's routing tables.
+const ProtocolVersion = 2
+
+// Status is the outcome code carried by every Response.
+type Status int
+
+const (
+	StatusOK              Status = 200
+	StatusBadRequest      Status = 400
+	StatusNotFound        Status = 404
+	StatusUpgradeRequired Status = 426
+	StatusInternal        Status = 500
+	StatusUnreachable     Status = 502
+	StatusTimeout         Status = 504
+)
+
+var (
+	// ErrTimeout means no response arrived before the caller's deadline.
+	ErrTimeout = errors.New("request timed out")
+	// ErrPeerUnreachable means the relay could not reach the target peer.
+	ErrPeerUnreachable = errors.New("peer unreachable")
+	// ErrStreamClosed means the relay stream died with the request in flight.
+	ErrStreamClosed = errors.New("relay stream closed")
+	// ErrIncompatibleVersion means the peer speaks another ProtocolVersion.
+	ErrIncompatibleVersion = errors.New("incompatible protocol version")
+)
+
+// Response is the envelope every request is answered with. RequestID echoes
+// the request_id of the request it answers, so several requests can be in
+// flight on one stream. Version is the responder's ProtocolVersion; error
+// responses made up by the relay carry none.
+type Response struct {
+	RequestID string          `json:"request_id"`
+	Version   int             `json:"version,omitempty"`
+	Status    Status          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+// NewRequestID returns a random 128-bit hex request ID.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// OK wraps a handler's reply for requestID. Replies that are not valid JSON
+// are turned into a StatusInternal response.
+func OK(requestID string, body []byte) Response {
+	if !json.Valid(body) {
+		return Fail(requestID, StatusInternal, "handler produced invalid JSON")
+	}
+	return Response{RequestID: requestID, Version: ProtocolVersion, Status: StatusOK, Body: body}
+}
+
+// Fail builds an error response for requestID.
+func Fail(requestID string, status Status, msg string) Response {
+	return Response{RequestID: requestID, Version: ProtocolVersion, Status: status, Error: msg}
+}
+
+// Err maps a non-OK response to a typed error: ErrPeerUnreachable,
+// ErrTimeout and ErrIncompatibleVersion for the matching statuses,
+// *RemoteError otherwise. An OK response from a peer on another
+// ProtocolVersion is an ErrIncompatibleVersion too.
+func (r *Response) Err() error {
+	switch r.Status {
+	case StatusOK:
+		if r.Version != ProtocolVersion {
+			return fmt.Errorf("%w: peer speaks %d, want %d", ErrIncompatibleVersion, r.Version, ProtocolVersion)
+		}
+		return nil
+	case StatusUpgradeRequired:
+		return fmt.Errorf("%w: %s", ErrIncompatibleVersion, r.Error)
+	case StatusUnreachable:
+		return fmt.Errorf("%w: %s", ErrPeerUnreachable, r.Error)
+	case StatusTimeout:
+		return fmt.Errorf("%w: %s", ErrTimeout, r.Error)
+	default:
+		return &RemoteError{Status: r.Status, Msg: r.Error}
+	}
+}
@@ -0,0 +1,160 @@
+// Package framing is the wire codec of the chat stream protocol shared by db
+// nodes and mod clients.
+//
+// Every frame is a 1-byte type, a 4-byte big-endian payload length and the
+// payload. A message is any number of data frames followed by an end frame;
+// an error frame replaces the end frame when the remote side failed.
+package framing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ProtocolID is the libp2p protocol the codec is spoken on. Bump the version
+// whenever the frame layout changes.
+const ProtocolID = "/libr/chat/2.0.0"
+
+const (
+	// MaxFrameSize bounds the payload of a single frame.
+	MaxFrameSize = 256 * 1024
+	// MaxMessageSize bounds a reassembled message.
+	MaxMessageSize = 16 * 1024 * 1024
+)
+
+const (
+	FrameData  byte = 0x01
+	FrameError byte = 0x02
+	FrameEnd   byte = 0x03
+)
+
+const headerSize = 5
+
+var (
+	ErrFrameTooLarge   = errors.New("frame exceeds maximum size")
+	ErrMessageTooLarge = errors.New("message exceeds maximum size")
+	ErrUnknownFrame    = errors.New("unknown frame type")
+)
+
+// RemoteError is returned by ReadMessage when the remote side answered with
+// an error frame, and by Response.Err for failed requests. Status is zero
+// for error frames.
+type RemoteError struct {
+	Status Status
+	Msg    string
+}
+
+func (e *RemoteError) Error() string {
+	if e.Status == 0 {
+		return "remote error: " + e.Msg
+	}
+	return fmt.Sprintf("remote error (%d): %s", e.Status, e.Msg)
+}
+
+// WriteFrame writes a single frame.
+func WriteFrame(w io.Writer, typ byte, payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+	var header [headerSize]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// ReadFrame reads a single frame. It returns io.EOF only when the stream
+// ends cleanly before a new frame starts.
+func ReadFrame(r io.Reader) (typ byte, payload []byte, err error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("reading frame header: %w", err)
+		}
+		return 0, nil, err
+	}
+
+	typ = header[0]
+	if typ != FrameData && typ != FrameError && typ != FrameEnd {
+		return 0, nil, ErrUnknownFrame
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > MaxFrameSize {
+		return 0, nil, ErrFrameTooLarge
+	}
+
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, fmt.Errorf("reading frame payload: %w", err)
+	}
+	return typ, payload, nil
+}
+
+// WriteMessage writes msg as data frames followed by an end frame.
+func WriteMessage(w io.Writer, msg []byte) error {
+	if len(msg) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	for len(msg) > 0 {
+		n := len(msg)
+		if n > MaxFrameSize {
+			n = MaxFrameSize
+		}
+		if err := WriteFrame(w, FrameData, msg[:n]); err != nil {
+			return err
+		}
+		msg = msg[n:]
+	}
+	return WriteFrame(w, FrameEnd, nil)
+}
+
+// WriteError ends the current message with an error frame.
+func WriteError(w io.Writer, msg string) error {
+	payload := []byte(msg)
+	if len(payload) > MaxFrameSize {
+		payload = payload[:MaxFrameSize]
+	}
+	return WriteFrame(w, FrameError, payload)
+}
+
+// ReadMessage reads data frames up to the end frame and returns their
+// concatenated payloads. An error frame yields a *RemoteError. io.EOF is
+// returned only when the stream ends cleanly between messages.
+func ReadMessage(r io.Reader) ([]byte, error) {
+	var msg []byte
+	for first := true; ; first = false {
+		typ, payload, err := ReadFrame(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) && !first {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		switch typ {
+		case FrameData:
+			if len(msg)+len(payload) > MaxMessageSize {
+				return nil, ErrMessageTooLarge
+			}
+			msg = append(msg, payload...)
+		case FrameError:
+			return nil, &RemoteError{Msg: string(payload)}
+		case FrameEnd:
+			if msg == nil {
+				msg = []byte{}
+			}
+			return msg, nil
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package framing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// rawFrame returns a frame header claiming n payload bytes, followed by
+// payload as is.
+func rawFrame(typ byte, n uint32, payload []byte) []byte {
+	header := make([]byte, headerSize)
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], n)
+	return append(header, payload...)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	messages := [][]byte{
+		{},
+		[]byte("{\"a\":\"line one\nline two\n\"}\n"),
+		[]byte("nul\x00in\x00the\x00middle\x00"),
+		bytes.Repeat([]byte{'x'}, MaxFrameSize),
+		bytes.Repeat([]byte("ab\n\x00"), (2*MaxFrameSize+7)/4),
+	}
+
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		if err := WriteMessage(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, want := range messages {
+		got, err := ReadMessage(&buf)
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("message %d: read %d bytes back, want %d", i, len(got), len(want))
+		}
+	}
+	if _, err := ReadMessage(&buf); err != io.EOF {
+		t.Fatalf("after the last message: got %v, want io.EOF", err)
+	}
+}
+
+func TestWriteMessageSplitsFrames(t *testing.T) {
+	msg := bytes.Repeat([]byte{'y'}, 2*MaxFrameSize+1)
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes []int
+	for {
+		typ, payload, err := ReadFrame(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ == FrameEnd {
+			break
+		}
+		if typ != FrameData {
+			t.Fatalf("frame type %#x inside a message", typ)
+		}
+		sizes = append(sizes, len(payload))
+	}
+	if len(sizes) != 3 || sizes[0] != MaxFrameSize || sizes[1] != MaxFrameSize || sizes[2] != 1 {
+		t.Fatalf("message split into frames of %v", sizes)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("%d bytes left after the end frame", buf.Len())
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	if err := WriteFrame(io.Discard, FrameData, make([]byte, MaxFrameSize+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("writing an oversized frame: got %v, want ErrFrameTooLarge", err)
+	}
+	if err := WriteMessage(io.Discard, make([]byte, MaxMessageSize+1)); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("writing an oversized message: got %v, want ErrMessageTooLarge", err)
+	}
+
+	// A header announcing too much is refused before the payload is read
+	oversized := bytes.NewReader(rawFrame(FrameData, MaxFrameSize+1, nil))
+	if _, _, err := ReadFrame(oversized); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("reading an oversized frame: got %v, want ErrFrameTooLarge", err)
+	}
+
+	// Frames that are each fine but add up to too much
+	full := rawFrame(FrameData, MaxFrameSize, make([]byte, MaxFrameSize))
+	var stream bytes.Buffer
+	for i := 0; i <= MaxMessageSize/MaxFrameSize; i++ {
+		stream.Write(full)
+	}
+	stream.Write(rawFrame(FrameEnd, 0, nil))
+	if _, err := ReadMessage(&stream); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("reading an oversized message: got %v, want ErrMessageTooLarge", err)
+	}
+
+	// A message of exactly MaxMessageSize still goes through
+	var exact bytes.Buffer
+	if err := WriteMessage(&exact, make([]byte, MaxMessageSize)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := ReadMessage(&exact); err != nil || len(msg) != MaxMessageSize {
+		t.Fatalf("reading a message of MaxMessageSize: %d bytes, %v", len(msg), err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	data := rawFrame(FrameData, 4, []byte("data"))
+	cases := []struct {
+		name   string
+		stream []byte
+	}{
+		{"header", rawFrame(FrameData, 4, nil)[:3]},
+		{"payload", rawFrame(FrameData, 10, []byte("short"))},
+		{"payload missing", rawFrame(FrameData, 10, nil)},
+		{"end frame", data},
+		{"header after data", append(data, FrameEnd, 0)},
+	}
+	for _, c := range cases {
+		_, err := ReadMessage(bytes.NewReader(c.stream))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("truncated %s: got %v, want io.ErrUnexpectedEOF", c.name, err)
+		}
+	}
+
+	if _, err := ReadMessage(bytes.NewReader(nil)); err != io.EOF {
+		t.Fatalf("empty stream: got %v, want io.EOF", err)
+	}
+	if _, _, err := ReadFrame(bytes.NewReader(rawFrame(0x7f, 0, nil))); !errors.Is(err, ErrUnknownFrame) {
+		t.Fatalf("frame type 0x7f: got %v, want ErrUnknownFrame", err)
+	}
+}
+
+func TestErrorFrame(t *testing.T) {
+	var buf bytes.Buffer
+	WriteFrame(&buf, FrameData, []byte("partial"))
+	if err := WriteError(&buf, "handler failed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteError(&buf, strings.Repeat("e", MaxFrameSize+10)); err != nil {
+		t.Fatalf("long error text: %v", err)
+	}
+
+	var remote *RemoteError
+	if _, err := ReadMessage(&buf); !errors.As(err, &remote) || remote.Msg != "handler failed" || remote.Status != 0 {
+		t.Fatalf("got %v, want a RemoteError carrying the frame's text", err)
+	}
+	if _, err := ReadMessage(&buf); !errors.As(err, &remote) || len(remote.Msg) != MaxFrameSize {
+		t.Fatalf("long error text read back as %d bytes (%v), want it cut to %d", len(remote.Msg), err, MaxFrameSize)
+	}
+}
+
+func TestResponseErr(t *testing.T) {
+	cases := []struct {
+		resp Response
+		want error
+	}{
+		{Response{Status: StatusOK, Version: ProtocolVersion}, nil},
+		{Response{Status: StatusOK, Version: ProtocolVersion - 1}, ErrIncompatibleVersion},
+		{Response{Status: StatusOK}, ErrIncompatibleVersion},
+		{Response{Status: StatusUpgradeRequired, Error: "v1"}, ErrIncompatibleVersion},
+		{Response{Status: StatusUnreachable, Error: "no route"}, ErrPeerUnreachable},
+		{Response{Status: StatusTimeout, Error: "slow"}, ErrTimeout},
+	}
+	for _, c := range cases {
+		if err := c.resp.Err(); !errors.Is(err, c.want) || (c.want == nil) != (err == nil) {
+			t.Errorf("status %d version %d: got %v, want %v", c.resp.Status, c.resp.Version, err, c.want)
+		}
+	}
+
+	for _, status := range []Status{StatusBadRequest, StatusNotFound, StatusInternal} {
+		resp := Fail("req", status, "boom")
+		var remote *RemoteError
+		if err := resp.Err(); !errors.As(err, &remote) || remote.Status != status || remote.Msg != "boom" {
+			t.Errorf("status %d: got %v, want a RemoteError with the status", status, err)
+		}
+	}
+
+	if resp := OK("req", []byte("not json")); resp.Status != StatusInternal || resp.RequestID != "req" {
+		t.Fatalf("invalid handler reply wrapped as %+v, want StatusInternal", resp)
+	}
+	if resp := OK("req", []byte(`{"ok":true}`)); resp.Err() != nil || string(resp.Body) != `{"ok":true}` {
+		t.Fatalf("valid handler reply wrapped as %+v", resp)
+	}
+}
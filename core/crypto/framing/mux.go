@@ -0,0 +1,160 @@
+package framing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultRequestTimeout applies to RoundTrip calls whose context has no
+// deadline.
+const defaultRequestTimeout = 10 * time.Second
+
+// Stream is the part of a libp2p stream Mux uses.
+type Stream interface {
+	io.ReadWriter
+	Reset() error
+}
+
+// Mux multiplexes requests over one long-lived stream to the relay.
+// Responses are matched to their request by request_id, so any number of
+// requests can be in flight at once. The stream is opened on first use and
+// reopened after it fails.
+type Mux struct {
+	open func(ctx context.Context) (Stream, error)
+
+	mu      sync.Mutex
+	stream  Stream
+	pending map[string]chan muxResult
+
+	writeMu sync.Mutex
+}
+
+type muxResult struct {
+	resp Response
+	err  error
+}
+
+// NewMux returns a Mux that opens its stream with open.
+func NewMux(open func(ctx context.Context) (Stream, error)) *Mux {
+	return &Mux{open: open, pending: make(map[string]chan muxResult)}
+}
+
+// register returns the shared stream, opening it (and its reader) if there
+// is none, and registers ch for the response to requestID. Both happen under
+// one lock, so a stream that fails right after being handed out still fails
+// the request instead of leaving it to time out.
+func (m *Mux) register(ctx context.Context, requestID string, ch chan muxResult) (Stream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stream == nil {
+		s, err := m.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.stream = s
+		go m.readResponses(s)
+	}
+	m.pending[requestID] = ch
+	return m.stream, nil
+}
+
+// readResponses delivers every response read from s to the request waiting
+// for it. When s fails, every request still waiting on it is failed with
+// ErrStreamClosed and the next RoundTrip opens a fresh stream.
+func (m *Mux) readResponses(s Stream) {
+	reader := bufio.NewReader(s)
+	for {
+		msg, err := ReadMessage(reader)
+		var remoteErr *RemoteError
+		if errors.As(err, &remoteErr) {
+			fmt.Println("[DEBUG]Uncorrelated error frame from relay:", remoteErr.Msg)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				fmt.Println("[DEBUG]Relay stream read failed:", err)
+			}
+			m.closeStream(s, err)
+			return
+		}
+
+		var resp Response
+		if err := json.Unmarshal(msg, &resp); err != nil || resp.RequestID == "" {
+			fmt.Println("[DEBUG]Dropping response without request_id")
+			continue
+		}
+
+		m.mu.Lock()
+		ch, ok := m.pending[resp.RequestID]
+		delete(m.pending, resp.RequestID)
+		m.mu.Unlock()
+		if !ok {
+			fmt.Println("[DEBUG]Dropping response for unknown request", resp.RequestID)
+			continue
+		}
+		ch <- muxResult{resp: resp}
+	}
+}
+
+func (m *Mux) closeStream(s Stream, cause error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stream != s {
+		return
+	}
+	s.Reset()
+	m.stream = nil
+	for id, ch := range m.pending {
+		ch <- muxResult{err: fmt.Errorf("%w: %v", ErrStreamClosed, cause)}
+		delete(m.pending, id)
+	}
+}
+
+// RoundTrip writes msg, a request carrying requestID, to the shared stream
+// and waits for the response carrying the same ID. It returns the body of an
+// OK response and the error of any other.
+func (m *Mux) RoundTrip(ctx context.Context, requestID string, msg []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+
+	ch := make(chan muxResult, 1)
+	s, err := m.register(ctx, requestID, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	m.writeMu.Lock()
+	err = WriteMessage(s, msg)
+	m.writeMu.Unlock()
+	if err != nil {
+		m.closeStream(s, err)
+		return nil, fmt.Errorf("%w: %v", ErrStreamClosed, err)
+	}
+
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if err := res.resp.Err(); err != nil {
+			return nil, err
+		}
+		return res.resp.Body, nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		delete(m.pending, requestID)
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %v", ErrTimeout, ctx.Err())
+	}
+}
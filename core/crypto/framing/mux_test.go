@@ -0,0 +1,297 @@
+package framing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeStream is one end of an in-memory stream. Reset breaks both ends.
+type pipeStream struct {
+	io.Reader
+	io.Writer
+	reset func()
+}
+
+func (s *pipeStream) Reset() error {
+	s.reset()
+	return nil
+}
+
+// relay hands out in-memory streams to a Mux and passes the far end of
+// each to the test.
+type relay struct {
+	mu     sync.Mutex
+	opened int
+	far    chan *pipeStream
+}
+
+func newRelay() *relay {
+	return &relay{far: make(chan *pipeStream, 4)}
+}
+
+func (r *relay) open(ctx context.Context) (Stream, error) {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	var once sync.Once
+	reset := func() {
+		once.Do(func() {
+			err := errors.New("stream reset")
+			reqR.CloseWithError(err)
+			reqW.CloseWithError(err)
+			respR.CloseWithError(err)
+			respW.CloseWithError(err)
+		})
+	}
+	r.mu.Lock()
+	r.opened++
+	r.mu.Unlock()
+	r.far <- &pipeStream{Reader: reqR, Writer: respW, reset: reset}
+	return &pipeStream{Reader: respR, Writer: reqW, reset: reset}, nil
+}
+
+func (r *relay) opens() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.opened
+}
+
+// next returns the far end of the stream the Mux opened next.
+func (r *relay) next(t *testing.T) *pipeStream {
+	t.Helper()
+	select {
+	case s := <-r.far:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("mux opened no stream")
+		return nil
+	}
+}
+
+type request struct {
+	RequestID string `json:"request_id"`
+	N         int    `json:"n"`
+}
+
+// readRequest reads one request off the far end of a stream.
+func readRequest(s *pipeStream) (request, error) {
+	msg, err := ReadMessage(s)
+	if err != nil {
+		return request{}, err
+	}
+	var req request
+	err = json.Unmarshal(msg, &req)
+	return req, err
+}
+
+func respond(s *pipeStream, resp Response) error {
+	data, _ := json.Marshal(resp)
+	return WriteMessage(s, data)
+}
+
+func roundTrip(ctx context.Context, m *Mux, n int) ([]byte, error) {
+	id := NewRequestID()
+	msg, _ := json.Marshal(request{RequestID: id, N: n})
+	return m.RoundTrip(ctx, id, msg)
+}
+
+func TestMuxInterleaved(t *testing.T) {
+	r := newRelay()
+	m := NewMux(r.open)
+	const n = 8
+
+	results := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = roundTrip(context.Background(), m, i)
+		}(i)
+	}
+
+	// Answer only once every request is in, last one first
+	far := r.next(t)
+	var reqs []request
+	for len(reqs) < n {
+		req, err := readRequest(far)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqs = append(reqs, req)
+	}
+	for i := len(reqs) - 1; i >= 0; i-- {
+		body := fmt.Sprintf(`{"n":%d}`, reqs[i].N)
+		if err := respond(far, OK(reqs[i].RequestID, []byte(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("request %d: %v", i, errs[i])
+		}
+		if want := fmt.Sprintf(`{"n":%d}`, i); string(results[i]) != want {
+			t.Fatalf("request %d got %s, want %s", i, results[i], want)
+		}
+	}
+	if r.opens() != 1 {
+		t.Fatalf("opened %d streams for one batch", r.opens())
+	}
+}
+
+func TestMuxTimeoutVersusEmptyReply(t *testing.T) {
+	r := newRelay()
+	m := NewMux(r.open)
+
+	done := make(chan error, 1)
+	go func() {
+		far := <-r.far
+		// The first request is never answered in time
+		late, err := readRequest(far)
+		if err != nil {
+			done <- err
+			return
+		}
+		empty, err := readRequest(far)
+		if err != nil {
+			done <- err
+			return
+		}
+		if err := respond(far, Response{RequestID: empty.RequestID, Version: ProtocolVersion, Status: StatusOK}); err != nil {
+			done <- err
+			return
+		}
+		// The answer to the timed out request turns up after all
+		time.Sleep(100 * time.Millisecond)
+		done <- respond(far, OK(late.RequestID, []byte(`{}`)))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := roundTrip(ctx, m, 1); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("unanswered request: got %v, want ErrTimeout", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	body, err := roundTrip(ctx, m, 2)
+	if err != nil || len(body) != 0 {
+		t.Fatalf("empty reply: got %q, %v; want no body and no error", body, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	m.mu.Lock()
+	pending := len(m.pending)
+	m.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("%d requests still registered after both returned", pending)
+	}
+}
+
+func TestMuxReopenFailsPending(t *testing.T) {
+	r := newRelay()
+	m := NewMux(r.open)
+	const n = 4
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			_, err := roundTrip(ctx, m, i)
+			errs <- err
+		}(i)
+	}
+
+	// Take every request in, then drop the stream
+	far := r.next(t)
+	for i := 0; i < n; i++ {
+		if _, err := readRequest(far); err != nil {
+			t.Fatal(err)
+		}
+	}
+	far.Reset()
+
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrStreamClosed) {
+				t.Fatalf("pending request: got %v, want ErrStreamClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("pending request hangs after the stream closed")
+		}
+	}
+
+	// The next request goes out on a fresh stream
+	go func() {
+		far := <-r.far
+		req, err := readRequest(far)
+		if err == nil {
+			respond(far, OK(req.RequestID, []byte(`{"again":true}`)))
+		}
+	}()
+	if body, err := roundTrip(ctx, m, n); err != nil || string(body) != `{"again":true}` {
+		t.Fatalf("request after the reopen: %q, %v", body, err)
+	}
+	if r.opens() != 2 {
+		t.Fatalf("opened %d streams, want 2", r.opens())
+	}
+}
+
+func TestMuxUnknownID(t *testing.T) {
+	r := newRelay()
+	m := NewMux(r.open)
+
+	done := make(chan error, 1)
+	go func() {
+		far := <-r.far
+		req, err := readRequest(far)
+		if err != nil {
+			done <- err
+			return
+		}
+		// Noise the reader must skip without failing the stream
+		for _, resp := range []Response{
+			OK("someone-else", []byte(`{"wrong":true}`)),
+			OK("", []byte(`{"wrong":true}`)),
+		} {
+			if err := respond(far, resp); err != nil {
+				done <- err
+				return
+			}
+		}
+		if err := WriteMessage(far, []byte("not json")); err != nil {
+			done <- err
+			return
+		}
+		if err := WriteError(far, "uncorrelated"); err != nil {
+			done <- err
+			return
+		}
+		done <- respond(far, OK(req.RequestID, []byte(`{"right":true}`)))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	body, err := roundTrip(ctx, m, 1)
+	if err != nil || string(body) != `{"right":true}` {
+		t.Fatalf("got %q, %v; want the response carrying the request's ID", body, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if r.opens() != 1 {
+		t.Fatalf("opened %d streams, want the first one kept", r.opens())
+	}
+}
@@ -0,0 +1,58 @@
+// Package keyspace defines the DHT keyspace shared by db nodes and clients.
+// Node IDs and content keys are SHA-256 digests; everything that sizes an ID,
+// a bucket array or a distance takes its width from here.
+package keyspace
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+const (
+	// Bits is the width of the keyspace and the number of k-buckets.
+	Bits = 256
+	// Size is the width of an ID in bytes.
+	Size = Bits / 8
+)
+
+// ID is a node ID or content key.
+type ID = [Size]byte
+
+// Sum hashes input into the keyspace.
+func Sum(input string) ID {
+	return sha256.Sum256([]byte(input))
+}
+
+// Decode parses a base64 encoded ID and rejects anything that is not
+// exactly Size bytes, such as IDs from the old 160-bit keyspace.
+func Decode(base64Str string) (ID, error) {
+	var id ID
+
+	raw, err := base64.StdEncoding.DecodeString(base64Str)
+	if err != nil {
+		return id, err
+	}
+	if len(raw) != Size {
+		return id, fmt.Errorf("invalid ID length: expected %d bytes, got %d", Size, len(raw))
+	}
+
+	copy(id[:], raw)
+	return id, nil
+}
+
+// CompareDistance compares the XOR distances of a and b to target byte by
+// byte, returning -1 if a is closer, 1 if b is closer and 0 if a == b. It
+// does not allocate.
+func CompareDistance(target, a, b ID) int {
+	for i := 0; i < Size; i++ {
+		da, db := a[i]^target[i], b[i]^target[i]
+		if da != db {
+			if da < db {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package keyspace
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func bigDistance(a, b ID) *big.Int {
+	var d ID
+	for i := range d {
+		d[i] = a[i] ^ b[i]
+	}
+	return new(big.Int).SetBytes(d[:])
+}
+
+func TestCompareDistance(t *testing.T) {
+	target := Sum("target")
+	if got := CompareDistance(target, target, target); got != 0 {
+		t.Fatalf("equal IDs compare %d", got)
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := Sum(fmt.Sprintf("a-%d", i)), Sum(fmt.Sprintf("b-%d", i))
+		want := bigDistance(a, target).Cmp(bigDistance(b, target))
+		if got := CompareDistance(target, a, b); got != want {
+			t.Fatalf("pair %d compares %d, want %d", i, got, want)
+		}
+	}
+}
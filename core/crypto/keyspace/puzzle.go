@@ -0,0 +1,48 @@
+package keyspace
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+// The ID puzzle is the dynamic crypto puzzle of S/Kademlia: a node has to
+// find a nonce such that SHA-256(id || nonce) starts with difficulty zero
+// bits. Solving it costs about 2^difficulty hashes, checking it costs one, so
+// every ID a node brings into the network costs that much work. It does not
+// make aiming for a chosen key any dearer: keys can still be ground until
+// the ID lands near it, and the puzzle is solved once for the winner.
+// Closing that gap takes the static puzzle on H(H(pubkey)), which every node
+// key issued so far would fail.
+
+// SolvePuzzle returns the smallest nonce that solves the ID puzzle for id.
+// Difficulty 0 is solved by nonce 0.
+func SolvePuzzle(id ID, difficulty int) uint64 {
+	var nonce uint64
+	for !CheckPuzzle(id, nonce, difficulty) {
+		nonce++
+	}
+	return nonce
+}
+
+// CheckPuzzle reports whether nonce solves the ID puzzle for id.
+func CheckPuzzle(id ID, nonce uint64, difficulty int) bool {
+	if difficulty <= 0 {
+		return true
+	}
+
+	var buf [Size + 8]byte
+	copy(buf[:], id[:])
+	binary.BigEndian.PutUint64(buf[Size:], nonce)
+	sum := sha256.Sum256(buf[:])
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
@@ -0,0 +1,338 @@
+// Package lookup is the iterative Kademlia lookup shared by db nodes and
+// clients. It does no I/O of its own: every operation takes a QueryFunc that
+// sends one RPC to one contact and reports what came back, so each side keeps
+// its own transport and wire format.
+package lookup
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+)
+
+const (
+	DefaultAlpha        = 3
+	DefaultK            = 4
+	DefaultMaxRounds    = 20
+	DefaultQueryTimeout = 5 * time.Second
+)
+
+// ErrQueryTimeout is recorded for contacts that did not answer within
+// Config.QueryTimeout.
+var ErrQueryTimeout = errors.New("lookup: query timed out")
+
+// Contact is the part of a node a lookup needs.
+type Contact struct {
+	NodeID keyspace.ID
+	PeerID string
+}
+
+// Reply is what a single query returned.
+type Reply struct {
+	// Closer holds the contacts the responder pointed at.
+	Closer []Contact
+	// Found reports that the responder held the value (FindValue) or
+	// accepted it (Store).
+	Found bool
+}
+
+// QueryFunc sends one RPC to c. It should give up once ctx is done; the
+// lookup stops waiting for it at that point either way.
+type QueryFunc func(ctx context.Context, c Contact) (Reply, error)
+
+// Config tunes a lookup. Zero fields fall back to the defaults.
+type Config struct {
+	Alpha        int           // queries in flight per round
+	K            int           // size of the closest set
+	MaxRounds    int           // hard cap on rounds
+	QueryTimeout time.Duration // per-query deadline
+	Self         keyspace.ID   // never queried; zero means none
+}
+
+func (c Config) withDefaults() Config {
+	if c.Alpha <= 0 {
+		c.Alpha = DefaultAlpha
+	}
+	if c.K <= 0 {
+		c.K = DefaultK
+	}
+	if c.MaxRounds <= 0 {
+		c.MaxRounds = DefaultMaxRounds
+	}
+	if c.QueryTimeout <= 0 {
+		c.QueryTimeout = DefaultQueryTimeout
+	}
+	return c
+}
+
+// Failure records a contact that errored or timed out.
+type Failure struct {
+	Contact Contact
+	Err     error
+}
+
+// Result describes a finished lookup.
+type Result struct {
+	// Closest holds up to K contacts that answered, nearest to the target first.
+	Closest []Contact
+	// Responded holds every contact that answered, in the order they did.
+	Responded []Contact
+	// Found holds the responders whose reply had Found set.
+	Found []Contact
+	// Failed holds the contacts that errored or timed out.
+	Failed []Failure
+	// Rounds is the number of rounds that sent at least one query.
+	Rounds int
+}
+
+// FindNode walks towards target until the K closest contacts it knows of
+// have all been queried.
+func FindNode(ctx context.Context, cfg Config, target keyspace.ID, seeds []Contact, query QueryFunc) *Result {
+	return run(ctx, cfg, target, seeds, query, false, nil)
+}
+
+// FindValue walks towards target like FindNode, but also stops once K
+// responders reported holding the value. It does not stop at the first one:
+// callers collect the value from every replica the lookup reaches.
+func FindValue(ctx context.Context, cfg Config, target keyspace.ID, seeds []Contact, query QueryFunc) *Result {
+	return run(ctx, cfg, target, seeds, query, true, nil)
+}
+
+// Store walks towards target with query doing the store RPC, and stops once
+// K responders accepted the value or the closest set converged.
+func Store(ctx context.Context, cfg Config, target keyspace.ID, seeds []Contact, query QueryFunc) *Result {
+	return run(ctx, cfg, target, seeds, query, true, nil)
+}
+
+// DisjointResult describes a lookup over disjoint paths.
+type DisjointResult struct {
+	// Merged combines the paths: Closest is the K nearest responders of any
+	// path, Rounds the longest path.
+	Merged *Result
+	// Paths holds the result of each path.
+	Paths []*Result
+	// Reached is the number of paths that reached at least one responder
+	// holding the value. The values themselves are opaque here, so it says
+	// nothing about whether those paths saw the same ones; callers compare
+	// the values per path for that.
+	Reached int
+}
+
+// FindValueDisjoint runs FindValue over d paths at once, as in S/Kademlia.
+// The seeds are dealt out round-robin, nearest first, and no contact is
+// queried by more than one path, so a path can only be steered into a dead
+// end by the nodes on it. With fewer seeds than paths, the extra paths stay
+// empty.
+func FindValueDisjoint(ctx context.Context, cfg Config, d int, target keyspace.ID, seeds []Contact, query QueryFunc) *DisjointResult {
+	if d < 1 {
+		d = 1
+	}
+
+	seeds = slices.Clone(seeds)
+	sort.SliceStable(seeds, func(i, j int) bool {
+		return Less(target, seeds[i].NodeID, seeds[j].NodeID)
+	})
+	pathSeeds := make([][]Contact, d)
+	for i, c := range seeds {
+		pathSeeds[i%d] = append(pathSeeds[i%d], c)
+	}
+
+	var mu sync.Mutex
+	taken := make(map[keyspace.ID]bool)
+	claim := func(id keyspace.ID) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if taken[id] {
+			return false
+		}
+		taken[id] = true
+		return true
+	}
+
+	res := &DisjointResult{Paths: make([]*Result, d)}
+	var wg sync.WaitGroup
+	for i := range pathSeeds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res.Paths[i] = run(ctx, cfg, target, pathSeeds[i], query, true, claim)
+		}(i)
+	}
+	wg.Wait()
+
+	merged := &Result{}
+	var closestAll []Contact
+	for _, p := range res.Paths {
+		if len(p.Found) > 0 {
+			res.Reached++
+		}
+		closestAll = append(closestAll, p.Closest...)
+		merged.Responded = append(merged.Responded, p.Responded...)
+		merged.Found = append(merged.Found, p.Found...)
+		merged.Failed = append(merged.Failed, p.Failed...)
+		merged.Rounds = max(merged.Rounds, p.Rounds)
+	}
+	sort.Slice(closestAll, func(i, j int) bool {
+		return Less(target, closestAll[i].NodeID, closestAll[j].NodeID)
+	})
+	merged.Closest = closestAll[:min(len(closestAll), cfg.withDefaults().K)]
+	res.Merged = merged
+	return res
+}
+
+type state int
+
+const (
+	pending state = iota
+	inFlight
+	responded
+	failed
+	// claimed marks contacts another disjoint path has already queried
+	claimed
+)
+
+type entry struct {
+	contact Contact
+	state   state
+}
+
+// run is the lookup loop. When claim is set, a contact is only queried if
+// claim returns true for it; the others are dropped from the closest set.
+func run(ctx context.Context, cfg Config, target keyspace.ID, seeds []Contact, query QueryFunc, stopOnFound bool, claim func(keyspace.ID) bool) *Result {
+	cfg = cfg.withDefaults()
+	res := &Result{}
+	known := make(map[keyspace.ID]*entry)
+	var mu sync.Mutex
+
+	add := func(c Contact) {
+		if c.PeerID == "" || c.NodeID == (keyspace.ID{}) || c.NodeID == cfg.Self {
+			return
+		}
+		if _, ok := known[c.NodeID]; !ok {
+			known[c.NodeID] = &entry{contact: c}
+		}
+	}
+	for _, c := range seeds {
+		add(c)
+	}
+
+	for res.Rounds < cfg.MaxRounds && ctx.Err() == nil {
+		if stopOnFound && len(res.Found) >= cfg.K {
+			break
+		}
+
+		// The closest set is the K nearest contacts that have not failed or
+		// gone to another disjoint path. Once none of them is left to query
+		// the lookup has converged.
+		var toQuery []*entry
+		skipped := false
+		for _, e := range closest(known, target, cfg.K, func(e *entry) bool { return e.state != failed && e.state != claimed }) {
+			if e.state == pending {
+				if claim != nil && !claim(e.contact.NodeID) {
+					e.state = claimed
+					skipped = true
+					continue
+				}
+				toQuery = append(toQuery, e)
+				if len(toQuery) == cfg.Alpha {
+					break
+				}
+			}
+		}
+		if len(toQuery) == 0 {
+			// Contacts claimed by other paths left the closest set; look
+			// again at the ones that moved up before giving up
+			if skipped {
+				continue
+			}
+			break
+		}
+		res.Rounds++
+
+		var wg sync.WaitGroup
+		for _, e := range toQuery {
+			e.state = inFlight
+			wg.Add(1)
+			go func(e *entry) {
+				defer wg.Done()
+				reply, err := queryWithTimeout(ctx, cfg.QueryTimeout, e.contact, query)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					e.state = failed
+					res.Failed = append(res.Failed, Failure{Contact: e.contact, Err: err})
+					return
+				}
+				e.state = responded
+				res.Responded = append(res.Responded, e.contact)
+				if reply.Found {
+					res.Found = append(res.Found, e.contact)
+				}
+				for _, c := range reply.Closer {
+					add(c)
+				}
+			}(e)
+		}
+		wg.Wait()
+	}
+
+	for _, e := range closest(known, target, cfg.K, func(e *entry) bool { return e.state == responded }) {
+		res.Closest = append(res.Closest, e.contact)
+	}
+	return res
+}
+
+// queryWithTimeout runs query under its own deadline and stops waiting for
+// it once the deadline passes, even if query ignores ctx.
+func queryWithTimeout(ctx context.Context, timeout time.Duration, c Contact, query QueryFunc) (Reply, error) {
+	qctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		reply Reply
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		reply, err := query(qctx, c)
+		done <- outcome{reply, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.reply, o.err
+	case <-qctx.Done():
+		if errors.Is(qctx.Err(), context.DeadlineExceeded) {
+			return Reply{}, ErrQueryTimeout
+		}
+		return Reply{}, qctx.Err()
+	}
+}
+
+// closest returns up to k entries accepted by keep, nearest to target first.
+func closest(known map[keyspace.ID]*entry, target keyspace.ID, k int, keep func(*entry) bool) []*entry {
+	entries := make([]*entry, 0, len(known))
+	for _, e := range known {
+		if keep(e) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return Less(target, entries[i].contact.NodeID, entries[j].contact.NodeID)
+	})
+	if len(entries) > k {
+		entries = entries[:k]
+	}
+	return entries
+}
+
+// Less reports whether a is closer to target than b by XOR distance.
+func Less(target, a, b keyspace.ID) bool {
+	return keyspace.CompareDistance(target, a, b) < 0
+}
@@ -0,0 +1,319 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+)
+
+var errDown = errors.New("node is down")
+
+// testNet is a network of nodes whose routing tables keep up to k live
+// contacts per bucket, as a Kademlia table does once it has evicted dead
+// nodes. Dead nodes met before a bucket filled up stay in the table.
+type testNet struct {
+	k        int
+	contacts []Contact
+	tables   map[keyspace.ID][]Contact
+	down     map[keyspace.ID]bool
+	holders  map[keyspace.ID]bool
+
+	mu      sync.Mutex
+	queries map[keyspace.ID]int
+}
+
+func newTestNet(size, k int, down func(i int) bool) *testNet {
+	tn := &testNet{
+		k:       k,
+		tables:  make(map[keyspace.ID][]Contact),
+		down:    make(map[keyspace.ID]bool),
+		holders: make(map[keyspace.ID]bool),
+		queries: make(map[keyspace.ID]int),
+	}
+	for i := 0; i < size; i++ {
+		c := Contact{
+			NodeID: keyspace.Sum(fmt.Sprintf("node-%d", i)),
+			PeerID: fmt.Sprintf("peer-%d", i),
+		}
+		tn.contacts = append(tn.contacts, c)
+		if down != nil && down(i) {
+			tn.down[c.NodeID] = true
+		}
+	}
+	for _, self := range tn.contacts {
+		perBucket := make(map[int]int)
+		for _, c := range tn.contacts {
+			if c.NodeID == self.NodeID {
+				continue
+			}
+			bucket := bucketIndex(self.NodeID, c.NodeID)
+			if perBucket[bucket] < k {
+				if !tn.down[c.NodeID] {
+					perBucket[bucket]++
+				}
+				tn.tables[self.NodeID] = append(tn.tables[self.NodeID], c)
+			}
+		}
+	}
+	return tn
+}
+
+func bucketIndex(a, b keyspace.ID) int {
+	for i := range a {
+		if x := a[i] ^ b[i]; x != 0 {
+			return keyspace.Bits - 1 - (i*8 + bits.LeadingZeros8(x))
+		}
+	}
+	return 0
+}
+
+// query answers like a db node: the contacts closest to target that the
+// queried node knows, up to k live ones and the dead ones among them, and
+// whether it holds the value.
+func (tn *testNet) query(target keyspace.ID) QueryFunc {
+	return func(ctx context.Context, c Contact) (Reply, error) {
+		tn.mu.Lock()
+		tn.queries[c.NodeID]++
+		tn.mu.Unlock()
+
+		if tn.down[c.NodeID] {
+			return Reply{}, errDown
+		}
+		var closer []Contact
+		live := 0
+		for _, n := range nearest(tn.tables[c.NodeID], target, len(tn.tables[c.NodeID])) {
+			if live == tn.k {
+				break
+			}
+			closer = append(closer, n)
+			if !tn.down[n.NodeID] {
+				live++
+			}
+		}
+		return Reply{Closer: closer, Found: tn.holders[c.NodeID]}, nil
+	}
+}
+
+// liveClosest is the k live nodes of the network closest to target.
+func (tn *testNet) liveClosest(target keyspace.ID) []Contact {
+	var live []Contact
+	for _, c := range tn.contacts {
+		if !tn.down[c.NodeID] {
+			live = append(live, c)
+		}
+	}
+	return nearest(live, target, tn.k)
+}
+
+func nearest(contacts []Contact, target keyspace.ID, k int) []Contact {
+	sorted := append([]Contact(nil), contacts...)
+	sort.Slice(sorted, func(i, j int) bool { return Less(target, sorted[i].NodeID, sorted[j].NodeID) })
+	if len(sorted) > k {
+		sorted = sorted[:k]
+	}
+	return sorted
+}
+
+func sameContacts(t *testing.T, got, want []Contact) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d contacts, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("contact %d is %s, want %s", i, got[i].PeerID, want[i].PeerID)
+		}
+	}
+}
+
+func TestFindNodeConverges(t *testing.T) {
+	tn := newTestNet(300, DefaultK, nil)
+	cfg := Config{K: DefaultK, Alpha: DefaultAlpha}
+
+	for i := 0; i < 20; i++ {
+		target := keyspace.Sum(fmt.Sprintf("key-%d", i))
+		seed := tn.contacts[i]
+
+		res := FindNode(context.Background(), cfg, target, []Contact{seed}, tn.query(target))
+		sameContacts(t, res.Closest, tn.liveClosest(target))
+		if res.Rounds >= DefaultMaxRounds {
+			t.Fatalf("lookup for key-%d hit the round cap", i)
+		}
+	}
+}
+
+func TestFindNodeRoutesAroundFailedNodes(t *testing.T) {
+	tn := newTestNet(300, DefaultK, func(i int) bool { return i%4 == 1 })
+	cfg := Config{K: DefaultK, Alpha: DefaultAlpha}
+
+	failures := 0
+	for i := 0; i < 20; i++ {
+		target := keyspace.Sum(fmt.Sprintf("key-%d", i))
+		seed := tn.contacts[i*4]
+
+		res := FindNode(context.Background(), cfg, target, []Contact{seed}, tn.query(target))
+		sameContacts(t, res.Closest, tn.liveClosest(target))
+		for _, f := range res.Failed {
+			if !tn.down[f.Contact.NodeID] || !errors.Is(f.Err, errDown) {
+				t.Fatalf("%s failed with %v", f.Contact.PeerID, f.Err)
+			}
+		}
+		failures += len(res.Failed)
+	}
+	if failures == 0 {
+		t.Fatal("no lookup ran into a dead node")
+	}
+}
+
+func TestFindValueReachesEveryReplica(t *testing.T) {
+	tn := newTestNet(300, DefaultK, nil)
+	target := keyspace.Sum("value")
+	for _, c := range tn.liveClosest(target) {
+		tn.holders[c.NodeID] = true
+	}
+
+	res := FindValue(context.Background(), Config{}, target, []Contact{tn.contacts[0]}, tn.query(target))
+	if len(res.Found) != DefaultK {
+		t.Fatalf("found %d replicas, want %d", len(res.Found), DefaultK)
+	}
+	for _, c := range res.Found {
+		if !tn.holders[c.NodeID] {
+			t.Fatalf("%s reported a value it does not hold", c.PeerID)
+		}
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	seed := Contact{NodeID: keyspace.Sum("slow"), PeerID: "slow"}
+	hang := func(ctx context.Context, c Contact) (Reply, error) {
+		time.Sleep(time.Second) // ignores ctx
+		return Reply{}, nil
+	}
+
+	start := time.Now()
+	res := FindNode(context.Background(), Config{QueryTimeout: 20 * time.Millisecond}, keyspace.Sum("key"), []Contact{seed}, hang)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("lookup waited %v for a query past its deadline", elapsed)
+	}
+	if len(res.Failed) != 1 || !errors.Is(res.Failed[0].Err, ErrQueryTimeout) {
+		t.Fatalf("failures = %+v, want one ErrQueryTimeout", res.Failed)
+	}
+}
+
+func TestLookupSkipsSelf(t *testing.T) {
+	tn := newTestNet(100, DefaultK, nil)
+	self := tn.contacts[0]
+	target := self.NodeID
+
+	res := FindNode(context.Background(), Config{Self: self.NodeID}, target, []Contact{tn.contacts[1]}, tn.query(target))
+	if tn.queries[self.NodeID] != 0 {
+		t.Fatal("lookup queried its own node")
+	}
+	for _, c := range res.Closest {
+		if c.NodeID == self.NodeID {
+			t.Fatal("own node is in the closest set")
+		}
+	}
+}
+
+func TestFindValueDisjointQueriesEachContactOnce(t *testing.T) {
+	tn := newTestNet(300, DefaultK, nil)
+	target := keyspace.Sum("value")
+	for _, c := range tn.liveClosest(target) {
+		tn.holders[c.NodeID] = true
+	}
+	seeds := tn.contacts[:9]
+
+	res := FindValueDisjoint(context.Background(), Config{}, 3, target, seeds, tn.query(target))
+	if len(res.Paths) != 3 {
+		t.Fatalf("got %d paths, want 3", len(res.Paths))
+	}
+	for id, n := range tn.queries {
+		if n > 1 {
+			t.Fatalf("node %x was queried %d times", id, n)
+		}
+	}
+
+	onPath := make(map[keyspace.ID]int)
+	for i, p := range res.Paths {
+		for _, c := range p.Responded {
+			if j, ok := onPath[c.NodeID]; ok {
+				t.Fatalf("%s answered on paths %d and %d", c.PeerID, j, i)
+			}
+			onPath[c.NodeID] = i
+		}
+	}
+	if res.Reached == 0 || len(res.Merged.Found) != DefaultK {
+		t.Fatalf("%d paths found the value at %d replicas, want all %d replicas", res.Reached, len(res.Merged.Found), DefaultK)
+	}
+}
+
+func TestFindValueDisjointDealsSeedsNearestFirst(t *testing.T) {
+	tn := newTestNet(50, DefaultK, nil)
+	target := keyspace.Sum("value")
+	seeds := nearest(tn.contacts, target, 4)
+
+	// Nothing answers, so each path only ever tries its own seeds
+	dead := func(ctx context.Context, c Contact) (Reply, error) { return Reply{}, errDown }
+	res := FindValueDisjoint(context.Background(), Config{}, 2, target, []Contact{seeds[3], seeds[2], seeds[1], seeds[0]}, dead)
+
+	for i, want := range [][]Contact{{seeds[0], seeds[2]}, {seeds[1], seeds[3]}} {
+		var got []Contact
+		for _, f := range res.Paths[i].Failed {
+			got = append(got, f.Contact)
+		}
+		sort.Slice(got, func(a, b int) bool { return Less(target, got[a].NodeID, got[b].NodeID) })
+		sameContacts(t, got, want)
+	}
+}
+
+func TestFindValueDisjointSurvivesPoisonedPath(t *testing.T) {
+	tn := newTestNet(300, DefaultK, nil)
+	target := keyspace.Sum("value")
+	for _, c := range tn.liveClosest(target) {
+		tn.holders[c.NodeID] = true
+	}
+
+	// The evil seed is the nearest one, so it leads path 0. It points at
+	// made-up contacts right next to the target that never answer.
+	evil := Contact{NodeID: target, PeerID: "evil"}
+	evil.NodeID[keyspace.Size-1] ^= 0x01
+	var fakes []Contact
+	for i := 0; i < DefaultK; i++ {
+		fake := Contact{NodeID: target, PeerID: fmt.Sprintf("fake-%d", i)}
+		fake.NodeID[keyspace.Size-1] ^= byte(2 + i)
+		fakes = append(fakes, fake)
+	}
+	honest := tn.query(target)
+	query := func(ctx context.Context, c Contact) (Reply, error) {
+		switch {
+		case c.PeerID == "evil":
+			return Reply{Closer: fakes}, nil
+		case strings.HasPrefix(c.PeerID, "fake-"):
+			return Reply{}, errDown
+		}
+		return honest(ctx, c)
+	}
+
+	res := FindValueDisjoint(context.Background(), Config{}, 2, target, []Contact{evil, tn.contacts[0]}, query)
+	if len(res.Paths[0].Found) != 0 {
+		t.Fatal("the poisoned path found the value")
+	}
+	if len(res.Paths[1].Found) != DefaultK || res.Reached != 1 {
+		t.Fatalf("honest path found %d replicas and %d paths reached one, want %d and 1", len(res.Paths[1].Found), res.Reached, DefaultK)
+	}
+
+	// A single lookup led by the same seed is steered away for good
+	single := FindValue(context.Background(), Config{}, target, []Contact{evil}, query)
+	if len(single.Found) != 0 {
+		t.Fatal("a single lookup seeded only by the evil node found the value")
+	}
+}
@@ -0,0 +1,290 @@
+// Package modset is the moderator registry. A Set names the moderators of an
+// epoch and the unix time the epoch activates at. Epoch sets form a chain:
+// each must be signed by a root (governance) key, or by a quorum of the
+// members of the epoch before it. Registry keeps the sets that verify and
+// answers which one was active at a message's timestamp, which is the set a
+// message's mod certs are checked against.
+package modset
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+)
+
+var (
+	ErrBadSet      = errors.New("invalid moderator set")
+	ErrUntrusted   = errors.New("moderator set is not signed by a root key or a quorum of the previous epoch")
+	ErrConflict    = errors.New("another moderator set is known for this epoch")
+	ErrNoActiveSet = errors.New("no moderator set known for this time")
+	ErrUnanchored  = errors.New("no moderator root keys and no known moderator sets")
+)
+
+// Signature is one signer's signature over a Set.
+type Signature struct {
+	PublicKey string `json:"public_key" yaml:"public_key" bson:"public_key"`
+	Sign      string `json:"sign" yaml:"sign" bson:"sign"`
+}
+
+// Set is the moderator set of an epoch. Members are base64 ed25519 public
+// keys; ActivatesAt is a unix time. The signatures cover everything else,
+// with members in sorted order.
+type Set struct {
+	Epoch       uint64      `json:"epoch" yaml:"epoch" bson:"epoch"`
+	ActivatesAt int64       `json:"activates_at" yaml:"activates_at" bson:"activates_at"`
+	Members     []string    `json:"members" yaml:"members" bson:"members"`
+	Signatures  []Signature `json:"signatures" yaml:"signatures" bson:"signatures"`
+}
+
+// Quorum is how many members of an epoch must sign the next one: more than
+// half.
+func Quorum(members int) int {
+	return members/2 + 1
+}
+
+func (s *Set) message() string {
+	members := append([]string(nil), s.Members...)
+	sort.Strings(members)
+	return "libr-modset|" + strconv.FormatUint(s.Epoch, 10) + "|" +
+		strconv.FormatInt(s.ActivatesAt, 10) + "|" + strings.Join(members, ",")
+}
+
+// Sign adds the signature of priv to s, replacing an earlier one by the
+// same key.
+func (s *Set) Sign(priv ed25519.PrivateKey) error {
+	pubKey, sign, err := cryptoutils.SignMessage(priv, s.message())
+	if err != nil {
+		return err
+	}
+	sigs := []Signature{{PublicKey: pubKey, Sign: sign}}
+	for _, sig := range s.Signatures {
+		if sig.PublicKey != pubKey {
+			sigs = append(sigs, sig)
+		}
+	}
+	s.Signatures = sigs
+	return nil
+}
+
+// Has reports whether pubKey is a member of s.
+func (s *Set) Has(pubKey string) bool {
+	for _, m := range s.Members {
+		if m == pubKey {
+			return true
+		}
+	}
+	return false
+}
+
+// check rejects sets no signature could make valid.
+func (s *Set) check() error {
+	if len(s.Members) == 0 {
+		return fmt.Errorf("%w: epoch %d has no members", ErrBadSet, s.Epoch)
+	}
+	seen := make(map[string]bool, len(s.Members))
+	for _, m := range s.Members {
+		if m == "" || seen[m] {
+			return fmt.Errorf("%w: epoch %d has an empty or repeated member", ErrBadSet, s.Epoch)
+		}
+		seen[m] = true
+	}
+	return nil
+}
+
+// signers returns the distinct keys in trusted with a valid signature on s.
+func (s *Set) signers(trusted func(string) bool) int {
+	msg := s.message()
+	seen := make(map[string]bool)
+	for _, sig := range s.Signatures {
+		if seen[sig.PublicKey] || !trusted(sig.PublicKey) {
+			continue
+		}
+		if cryptoutils.VerifySignature(sig.PublicKey, msg, sig.Sign) {
+			seen[sig.PublicKey] = true
+		}
+	}
+	return len(seen)
+}
+
+// Registry holds the moderator sets that verified against its root keys.
+// It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	roots map[string]bool
+	sets  map[uint64]*Set
+}
+
+// NewRegistry returns an empty registry trusting the base64 ed25519 rootKeys.
+func NewRegistry(rootKeys []string) *Registry {
+	r := &Registry{roots: make(map[string]bool), sets: make(map[uint64]*Set)}
+	for _, k := range rootKeys {
+		if k = strings.TrimSpace(k); k != "" {
+			r.roots[k] = true
+		}
+	}
+	return r
+}
+
+// Add verifies s and keeps it. A set is accepted when a root key signed it,
+// or when signatures from a Quorum of the previous epoch's members are on it
+// and that epoch is already known. Activation times must grow with the
+// epoch. Adding a set that is already known is a no-op.
+func (r *Registry) Add(s Set) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if known, ok := r.sets[s.Epoch]; ok {
+		if known.message() == s.message() {
+			return nil
+		}
+		return fmt.Errorf("%w: epoch %d", ErrConflict, s.Epoch)
+	}
+
+	prev, hasPrev := r.sets[s.Epoch-1]
+	if s.Epoch == 0 {
+		hasPrev = false
+	}
+	if hasPrev && s.ActivatesAt <= prev.ActivatesAt {
+		return fmt.Errorf("%w: epoch %d activates before epoch %d", ErrBadSet, s.Epoch, prev.Epoch)
+	}
+	if next, ok := r.sets[s.Epoch+1]; ok && s.ActivatesAt >= next.ActivatesAt {
+		return fmt.Errorf("%w: epoch %d activates after epoch %d", ErrBadSet, s.Epoch, next.Epoch)
+	}
+
+	trusted := s.signers(func(k string) bool { return r.roots[k] }) > 0
+	if !trusted && hasPrev {
+		trusted = s.signers(prev.Has) >= Quorum(len(prev.Members))
+	}
+	if !trusted {
+		return fmt.Errorf("%w: epoch %d", ErrUntrusted, s.Epoch)
+	}
+
+	s.Members = append([]string(nil), s.Members...)
+	s.Signatures = append([]Signature(nil), s.Signatures...)
+	r.sets[s.Epoch] = &s
+	return nil
+}
+
+// AddAll adds sets in epoch order, so a batch can carry a whole chain. It
+// returns how many sets were new, and the errors of those it rejected.
+func (r *Registry) AddAll(sets []Set) (added int, errs []error) {
+	sorted := append([]Set(nil), sets...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Epoch < sorted[j].Epoch })
+
+	for _, s := range sorted {
+		before := r.Len()
+		if err := r.Add(s); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if r.Len() > before {
+			added++
+		}
+	}
+	return added, errs
+}
+
+// Len is how many sets r holds.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sets)
+}
+
+// Anchored returns ErrUnanchored when r trusts no root key and holds no set.
+// Such a registry can never take a set, so every moderator check against it
+// fails.
+func (r *Registry) Anchored() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.roots) == 0 && len(r.sets) == 0 {
+		return ErrUnanchored
+	}
+	return nil
+}
+
+// ActiveAt returns the set of the latest epoch activated at unix time ts.
+// It fails when no known epoch had activated by ts, or when the epoch after
+// that set is unknown while later ones are, since the missing epoch may be
+// the one active at ts.
+func (r *Registry) ActiveAt(ts int64) (*Set, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var active *Set
+	later := false
+	for _, s := range r.sets {
+		if s.ActivatesAt > ts {
+			later = true
+			continue
+		}
+		if active == nil || s.Epoch > active.Epoch {
+			active = s
+		}
+	}
+	if active == nil {
+		return nil, fmt.Errorf("%w: %d", ErrNoActiveSet, ts)
+	}
+	if _, ok := r.sets[active.Epoch+1]; !ok && later {
+		return nil, fmt.Errorf("%w: epoch %d is missing", ErrNoActiveSet, active.Epoch+1)
+	}
+	set := *active
+	return &set, nil
+}
+
+// Sets returns the sets r holds, by epoch.
+func (r *Registry) Sets() []Set {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sets := make([]Set, 0, len(r.sets))
+	for _, s := range r.sets {
+		sets = append(sets, *s)
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Epoch < sets[j].Epoch })
+	return sets
+}
+
+// Load adds the sets cached in the JSON file at path. Cached sets are
+// verified like any other, so the cache need not be trusted. A missing file
+// is not an error.
+func (r *Registry) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var sets []Set
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return fmt.Errorf("failed to parse moderator set cache: %w", err)
+	}
+	_, errs := r.AddAll(sets)
+	return errors.Join(errs...)
+}
+
+// Save writes the sets r holds to the JSON file at path.
+func (r *Registry) Save(path string) error {
+	data, err := json.MarshalIndent(r.Sets(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,221 @@
+package modset
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type testKey struct {
+	pub  string
+	priv ed25519.PrivateKey
+}
+
+func newKeys(t *testing.T, n int) []testKey {
+	t.Helper()
+	keys := make([]testKey, n)
+	for i := range keys {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = testKey{pub: base64.StdEncoding.EncodeToString(pub), priv: priv}
+	}
+	return keys
+}
+
+// newSet returns the set of members for epoch, signed by signers.
+func newSet(t *testing.T, epoch uint64, activatesAt int64, members, signers []testKey) Set {
+	t.Helper()
+	s := Set{Epoch: epoch, ActivatesAt: activatesAt}
+	for _, m := range members {
+		s.Members = append(s.Members, m.pub)
+	}
+	for _, k := range signers {
+		if err := s.Sign(k.priv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestAddRootSigned(t *testing.T) {
+	root := newKeys(t, 1)
+	mods := newKeys(t, 3)
+	r := NewRegistry([]string{root[0].pub})
+
+	if err := r.Add(newSet(t, 0, 1000, mods, mods)); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("self-signed epoch 0: got %v, want ErrUntrusted", err)
+	}
+	set := newSet(t, 0, 1000, mods, root)
+	if err := r.Add(set); err != nil {
+		t.Fatalf("root-signed epoch 0 rejected: %v", err)
+	}
+	if err := r.Add(set); err != nil || r.Len() != 1 {
+		t.Fatalf("adding a known set again: %v, %d sets", err, r.Len())
+	}
+}
+
+func TestAddQuorumChained(t *testing.T) {
+	root := newKeys(t, 1)
+	mods := newKeys(t, 5)
+	next := newKeys(t, 3)
+	r := NewRegistry([]string{root[0].pub})
+	if err := r.Add(newSet(t, 0, 1000, mods, root)); err != nil {
+		t.Fatal(err)
+	}
+
+	quorum := Quorum(len(mods))
+	below := newSet(t, 1, 2000, next, mods[:quorum-1])
+	if err := r.Add(below); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("%d of %d signatures: got %v, want ErrUntrusted", quorum-1, len(mods), err)
+	}
+
+	// Signatures by outsiders and repeated signatures do not count
+	padded := below
+	padded.Signatures = append(padded.Signatures, below.Signatures[0])
+	if err := padded.Sign(next[0].priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add(padded); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("padded signatures: got %v, want ErrUntrusted", err)
+	}
+
+	if err := r.Add(newSet(t, 1, 2000, next, mods[:quorum])); err != nil {
+		t.Fatalf("quorum-signed epoch 1 rejected: %v", err)
+	}
+
+	// Epoch 2 needs a quorum of epoch 1, not of epoch 0
+	if err := r.Add(newSet(t, 2, 3000, mods, mods)); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("epoch 2 signed by epoch 0: got %v, want ErrUntrusted", err)
+	}
+	if err := r.Add(newSet(t, 2, 3000, mods, next[:Quorum(len(next))])); err != nil {
+		t.Fatalf("epoch 2 rejected: %v", err)
+	}
+}
+
+func TestAddRejects(t *testing.T) {
+	root := newKeys(t, 1)
+	mods := newKeys(t, 3)
+	r := NewRegistry([]string{root[0].pub})
+	if err := r.Add(newSet(t, 0, 1000, mods, root)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add(newSet(t, 2, 3000, mods, root)); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		set  Set
+		want error
+	}{
+		{"conflicting epoch", newSet(t, 0, 1000, mods[:2], root), ErrConflict},
+		{"no members", newSet(t, 1, 2000, nil, root), ErrBadSet},
+		{"repeated member", newSet(t, 1, 2000, []testKey{mods[0], mods[0]}, root), ErrBadSet},
+		{"activates with the previous epoch", newSet(t, 1, 1000, mods, root), ErrBadSet},
+		{"activates after the next epoch", newSet(t, 1, 3000, mods, root), ErrBadSet},
+		{"activates before the previous epoch", newSet(t, 3, 2500, mods, root), ErrBadSet},
+	}
+	for _, c := range cases {
+		if err := r.Add(c.set); !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+	if r.Len() != 2 {
+		t.Fatalf("registry holds %d sets, want 2", r.Len())
+	}
+}
+
+func TestActiveAt(t *testing.T) {
+	root := newKeys(t, 1)
+	mods := newKeys(t, 3)
+	r := NewRegistry([]string{root[0].pub})
+	for _, s := range []Set{
+		newSet(t, 0, 1000, mods, root),
+		newSet(t, 2, 3000, mods[:2], root),
+	} {
+		if err := r.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		ts    int64
+		epoch uint64
+		err   error
+	}{
+		{999, 0, ErrNoActiveSet},
+		{3000, 2, nil},
+		{5000, 2, nil},
+		// Epoch 1 is unknown, and may have activated anywhere after 1000
+		{1000, 0, ErrNoActiveSet},
+		{2500, 0, ErrNoActiveSet},
+	}
+	for _, c := range cases {
+		set, err := r.ActiveAt(c.ts)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("ts %d: got %v, want %v", c.ts, err, c.err)
+		}
+		if err == nil && set.Epoch != c.epoch {
+			t.Fatalf("ts %d: got epoch %d, want %d", c.ts, set.Epoch, c.epoch)
+		}
+	}
+
+	// Once epoch 1 is known, its whole span resolves
+	if err := r.Add(newSet(t, 1, 2000, mods, root)); err != nil {
+		t.Fatal(err)
+	}
+	if set, err := r.ActiveAt(1000); err != nil || set.Epoch != 0 {
+		t.Fatalf("ts 1000: got %+v, %v; want epoch 0", set, err)
+	}
+	if set, err := r.ActiveAt(2500); err != nil || set.Epoch != 1 {
+		t.Fatalf("ts 2500: got %+v, %v; want epoch 1", set, err)
+	}
+
+	// The set handed out is a copy
+	set, _ := r.ActiveAt(1000)
+	set.Members = nil
+	if again, _ := r.ActiveAt(1000); len(again.Members) != len(mods) {
+		t.Fatal("changing a returned set changed the registry")
+	}
+}
+
+func TestAnchored(t *testing.T) {
+	root := newKeys(t, 1)
+	mods := newKeys(t, 3)
+
+	if err := NewRegistry(nil).Anchored(); !errors.Is(err, ErrUnanchored) {
+		t.Fatalf("empty registry: got %v, want ErrUnanchored", err)
+	}
+	if err := NewRegistry([]string{" ", ""}).Anchored(); !errors.Is(err, ErrUnanchored) {
+		t.Fatalf("blank root keys: got %v, want ErrUnanchored", err)
+	}
+	if err := NewRegistry([]string{root[0].pub}).Anchored(); err != nil {
+		t.Fatalf("registry with a root key: %v", err)
+	}
+
+	// Cached sets verify like any other, so a cache alone anchors nothing
+	path := filepath.Join(t.TempDir(), "modsets.json")
+	trusting := NewRegistry([]string{root[0].pub})
+	if err := trusting.Add(newSet(t, 0, 1000, mods, root)); err != nil {
+		t.Fatal(err)
+	}
+	if err := trusting.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(nil)
+	if err := r.Load(path); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("loading without the root key: got %v, want ErrUntrusted", err)
+	}
+	if err := r.Anchored(); !errors.Is(err, ErrUnanchored) {
+		t.Fatalf("got %v, want ErrUnanchored", err)
+	}
+
+	r = NewRegistry([]string{root[0].pub})
+	if err := r.Load(path); err != nil || r.Len() != 1 {
+		t.Fatalf("loading the cache: %v, %d sets", err, r.Len())
+	}
+}
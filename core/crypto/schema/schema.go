@@ -0,0 +1,112 @@
+// Package schema runs the forward-only SQL migrations of the db node and the
+// moderator client, recording every applied step in a schema_version table.
+package schema
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrTooNew is returned by Migrate when the database was written by a newer
+// binary than the one running.
+var ErrTooNew = errors.New("database schema is newer than this binary")
+
+// Migration is one forward-only schema step. Versions start at 1 and must
+// be contiguous; a shipped migration is never edited, only followed by a
+// new one.
+type Migration struct {
+	Version     int
+	Description string
+	SQL         string
+}
+
+// Placeholder is the bind parameter style of a SQL driver.
+type Placeholder int
+
+const (
+	Question Placeholder = iota // ?, as SQLite takes
+	Dollar                      // $1, $2, ..., as PostgreSQL takes
+)
+
+// Migrate brings db up to the last migration in the list. Each step runs in
+// its own transaction.
+func Migrate(db *sql.DB, placeholder Placeholder, migrations []Migration) error {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("migration list out of order: position %d has version %d", i+1, m.Version)
+		}
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version     INTEGER PRIMARY KEY,
+		description TEXT    NOT NULL,
+		applied_at  BIGINT  NOT NULL
+	);`); err != nil {
+		return fmt.Errorf("creating schema_version table: %w", err)
+	}
+
+	current, err := Version(db)
+	if err != nil {
+		return err
+	}
+
+	latest := len(migrations)
+	if current > latest {
+		return fmt.Errorf("%w: database is at v%d, binary supports up to v%d", ErrTooNew, current, latest)
+	}
+
+	insert := placeholder.rebind("INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)")
+	for _, m := range migrations[current:] {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("migration v%d: starting transaction: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(m.SQL); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration v%d (%s): %w", m.Version, m.Description, err)
+		}
+		if _, err := tx.Exec(insert, m.Version, m.Description, time.Now().Unix()); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration v%d: recording version: %w", m.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration v%d: commit: %w", m.Version, err)
+		}
+		log.Printf("Applied migration v%d: %s", m.Version, m.Description)
+	}
+
+	return nil
+}
+
+// Version returns the highest applied migration, or 0 for a database that
+// has never been migrated.
+func Version(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// rebind rewrites the ? placeholders of query into p's style.
+func (p Placeholder) rebind(query string) string {
+	if p != Dollar {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
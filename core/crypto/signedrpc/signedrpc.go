@@ -0,0 +1,177 @@
+// Package signedrpc wraps the body of a mutating RPC in an envelope signed by
+// the sender's ed25519 key. The signature covers the route, the target peer,
+// a timestamp and a nonce. A receiver refuses envelopes that are unsigned,
+// outside its timestamp window, meant for another route or peer, or signed
+// with a key that does not belong to the peer the relay says sent them.
+//
+// Replay protection covers the envelope only. A NonceCache refuses nonces it
+// has seen, and since it lives in memory it also refuses envelopes signed
+// before it was created, which an earlier process may have accepted. The
+// body is not bound to its first envelope: whoever holds a copy of it, such
+// as a ReportCert, can seal it again under their own key and peer ID.
+// Receivers that must not apply a body twice have to make it single-use
+// themselves.
+package signedrpc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+)
+
+// DefaultWindow is how far an envelope's timestamp may drift from the
+// receiver's clock, in either direction.
+const DefaultWindow = 2 * time.Minute
+
+var (
+	ErrUnsigned     = errors.New("request is not signed")
+	ErrBadSignature = errors.New("bad request signature")
+	ErrWrongTarget  = errors.New("request was signed for another route or peer")
+	ErrExpired      = errors.New("request timestamp outside the accepted window")
+	ErrReplayed     = errors.New("request nonce already seen")
+	ErrWrongSender  = errors.New("request was signed by a key the sender does not own")
+)
+
+// mutatingRoutes are the POST routes that must arrive in an Envelope.
+var mutatingRoutes = map[string]bool{
+	"store":  true,
+	"delete": true,
+}
+
+// Mutating reports whether route has to be signed.
+func Mutating(route string) bool {
+	return mutatingRoutes[route]
+}
+
+// Envelope is the signed form of a mutating RPC body.
+type Envelope struct {
+	Route     string          `json:"route"`
+	To        string          `json:"to"`
+	Ts        int64           `json:"ts"`
+	Nonce     string          `json:"nonce"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// signedMessage is what the signature covers. The body is hashed in its
+// compact form, the form json.Marshal puts on the wire.
+func (e *Envelope) signedMessage() (string, error) {
+	var body bytes.Buffer
+	if err := json.Compact(&body, e.Body); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body.Bytes())
+	return "libr-rpc|" + e.Route + "|" + e.To + "|" + strconv.FormatInt(e.Ts, 10) + "|" + e.Nonce + "|" +
+		base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// Seal signs body for route on peer to and returns the encoded envelope.
+func Seal(priv ed25519.PrivateKey, route, to string, body []byte) ([]byte, error) {
+	var nonce [16]byte
+	rand.Read(nonce[:])
+
+	env := &Envelope{
+		Route: route,
+		To:    to,
+		Ts:    time.Now().Unix(),
+		Nonce: base64.RawURLEncoding.EncodeToString(nonce[:]),
+		Body:  body,
+	}
+	msg, err := env.signedMessage()
+	if err != nil {
+		return nil, fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	env.PublicKey, env.Signature, err = cryptoutils.SignMessage(priv, msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// Open checks that data is an envelope for route on peer self, signed with a
+// key senderOwns accepts, within window of now, after nonces was created and
+// not seen before by nonces, and returns it. senderOwns reports whether a key belongs to the peer that
+// sent the request.
+func Open(data []byte, route, self string, senderOwns func(ed25519.PublicKey) bool, now time.Time, window time.Duration, nonces *NonceCache) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Signature == "" || env.PublicKey == "" || len(env.Body) == 0 {
+		return nil, ErrUnsigned
+	}
+	if env.Route != route || env.To != self {
+		return nil, ErrWrongTarget
+	}
+	if d := now.Sub(time.Unix(env.Ts, 0)); d > window || d < -window {
+		return nil, fmt.Errorf("%w: %s off", ErrExpired, d.Round(time.Second))
+	}
+
+	msg, err := env.signedMessage()
+	if err != nil || !cryptoutils.VerifySignature(env.PublicKey, msg, env.Signature) {
+		return nil, ErrBadSignature
+	}
+	pub, err := base64.StdEncoding.DecodeString(env.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize || !senderOwns(pub) {
+		return nil, ErrWrongSender
+	}
+
+	// Nonces seen before the cache existed are lost, so nothing signed
+	// then can be told apart from a replay
+	if env.Ts <= nonces.started.Unix() {
+		return nil, fmt.Errorf("%w: signed before this node started", ErrReplayed)
+	}
+	// Only remember nonces of genuine requests, so junk cannot fill the cache
+	if !nonces.Add(env.PublicKey, env.Nonce, now) {
+		return nil, ErrReplayed
+	}
+	return &env, nil
+}
+
+// NonceCache remembers the nonces seen within the timestamp window. Entries
+// older than twice the window are dropped, since envelopes that old fail
+// the timestamp check anyway.
+type NonceCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	started time.Time
+	seen    map[string]time.Time
+	pruned  time.Time
+}
+
+// NewNonceCache returns an empty cache that has seen no nonce since
+// started, normally the time the process came up. Open refuses envelopes
+// signed up to that second.
+func NewNonceCache(window time.Duration, started time.Time) *NonceCache {
+	return &NonceCache{window: window, started: started, seen: make(map[string]time.Time)}
+}
+
+// Add records nonce for pubKey and reports whether it was new.
+func (c *NonceCache) Add(pubKey, nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.pruned) > c.window {
+		for k, t := range c.seen {
+			if now.Sub(t) > 2*c.window {
+				delete(c.seen, k)
+			}
+		}
+		c.pruned = now
+	}
+
+	key := pubKey + "|" + nonce
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+	c.seen[key] = now
+	return true
+}
@@ -0,0 +1,127 @@
+package signedrpc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+// ownedBy stands in for the relay's sender stamp: only pub belongs to the
+// sender.
+func ownedBy(pub ed25519.PublicKey) func(ed25519.PublicKey) bool {
+	return func(key ed25519.PublicKey) bool { return bytes.Equal(key, pub) }
+}
+
+// newNonceCache returns a cache that started well before any envelope the
+// test seals.
+func newNonceCache() *NonceCache {
+	return NewNonceCache(DefaultWindow, time.Now().Add(-DefaultWindow))
+}
+
+func TestOpen(t *testing.T) {
+	pub, priv := newKey(t)
+	body := []byte(`{"sign":"abc"}`)
+	data, err := Seal(priv, "store", "peer-db", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonces := newNonceCache()
+	env, err := Open(data, "store", "peer-db", ownedBy(pub), time.Now(), DefaultWindow, nonces)
+	if err != nil {
+		t.Fatalf("valid envelope rejected: %v", err)
+	}
+	if !bytes.Equal(env.Body, body) {
+		t.Fatalf("body is %s, want %s", env.Body, body)
+	}
+	if _, err := Open(data, "store", "peer-db", ownedBy(pub), time.Now(), DefaultWindow, nonces); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("second open: got %v, want ErrReplayed", err)
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	pub, priv := newKey(t)
+	data, err := Seal(priv, "delete", "peer-db", []byte(`{"mode":"report"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(data, []byte("report"), []byte("delete"), 1)
+
+	cases := []struct {
+		name  string
+		data  []byte
+		route string
+		self  string
+		now   time.Time
+		want  error
+	}{
+		{"unsigned", []byte(`{"mode":"report"}`), "delete", "peer-db", time.Now(), ErrUnsigned},
+		{"other route", data, "store", "peer-db", time.Now(), ErrWrongTarget},
+		{"other peer", data, "delete", "peer-other", time.Now(), ErrWrongTarget},
+		{"expired", data, "delete", "peer-db", time.Now().Add(2 * DefaultWindow), ErrExpired},
+		{"tampered body", tampered, "delete", "peer-db", time.Now(), ErrBadSignature},
+	}
+	for _, c := range cases {
+		_, err := Open(c.data, c.route, c.self, ownedBy(pub), c.now, DefaultWindow, newNonceCache())
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestOpenRejectsResealedBody(t *testing.T) {
+	authorPub, authorPriv := newKey(t)
+	_, mallory := newKey(t)
+
+	data, err := Seal(authorPriv, "delete", "peer-db", []byte(`{"mode":"delete"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+
+	// A captured body sealed again under another key fails when the relay
+	// stamps the author's peer as the sender
+	resealed, err := Seal(mallory, "delete", "peer-db", env.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(resealed, "delete", "peer-db", ownedBy(authorPub), time.Now(), DefaultWindow, newNonceCache()); !errors.Is(err, ErrWrongSender) {
+		t.Fatalf("got %v, want ErrWrongSender", err)
+	}
+}
+
+func TestOpenRejectsEnvelopesFromBeforeStart(t *testing.T) {
+	pub, priv := newKey(t)
+	data, err := Seal(priv, "store", "peer-db", []byte(`{"sign":"abc"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted node has lost the nonces it saw, so it cannot tell this
+	// envelope from one it already accepted
+	restarted := NewNonceCache(DefaultWindow, time.Now())
+	if _, err := Open(data, "store", "peer-db", ownedBy(pub), time.Now(), DefaultWindow, restarted); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("got %v, want ErrReplayed", err)
+	}
+
+	// Envelopes signed after the start go through
+	later := NewNonceCache(DefaultWindow, time.Now().Add(-2*time.Second))
+	if _, err := Open(data, "store", "peer-db", ownedBy(pub), time.Now(), DefaultWindow, later); err != nil {
+		t.Fatalf("envelope signed after the start rejected: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+// Command directory serves a directory over HTTP, standing in for the shared
+// MongoDB directory on a local or test network. Point db nodes and clients
+// at it with LIBR_DIRECTORY=http://<host>:<port>.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/libr-forum/Libr/core/crypto/directory"
+)
+
+func main() {
+	addr := flag.String("addr", ":8088", "address to listen on")
+	from := flag.String("from", "directory.yaml", "directory to serve: a YAML/JSON file or any LIBR_DIRECTORY URI")
+	flag.Parse()
+
+	dir, err := directory.Open(context.Background(), *from)
+	if err != nil {
+		log.Fatalf("Failed to open directory: %v", err)
+	}
+
+	fmt.Printf("📒 Serving directory %s on %s\n", *from, *addr)
+	log.Fatal(http.ListenAndServe(*addr, directory.Handler(dir)))
+}
@@ -2,46 +2,218 @@ package config
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/libr-forum/Libr/core/crypto/schema"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 const K = 4
 const RepMajority = 0.5
 
-// DB is the global SQLite connection
+// Alpha is how many queries an iterative lookup keeps in flight per round.
+const Alpha = 3
+
+// ReplacementCacheSize bounds the replacement cache of each k-bucket.
+const ReplacementCacheSize = K
+
+// RepublishInterval is how often a db node pushes every cert it holds to
+// the K nodes currently closest to the cert's minute key.
+const RepublishInterval = 10 * time.Minute
+
+// FindValuePageSize is the find_value and find_range page size when a
+// request sets no limit; MaxFindValuePageSize caps what a request may ask
+// for. Both keep a page well inside one stream read on the client.
+const FindValuePageSize = 20
+const MaxFindValuePageSize = 50
+
+// MaxRangeMinutes caps how many minute keys one find_range request may span.
+const MaxRangeMinutes = 120
+
+// RoutingTableFlushInterval is how often routing table changes are written
+// to the database.
+const RoutingTableFlushInterval = 30 * time.Second
+
+// MaintenanceInterval is how often the routing table maintenance loop runs.
+const MaintenanceInterval = time.Minute
+
+// BucketRefreshAfter is how long a bucket may go without activity before
+// maintenance refreshes it with a lookup for a random ID in its range.
+const BucketRefreshAfter = time.Hour
+
+// PingAfter is how long a node may go unseen before maintenance pings it.
+const PingAfter = 5 * time.Minute
+
+// MaxFailedPings is how many consecutive failed pings evict a node from the
+// routing table. Override with MAX_FAILED_PINGS.
+var MaxFailedPings = getEnvInt("MAX_FAILED_PINGS", 3)
+
+// NodeIDDifficulty is how many leading zero bits the S/Kademlia ID puzzle
+// demands from every node ID proof. 0 turns the puzzle off; all nodes of a
+// network must agree on it. Override with NODE_ID_DIFFICULTY.
+var NodeIDDifficulty = getEnvInt("NODE_ID_DIFFICULTY", 0)
+
+// ChallengeTTL is how long a node ID challenge stays valid after it is issued.
+const ChallengeTTL = time.Minute
+
+// RPCWindow is how far the timestamp of a signed store or delete request may
+// be from the local clock before it is rejected as expired.
+const RPCWindow = 2 * time.Minute
+
+// SyncInterval is how often a db node reconciles each minute it holds with
+// the other replicas of that minute.
+const SyncInterval = 5 * time.Minute
+
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// DB is the global database connection
 var DB *sql.DB
 
-// InitDB initializes the SQLite database
+// Driver is the database/sql driver name DB was opened with
+var Driver string
+
+// DirectoryURI returns the directory bootstrap nodes, moderators and relays
+// are read from, set by LIBR_DIRECTORY: a mongodb:// or mongodb+srv:// URI,
+// an http(s):// directory server or the path of a YAML/JSON directory file.
+// There is no default; unset, it is empty and directory.Open refuses it. Like
+// the DB settings, it may come from .env.
+func DirectoryURI() string {
+	godotenv.Load()
+	return getEnv("LIBR_DIRECTORY", "")
+}
+
+// A db node publishes a signed presence record, valid for PresenceTTL, to
+// the directory on startup and every PresenceInterval.
+const (
+	PresenceTTL      = 10 * time.Minute
+	PresenceInterval = 3 * time.Minute
+)
+
+// ModRootKeys returns the base64 ed25519 governance keys trusted to sign
+// moderator sets of any epoch, set by LIBR_MOD_ROOT_KEYS as a comma-separated
+// list. Without one, only sets chained from an already cached epoch verify.
+func ModRootKeys() []string {
+	godotenv.Load()
+	return strings.Split(os.Getenv("LIBR_MOD_ROOT_KEYS"), ",")
+}
+
+// ModSetSyncInterval is how often a db node fetches new moderator sets from
+// the directory.
+const ModSetSyncInterval = 10 * time.Minute
+
+// ModSetCachePath is where verified moderator sets are cached, next to the
+// SQLite database.
+func ModSetCachePath() string {
+	return filepath.Join(filepath.Dir(getDBPath()), "mod_sets.json")
+}
+
+// DBConfig selects the storage backend of a db node.
+type DBConfig struct {
+	Driver string
+	DSN    string
+}
+
+// LoadDBConfig reads the storage backend from the environment. DB_DRIVER
+// picks "sqlite3" (default) or "postgres". Postgres takes its DSN from
+// DATABASE_URL, or builds one from the POSTGRES_* variables.
+func LoadDBConfig() (*DBConfig, error) {
+	driver := os.Getenv("DB_DRIVER")
+	switch driver {
+	case "", "sqlite", DriverSQLite:
+		dbPath := getDBPath()
+		if dbPath == "" {
+			return nil, fmt.Errorf("could not resolve SQLite DB path")
+		}
+		return &DBConfig{Driver: DriverSQLite, DSN: dbPath}, nil
+
+	case "postgresql", DriverPostgres:
+		if url := os.Getenv("DATABASE_URL"); url != "" {
+			return &DBConfig{Driver: DriverPostgres, DSN: url}, nil
+		}
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			getEnv("POSTGRES_HOST", "localhost"),
+			getEnv("POSTGRES_PORT", "5432"),
+			getEnv("POSTGRES_USER", "user"),
+			getEnv("POSTGRES_PASSWORD", "password"),
+			getEnv("POSTGRES_DB", "libr"),
+			getEnv("POSTGRES_SSLMODE", "disable"),
+		)
+		return &DBConfig{Driver: DriverPostgres, DSN: dsn}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q", driver)
+	}
+}
+
+// InitDB opens the database selected by LoadDBConfig
 func InitDB() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("No .env file found or couldn't load it")
 	}
 
-	dbPath := getDBPath()
-	err = os.MkdirAll(filepath.Dir(dbPath), os.ModePerm)
+	cfg, err := LoadDBConfig()
 	if err != nil {
-		log.Fatalf("Failed to create DB directory: %v", err)
+		log.Fatalf("Failed to load DB config: %v", err)
 	}
 
-	DB, err = sql.Open("sqlite3", dbPath)
+	if cfg.Driver == DriverSQLite {
+		err = os.MkdirAll(filepath.Dir(cfg.DSN), os.ModePerm)
+		if err != nil {
+			log.Fatalf("Failed to create DB directory: %v", err)
+		}
+	}
+
+	DB, err = sql.Open(cfg.Driver, cfg.DSN)
 	if err != nil {
-		log.Fatalf("Failed to connect to SQLite DB: %v", err)
+		log.Fatalf("Failed to connect to %s DB: %v", cfg.Driver, err)
+	}
+	Driver = cfg.Driver
+
+	if err := DB.Ping(); err != nil {
+		log.Fatalf("Failed to ping %s DB: %v", cfg.Driver, err)
 	}
 
-	err = createTables()
+	migrations, placeholder := SQLiteMigrations, schema.Question
+	if Driver == DriverPostgres {
+		migrations, placeholder = PgMigrations, schema.Dollar
+	}
+	err = schema.Migrate(DB, placeholder, migrations)
+	if errors.Is(err, schema.ErrTooNew) {
+		log.Fatalf("Refusing to start: %v. Upgrade the db node binary.", err)
+	}
 	if err != nil {
-		log.Printf("Failed to create tables: %v", err)
+		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
-	log.Println("SQLite database initialized successfully.")
+	log.Printf("%s database initialized successfully.", Driver)
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
 }
 
 func getDBPath() string {
@@ -63,40 +235,3 @@ func getDBPath() string {
 	dbPath := filepath.Join(baseDir, "libr", "db", "libr.db")
 	return dbPath
 }
-
-func createTables() error {
-	createMsgCertTable := `
-	CREATE TABLE IF NOT EXISTS msgcert (
-		sender TEXT NOT NULL,
-		content TEXT NOT NULL,
-		ts INTEGER NOT NULL,
-		mod_certs TEXT NOT NULL,
-		sign TEXT NOT NULL,
-		deleted INTEGER DEFAULT 0
-	);
-	CREATE INDEX IF NOT EXISTS indx_ts ON msgcert(ts);
-	CREATE INDEX IF NOT EXISTS indx_ts_sender ON msgcert(ts, sender);`
-
-	createRoutingTable := `
-	CREATE TABLE IF NOT EXISTS RoutingTable (
-		bucket_idx INTEGER NOT NULL,
-		NodeID     BLOB    NOT NULL,
-		PeerID     TEXT    NOT NULL,
-		LastSeen   INTEGER NOT NULL,
-		PRIMARY KEY (bucket_idx, NodeID)
-	);
-	CREATE INDEX IF NOT EXISTS node_idx
-		ON RoutingTable(bucket_idx, LastSeen);`
-
-	// Create msgcert table + indexes
-	if _, err := DB.Exec(createMsgCertTable); err != nil {
-		return fmt.Errorf("creating msgcert table: %w", err)
-	}
-
-	// Create routing table + indexes
-	if _, err := DB.Exec(createRoutingTable); err != nil {
-		return fmt.Errorf("creating RoutingTable: %w", err)
-	}
-
-	return nil
-}
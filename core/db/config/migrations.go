@@ -0,0 +1,122 @@
+package config
+
+import "github.com/libr-forum/Libr/core/crypto/schema"
+
+// SQLiteMigrations is the schema history of libr.db. Append only.
+var SQLiteMigrations = []schema.Migration{
+	{
+		Version:     1,
+		Description: "msgcert and RoutingTable tables",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS msgcert (
+		sender TEXT NOT NULL,
+		content TEXT NOT NULL,
+		ts INTEGER NOT NULL,
+		mod_certs TEXT NOT NULL,
+		sign TEXT NOT NULL,
+		deleted INTEGER DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS indx_ts ON msgcert(ts);
+	CREATE INDEX IF NOT EXISTS indx_ts_sender ON msgcert(ts, sender);
+
+	CREATE TABLE IF NOT EXISTS RoutingTable (
+		bucket_idx INTEGER NOT NULL,
+		NodeID     BLOB    NOT NULL,
+		PeerID     TEXT    NOT NULL,
+		LastSeen   INTEGER NOT NULL,
+		PRIMARY KEY (bucket_idx, NodeID)
+	);
+	CREATE INDEX IF NOT EXISTS node_idx
+		ON RoutingTable(bucket_idx, LastSeen);`,
+	},
+	{
+		Version:     2,
+		Description: "unique msgcert per signature",
+		SQL: `
+	UPDATE msgcert SET deleted = (
+		SELECT MAX(deleted) FROM msgcert AS dup WHERE dup.sign = msgcert.sign
+	);
+	DELETE FROM msgcert WHERE rowid NOT IN (
+		SELECT MIN(rowid) FROM msgcert GROUP BY sign
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS uniq_sign ON msgcert(sign);`,
+	},
+	{
+		Version:     3,
+		Description: "keep the ReportCert of deleted msgcerts",
+		SQL: `
+	ALTER TABLE msgcert ADD COLUMN repmod_certs TEXT;
+	ALTER TABLE msgcert ADD COLUMN rep_mode TEXT;`,
+	},
+	{
+		Version:     4,
+		Description: "persist k-bucket replacement caches",
+		SQL: `
+	ALTER TABLE RoutingTable ADD COLUMN Replacement INTEGER NOT NULL DEFAULT 0;`,
+	},
+	{
+		Version:     5,
+		Description: "drop routing table rows from the 160-bit keyspace",
+		SQL: `
+	DELETE FROM RoutingTable;`,
+	},
+}
+
+// PgMigrations is the schema history of the PostgreSQL backend. Append only.
+var PgMigrations = []schema.Migration{
+	{
+		Version:     1,
+		Description: "msgcerts and routing_table tables",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS msgcerts (
+		sender    TEXT    NOT NULL,
+		content   TEXT    NOT NULL,
+		ts        BIGINT  NOT NULL,
+		mod_certs JSONB   NOT NULL,
+		sign      TEXT    NOT NULL,
+		deleted   INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS indx_ts ON msgcerts(ts);
+	CREATE INDEX IF NOT EXISTS indx_ts_sender ON msgcerts(ts, sender);
+
+	CREATE TABLE IF NOT EXISTS routing_table (
+		bucket_idx INTEGER NOT NULL,
+		node_id    BYTEA   NOT NULL,
+		peer_id    TEXT    NOT NULL,
+		last_seen  BIGINT  NOT NULL,
+		PRIMARY KEY (bucket_idx, node_id)
+	);
+	CREATE INDEX IF NOT EXISTS node_idx
+		ON routing_table(bucket_idx, last_seen);`,
+	},
+	{
+		Version:     2,
+		Description: "unique msgcert per signature",
+		SQL: `
+	UPDATE msgcerts SET deleted = dup.deleted
+	FROM (SELECT sign, MAX(deleted) AS deleted FROM msgcerts GROUP BY sign) AS dup
+	WHERE dup.sign = msgcerts.sign;
+	DELETE FROM msgcerts a USING msgcerts b
+	WHERE a.sign = b.sign AND a.ctid > b.ctid;
+	CREATE UNIQUE INDEX IF NOT EXISTS uniq_sign ON msgcerts(sign);`,
+	},
+	{
+		Version:     3,
+		Description: "keep the ReportCert of deleted msgcerts",
+		SQL: `
+	ALTER TABLE msgcerts ADD COLUMN IF NOT EXISTS repmod_certs JSONB;
+	ALTER TABLE msgcerts ADD COLUMN IF NOT EXISTS rep_mode TEXT;`,
+	},
+	{
+		Version:     4,
+		Description: "persist k-bucket replacement caches",
+		SQL: `
+	ALTER TABLE routing_table ADD COLUMN IF NOT EXISTS replacement BOOLEAN NOT NULL DEFAULT FALSE;`,
+	},
+	{
+		Version:     5,
+		Description: "drop routing table rows from the 160-bit keyspace",
+		SQL: `
+	DELETE FROM routing_table;`,
+	},
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/libr-forum/Libr/core/crypto/schema"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(DriverSQLite, filepath.Join(t.TempDir(), "libr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func versionRows(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrate(t *testing.T) {
+	db := openTestDB(t)
+	if err := schema.Migrate(db, schema.Question, SQLiteMigrations); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := schema.Version(db); v != len(SQLiteMigrations) {
+		t.Fatalf("database at v%d, want v%d", v, len(SQLiteMigrations))
+	}
+
+	// A second start finds nothing to do
+	if err := schema.Migrate(db, schema.Question, SQLiteMigrations); err != nil {
+		t.Fatalf("re-run failed: %v", err)
+	}
+	if n := versionRows(t, db); n != len(SQLiteMigrations) {
+		t.Fatalf("schema_version holds %d rows after a re-run, want %d", n, len(SQLiteMigrations))
+	}
+}
+
+func TestMigrateRollsBackFailingStep(t *testing.T) {
+	db := openTestDB(t)
+	migrations := []schema.Migration{
+		{Version: 1, Description: "first table", SQL: `CREATE TABLE first (x INTEGER);`},
+		{Version: 2, Description: "broken step", SQL: `
+	CREATE TABLE second (x INTEGER);
+	INSERT INTO missing VALUES (1);`},
+	}
+
+	if err := schema.Migrate(db, schema.Question, migrations); err == nil {
+		t.Fatal("broken migration applied")
+	}
+	if v, _ := schema.Version(db); v != 1 {
+		t.Fatalf("database at v%d after a failed v2, want v1", v)
+	}
+	var name string
+	err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'second'").Scan(&name)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("table from the failed step survived the rollback (%v)", err)
+	}
+
+	// Once fixed, the step applies on the next start
+	migrations[1].SQL = `CREATE TABLE second (x INTEGER);`
+	if err := schema.Migrate(db, schema.Question, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := schema.Version(db); v != 2 {
+		t.Fatalf("database at v%d, want v2", v)
+	}
+}
+
+func TestMigrateTooNew(t *testing.T) {
+	db := openTestDB(t)
+	if err := schema.Migrate(db, schema.Question, SQLiteMigrations); err != nil {
+		t.Fatal(err)
+	}
+
+	older := SQLiteMigrations[:len(SQLiteMigrations)-1]
+	if err := schema.Migrate(db, schema.Question, older); !errors.Is(err, schema.ErrTooNew) {
+		t.Fatalf("older binary got %v, want ErrTooNew", err)
+	}
+	if n := versionRows(t, db); n != len(SQLiteMigrations) {
+		t.Fatalf("schema_version holds %d rows, want %d", n, len(SQLiteMigrations))
+	}
+}
+
+func TestMigrationsInOrder(t *testing.T) {
+	for name, migrations := range map[string][]schema.Migration{"sqlite": SQLiteMigrations, "postgres": PgMigrations} {
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Fatalf("%s: position %d has version %d", name, i+1, m.Version)
+			}
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/libr-forum/Libr/core/crypto/keyspace"
+
 type Msg struct {
 	Content string `json:"content"`
 	Ts      int64  `json:"ts"`
@@ -24,6 +26,10 @@ type RetMsgCert struct {
 	ModCerts  []ModCert `json:"mod_certs"`
 	Sign      string    `json:"sign"`
 	Deleted   string    `json:"deleted"`
+	// RepModCerts and RepMode carry the ReportCert that deleted the cert so
+	// replicas can prove the deletion to each other.
+	RepModCerts []ModCert `json:"repmod_certs,omitempty"`
+	RepMode     string    `json:"rep_mode,omitempty"`
 }
 
 type DataToSign struct {
@@ -55,12 +61,23 @@ type DeleteCert struct {
 }
 
 type Node struct {
-	NodeId    [20]byte `json:"node_id"`
-	PeerId    string   `json:"peer_id"`
-	BucketIdx int      `json:"-"`
-	LastSeen  int64    `json:"lastseen"`
+	NodeId    keyspace.ID `json:"node_id"`
+	PeerId    string      `json:"peer_id"`
+	BucketIdx int         `json:"-"`
+	LastSeen  int64       `json:"lastseen"`
+
+	// FailedPings counts consecutive failed liveness pings
+	FailedPings int `json:"-"`
+
+	// Verified is set once the node proved it owns NodeId under PeerId
+	Verified bool `json:"-"`
 }
 
 type KBucket struct {
 	Nodes []*Node
+	// Replacements holds recently rejected candidates, freshest last, to
+	// promote when a node is evicted
+	Replacements []*Node `json:"replacements,omitempty"`
+	// LastRefreshed is the unix time the bucket last saw activity
+	LastRefreshed int64 `json:"last_refreshed"`
 }
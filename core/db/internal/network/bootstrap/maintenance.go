@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/network"
+	"github.com/libr-forum/Libr/core/db/internal/routing"
+)
+
+// Maintain keeps the routing table healthy until ctx is cancelled. Every
+// interval it pings nodes not seen for config.PingAfter, evicting those that
+// keep failing, and refreshes buckets idle for config.BucketRefreshAfter.
+func Maintain(ctx context.Context, localNode *models.Node, rt *routing.RoutingTable, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			CheckLiveness(localNode, rt)
+			RefreshBuckets(localNode, rt)
+		}
+	}
+}
+
+// CheckLiveness pings every node not seen for config.PingAfter.
+func CheckLiveness(localNode *models.Node, rt *routing.RoutingTable) {
+	for _, n := range rt.StaleNodes(config.PingAfter) {
+		if err := network.SendPing(localNode.PeerId, n); err != nil {
+			if rt.MarkFailed(n.PeerId) {
+				fmt.Printf("🗑️ Dropped unresponsive node %s\n", n.PeerId)
+			}
+			continue
+		}
+		rt.MarkSeen(n.PeerId)
+	}
+}
+
+// RefreshBuckets runs a lookup for a random ID in the range of every bucket
+// idle for config.BucketRefreshAfter and inserts every node that answered.
+func RefreshBuckets(localNode *models.Node, rt *routing.RoutingTable) {
+	if network.GlobalPinger == nil {
+		fmt.Println("❌ Pinger not registered in network")
+		return
+	}
+	for _, index := range rt.StaleBuckets(config.BucketRefreshAfter) {
+		target := rt.RandomIDInBucket(index)
+		fmt.Printf("🔄 Refreshing bucket %d\n", index)
+		res := network.FindNode(context.Background(), localNode, target, rt.FindClosest(target, config.K))
+		for _, n := range network.ToNodes(res.Responded) {
+			rt.InsertNode(localNode, n, network.GlobalPinger)
+		}
+		rt.TouchBucket(index)
+	}
+}
@@ -0,0 +1,127 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/network"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+	"github.com/libr-forum/Libr/core/db/internal/routing"
+)
+
+type acceptPinger struct{}
+
+func (acceptPinger) Ping(peerId string, target *models.Node) error { return nil }
+
+// stubNetwork routes every POST to post, and admits every node the table
+// asks to verify.
+func stubNetwork(t *testing.T, post func(peerId, route string, body []byte) ([]byte, error)) {
+	t.Helper()
+	prevPinger, prevPost := network.GlobalPinger, network.GlobalPostFunc
+	t.Cleanup(func() { network.GlobalPinger, network.GlobalPostFunc = prevPinger, prevPost })
+	network.GlobalPinger = acceptPinger{}
+	network.GlobalPostFunc = post
+}
+
+func bucketNode(rt *routing.RoutingTable, index, i int) *models.Node {
+	return &models.Node{
+		NodeId:   rt.RandomIDInBucket(index),
+		PeerId:   fmt.Sprintf("peer-%d-%d", index, i),
+		Verified: true,
+	}
+}
+
+func TestRefreshBuckets(t *testing.T) {
+	rt := routing.NewRoutingTable(keyspace.Sum("self"))
+	local := &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+	const idle, busy = 200, 100
+
+	rt.InsertNode(local, bucketNode(rt, idle, 0), nil)
+	rt.InsertNode(local, bucketNode(rt, busy, 0), nil)
+	rt.Buckets[idle].LastRefreshed = time.Now().Add(-2 * config.BucketRefreshAfter).Unix()
+
+	// Every node asked knows two more nodes in the idle bucket
+	found := []*models.Node{bucketNode(rt, idle, 1), bucketNode(rt, idle, 2)}
+	var mu sync.Mutex
+	var targets []keyspace.ID
+	stubNetwork(t, func(peerId, route string, body []byte) ([]byte, error) {
+		if route != "/route=find_node" {
+			return nil, fmt.Errorf("unexpected route %s", route)
+		}
+		var req map[string]string
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		target, err := node.DecodeNodeID(req["find_node_id"])
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		targets = append(targets, target)
+		mu.Unlock()
+		return json.Marshal(found)
+	})
+
+	RefreshBuckets(local, rt)
+
+	if len(targets) == 0 {
+		t.Fatal("no lookup ran")
+	}
+	for _, target := range targets {
+		if got := routing.GetBucketIndex(rt.SelfID, target); got != idle {
+			t.Fatalf("looked up an ID in bucket %d, want only bucket %d", got, idle)
+		}
+	}
+	for _, n := range found {
+		held := false
+		for _, m := range rt.Nodes() {
+			held = held || m.NodeId == n.NodeId
+		}
+		if !held {
+			t.Fatalf("%s found by the refresh was not added", n.PeerId)
+		}
+	}
+	if stale := rt.StaleBuckets(config.BucketRefreshAfter); len(stale) != 0 {
+		t.Fatalf("buckets %v still stale after the refresh", stale)
+	}
+}
+
+func TestCheckLiveness(t *testing.T) {
+	rt := routing.NewRoutingTable(keyspace.Sum("self"))
+	local := &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+
+	quiet, fresh := bucketNode(rt, 200, 0), bucketNode(rt, 100, 0)
+	rt.InsertNode(local, quiet, nil)
+	rt.InsertNode(local, fresh, nil)
+	rt.Buckets[200].Nodes[0].LastSeen = time.Now().Add(-2 * config.PingAfter).Unix()
+
+	var mu sync.Mutex
+	pinged := make(map[string]int)
+	stubNetwork(t, func(peerId, route string, body []byte) ([]byte, error) {
+		mu.Lock()
+		pinged[peerId]++
+		mu.Unlock()
+		return nil, errors.New("no answer")
+	})
+
+	for i := 0; i < config.MaxFailedPings; i++ {
+		CheckLiveness(local, rt)
+	}
+	if pinged[fresh.PeerId] != 0 {
+		t.Fatalf("pinged %s, which was seen just now", fresh.PeerId)
+	}
+	if pinged[quiet.PeerId] != config.MaxFailedPings {
+		t.Fatalf("pinged %s %d times, want %d", quiet.PeerId, pinged[quiet.PeerId], config.MaxFailedPings)
+	}
+	nodes := rt.Nodes()
+	if len(nodes) != 1 || nodes[0].PeerId != fresh.PeerId {
+		t.Fatalf("table holds %d nodes after the quiet one kept failing, want only %s", len(nodes), fresh.PeerId)
+	}
+}
@@ -2,22 +2,58 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
 	"github.com/libr-forum/Libr/core/db/internal/models"
 	"github.com/libr-forum/Libr/core/db/internal/node"
 	"github.com/libr-forum/Libr/core/db/internal/routing"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
 )
 
+// PingRequest is one leg of the node ID handshake. The first carries a
+// nonce in Challenge for the receiver to prove itself against; the second
+// carries the receiver's challenge and the sender's Proof over it.
 type PingRequest struct {
-	NodeID string `json:"node_id"`
+	NodeID    string      `json:"node_id"`
+	PeerID    string      `json:"peer_id"`
+	Challenge string      `json:"challenge"`
+	Proof     *node.Proof `json:"proof,omitempty"`
 }
 
+// PingResponse has Status "challenge" with the responder's Proof and its own
+// Challenge after the first leg, "ok" once the sender's proof checked out,
+// and "rejected" otherwise.
 type PingResponse struct {
-	Status string `json:"status"`
+	Status    string      `json:"status"`
+	Challenge string      `json:"challenge,omitempty"`
+	Proof     *node.Proof `json:"proof,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// SyncRequest carries the digest a replica holds for one minute key. Cursor
+// is the Next of the previous response, empty for the first page.
+type SyncRequest struct {
+	Ts     int64    `json:"ts"`
+	Digest []string `json:"digest"`
+	Cursor string   `json:"cursor,omitempty"`
+}
+
+// SyncResponse answers a SyncRequest with one page of the certs the
+// requester is missing or holds in a different state, and the digest
+// entries the responder wants pushed back to it. Want is only sent with the
+// first page; Next is the cursor of the following page, empty on the last.
+type SyncResponse struct {
+	Type   string              `json:"type"`
+	Values []models.RetMsgCert `json:"values"`
+	Want   []string            `json:"want"`
+	Next   string              `json:"next,omitempty"`
+	Error  string              `json:"error,omitempty"`
 }
 
 type StoredResponse struct {
@@ -26,34 +62,50 @@ type StoredResponse struct {
 	Nodes  []*models.Node `json:"nodes"`
 }
 
-func HandlePing(body interface{}, localNode *models.Node, rt *routing.RoutingTable) []byte {
-	bodyMap, ok := body.(map[string]interface{})
-	if !ok {
-		fmt.Println("Invalid body format in HandlePing")
-		return nil
+func HandlePing(req PingRequest, fromPeerID string, localNode *models.Node, rt *routing.RoutingTable) []byte {
+	reject := func(msg string) []byte {
+		fmt.Println("🚫 Ping rejected:", msg)
+		data, _ := json.Marshal(PingResponse{Status: "rejected", Error: msg})
+		return data
 	}
-	nodeIDStr, ok := bodyMap["node_id"].(string)
-	fmt.Println("node_id in handle ping:", nodeIDStr)
-	if !ok || nodeIDStr == "" {
-		fmt.Println("Missing or invalid node_id in HandlePing")
-		return nil
+
+	if req.NodeID == "" || req.PeerID == "" || req.Challenge == "" {
+		return reject("missing node_id, peer_id or challenge")
 	}
-	peerId, ok := bodyMap["peer_id"].(string)
-	fmt.Println("peer_id in handle ping:", peerId)
-	if !ok || peerId == "" {
-		fmt.Println("Missing or invalid peer_id in HandlePing")
-		return nil
+	// The relay stamps every request with its sender's peer ID
+	if fromPeerID != "" && fromPeerID != req.PeerID {
+		return reject("peer_id does not match the sending peer")
 	}
-	nodeID, err := node.DecodeNodeID(nodeIDStr)
+	nodeID, err := node.DecodeNodeID(strings.TrimSpace(req.NodeID))
 	if err != nil {
-		fmt.Println("Error decoding node ID:", err)
-		return nil
+		return reject("invalid node_id: " + err.Error())
+	}
+
+	// First leg: prove ourselves against the sender's nonce and hand it a
+	// challenge of our own
+	if req.Proof == nil {
+		proof, err := node.Prove(req.Challenge, localNode.PeerId)
+		if err != nil {
+			fmt.Println("Error proving local node ID:", err)
+			return nil
+		}
+		data, _ := json.Marshal(PingResponse{Status: "challenge", Challenge: node.NewChallenge(req.PeerID), Proof: &proof})
+		return data
+	}
+
+	// Second leg: the sender answers our challenge
+	if err := node.CheckChallenge(req.Challenge, req.PeerID); err != nil {
+		return reject(err.Error())
+	}
+	if err := node.VerifyProof(*req.Proof, req.Challenge, req.PeerID, nodeID); err != nil {
+		return reject(err.Error())
 	}
 
 	senderNode := &models.Node{
 		NodeId:   nodeID,
-		PeerId:   peerId,
+		PeerId:   req.PeerID,
 		LastSeen: time.Now().Unix(),
+		Verified: true,
 	}
 
 	if GlobalPinger == nil {
@@ -70,19 +122,31 @@ func HandlePing(body interface{}, localNode *models.Node, rt *routing.RoutingTab
 	return data
 }
 
-func FindValueHandler(key string, localNode *models.Node, rt *routing.RoutingTable) []byte {
+func FindValueHandler(key, cursor string, limit int, localNode *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) []byte {
 
-	values, closest := SendFindValue(key, localNode, rt)
+	values, next, closest, err := SendFindValue(key, cursor, limit, localNode, rt, store)
 
-	if values != nil {
+	if err != nil {
+		fmt.Println("Error serving find_value:", err)
+		type ErrorResponse struct {
+			Type  string `json:"type"`
+			Error string `json:"error"`
+		}
+		data, _ := json.Marshal(ErrorResponse{Type: "error", Error: err.Error()})
+		return data
+	}
+
+	if closest == nil {
 		fmt.Println("Found the value")
 		type FoundResponse struct {
 			Type   string              `json:"type"`
 			Values []models.RetMsgCert `json:"values"`
+			Next   string              `json:"next,omitempty"`
 		}
 		resp := FoundResponse{
 			Type:   "found",
 			Values: values,
+			Next:   next,
 		}
 		data, err := json.Marshal(resp)
 		if err != nil {
@@ -109,6 +173,65 @@ func FindValueHandler(key string, localNode *models.Node, rt *routing.RoutingTab
 	}
 }
 
+// RangeResponse answers find_range with one page of the certs the node holds
+// in the span and the union of the k closest nodes it knows for each minute
+// key, so a client can keep one candidate set across the whole span. Next is
+// the cursor of the following page, empty on the last one.
+type RangeResponse struct {
+	Type   string              `json:"type"`
+	Values []models.RetMsgCert `json:"values"`
+	Next   string              `json:"next,omitempty"`
+	Nodes  []*models.Node      `json:"nodes"`
+}
+
+func FindRangeHandler(fromStr, toStr, cursor string, limit int, localNode *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) []byte {
+	type ErrorResponse struct {
+		Type  string `json:"type"`
+		Error string `json:"error"`
+	}
+
+	from, err1 := strconv.ParseInt(fromStr, 10, 64)
+	to, err2 := strconv.ParseInt(toStr, 10, 64)
+	if err1 != nil || err2 != nil {
+		data, _ := json.Marshal(ErrorResponse{Type: "error", Error: "from and to must be unix timestamps"})
+		return data
+	}
+	from, to = storage.MinuteOf(from), storage.MinuteOf(to)
+	if to < from || (to-from)/60+1 > config.MaxRangeMinutes {
+		data, _ := json.Marshal(ErrorResponse{Type: "error", Error: fmt.Sprintf("range must span 1 to %d minutes", config.MaxRangeMinutes)})
+		return data
+	}
+
+	values, next, err := storage.GetMsgCertRange(store, from, to, cursor, limit)
+	if err != nil {
+		data, _ := json.Marshal(ErrorResponse{Type: "error", Error: err.Error()})
+		return data
+	}
+
+	resp := RangeResponse{
+		Type:   "range",
+		Values: values,
+		Next:   next,
+	}
+	seen := make(map[keyspace.ID]bool)
+	for minute := from; minute <= to; minute += 60 {
+		key := node.GenerateNodeID(strconv.FormatInt(minute, 10))
+		for _, n := range rt.FindClosest(key, config.K) {
+			if !seen[n.NodeId] {
+				seen[n.NodeId] = true
+				resp.Nodes = append(resp.Nodes, n)
+			}
+		}
+	}
+
+	fmt.Printf("find_range %d-%d at %s: %d values, %d nodes\n", from, to, localNode.PeerId, len(resp.Values), len(resp.Nodes))
+	data, err := json.Marshal(resp)
+	if err != nil {
+		fmt.Println("Error while marshaling the RangeResponse: ", err)
+	}
+	return data
+}
+
 func FindNodeHandler(body interface{}, localNode *models.Node, rt *routing.RoutingTable) []byte {
 	bodyMap, ok := body.(map[string]interface{})
 	fmt.Printf("[DEBUG] find_node_id type: %T, value: %#v\n", bodyMap["find_node_id"], bodyMap["find_node_id"])
@@ -167,8 +290,9 @@ func FindNodeHandler(body interface{}, localNode *models.Node, rt *routing.Routi
 	// Lookup closest nodes to the target ID
 	closest := SendFindNode(decKey, rt)
 
-	// Insert sender node into routing table
-	rt.InsertNode(localNode, senderNode, GlobalPinger)
+	// Insert sender node into routing table. It has not proven its ID yet,
+	// so InsertNode runs the handshake first; don't hold up the reply for it.
+	go rt.InsertNode(localNode, senderNode, GlobalPinger)
 
 	data, err := json.Marshal(closest)
 	if err != nil {
@@ -179,7 +303,7 @@ func FindNodeHandler(body interface{}, localNode *models.Node, rt *routing.Routi
 	return data
 }
 
-func StoreHandler(body interface{}, localNode *models.Node, rt *routing.RoutingTable) []byte {
+func StoreHandler(body interface{}, localNode *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) []byte {
 	// Optionally, validate and use public_key for senderNode if needed
 
 	var msgcert models.MsgCert
@@ -194,9 +318,9 @@ func StoreHandler(body interface{}, localNode *models.Node, rt *routing.RoutingT
 	keyBytes := node.GenerateNodeID(strconv.FormatInt(tsmin, 10))
 	fmt.Println(tsmin, keyBytes)
 
-	closest, stored := StoreValue(keyBytes, &msgcert, localNode, rt)
+	closest, responsible, err := StoreValue(keyBytes, &msgcert, localNode, rt, store)
 
-	if !stored {
+	if !responsible {
 		fmt.Println("Sending list of k closest nodes")
 		type RedirectResponse struct {
 			Type  string         `json:"type"`
@@ -214,9 +338,25 @@ func StoreHandler(body interface{}, localNode *models.Node, rt *routing.RoutingT
 		return data
 	}
 
-	fmt.Println("Store at: ", localNode)
+	respType := "stored"
+	switch {
+	case errors.Is(err, storage.ErrAlreadyStored):
+		fmt.Println("Already stored at: ", localNode)
+		respType = "already_stored"
+	case err != nil:
+		fmt.Println("Error storing MsgCert:", err)
+		type ErrorResponse struct {
+			Type  string `json:"type"`
+			Error string `json:"error"`
+		}
+		data, _ := json.Marshal(ErrorResponse{Type: "error", Error: err.Error()})
+		return data
+	default:
+		fmt.Println("Store at: ", localNode)
+	}
+
 	resp := StoredResponse{
-		Type:   "stored",
+		Type:   respType,
 		Status: "ok",
 		Nodes:  closest,
 	}
@@ -228,14 +368,14 @@ func StoreHandler(body interface{}, localNode *models.Node, rt *routing.RoutingT
 	return data
 }
 
-func DeleteHandler(repCert models.ReportCert, localNode *models.Node, rt *routing.RoutingTable) []byte {
+func DeleteHandler(repCert models.ReportCert, localNode *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) []byte {
 	tsmin := repCert.Msgcert.Msg.Ts
 	tsmin -= (tsmin % 60)
 
 	keyBytes := node.GenerateNodeID(strconv.FormatInt(tsmin, 10))
 	fmt.Println(tsmin, keyBytes)
 
-	closest, err := DeleteValue(&keyBytes, &repCert, localNode, rt)
+	closest, err := DeleteValue(&keyBytes, &repCert, localNode, rt, store)
 	if err != nil {
 		fmt.Println("Error", err)
 		type ErrorResponse struct {
@@ -272,3 +412,58 @@ func DeleteHandler(repCert models.ReportCert, localNode *models.Node, rt *routin
 	data, _ := json.Marshal(resp)
 	return data
 }
+
+// SyncHandler pages through the minute in (ts, sign) order and answers with
+// up to config.MaxFindValuePageSize certs whose digest entry the requester
+// lacks.
+func SyncHandler(req SyncRequest, localNode *models.Node, store storage.MsgCertStore) []byte {
+	theirs := make(map[string]bool, len(req.Digest))
+	for _, entry := range req.Digest {
+		theirs[entry] = true
+	}
+
+	resp := SyncResponse{Type: "sync"}
+	cursor := req.Cursor
+	for full := false; !full; {
+		page, next, err := storage.GetMsgCertPage(store, req.Ts, cursor, config.MaxFindValuePageSize)
+		if err != nil {
+			data, _ := json.Marshal(SyncResponse{Type: "error", Error: err.Error()})
+			return data
+		}
+		for _, cert := range page {
+			if theirs[storage.DigestEntry(cert)] {
+				continue
+			}
+			if len(resp.Values) == config.MaxFindValuePageSize {
+				last := resp.Values[len(resp.Values)-1]
+				resp.Next = storage.EncodeCursor(&storage.Cursor{Ts: last.Msg.Ts, Sign: last.Sign})
+				full = true
+				break
+			}
+			resp.Values = append(resp.Values, cert)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if req.Cursor == "" {
+		ours := make(map[string]bool)
+		for _, cert := range storage.GetMsgCert(store, req.Ts) {
+			ours[storage.DigestEntry(cert)] = true
+		}
+		for _, entry := range req.Digest {
+			if !ours[entry] {
+				resp.Want = append(resp.Want, entry)
+			}
+		}
+	}
+
+	fmt.Printf("Sync for minute %d at %s: sending %d, wanting %d\n", storage.MinuteOf(req.Ts), localNode.PeerId, len(resp.Values), len(resp.Want))
+	data, err := json.Marshal(resp)
+	if err != nil {
+		fmt.Println("Error while marshaling the SyncResponse: ", err)
+	}
+	return data
+}
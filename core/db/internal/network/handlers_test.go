@@ -0,0 +1,177 @@
+package network
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/keycache"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+	"github.com/libr-forum/Libr/core/db/internal/routing"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
+)
+
+type acceptPinger struct{}
+
+func (acceptPinger) Ping(peerId string, target *models.Node) error { return nil }
+
+// handshake wires SendPing straight to HandlePing on a second table, with
+// the relay's sender stamp set to from.
+func handshake(t *testing.T, from string) (nodeID keyspace.ID, remote *models.Node, remoteRT *routing.RoutingTable) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keycache.PubKey, keycache.PrivKey = pub, priv
+	nodeID = node.GenerateNodeID(base64.StdEncoding.EncodeToString(pub))
+
+	// Both ends run in this process and so share the key; the remote table
+	// gets another self ID so it will take the pinging node in.
+	remote = &models.Node{NodeId: nodeID, PeerId: "peer-remote"}
+	remoteRT = routing.NewRoutingTable(keyspace.Sum("remote table"))
+
+	prevPinger, prevPost := GlobalPinger, GlobalPostFunc
+	t.Cleanup(func() { GlobalPinger, GlobalPostFunc = prevPinger, prevPost })
+	GlobalPinger = acceptPinger{}
+	GlobalPostFunc = func(peerId, route string, body []byte) ([]byte, error) {
+		var req PingRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return HandlePing(req, from, remote, remoteRT), nil
+	}
+	return nodeID, remote, remoteRT
+}
+
+func TestPingHandshake(t *testing.T) {
+	nodeID, remote, remoteRT := handshake(t, "peer-local")
+
+	if err := SendPing("peer-local", remote); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	nodes := remoteRT.Nodes()
+	if len(nodes) != 1 || nodes[0].NodeId != nodeID || nodes[0].PeerId != "peer-local" || !nodes[0].Verified {
+		t.Fatalf("remote table holds %+v, want the verified pinging node", nodes)
+	}
+}
+
+func TestPingRejectsWrongNodeID(t *testing.T) {
+	_, remote, _ := handshake(t, "peer-local")
+
+	// The remote cannot prove an ID that is not the hash of its key
+	impostor := *remote
+	impostor.NodeId = keyspace.Sum("a node ID someone else owns")
+	if err := SendPing("peer-local", &impostor); !errors.Is(err, node.ErrBadProof) {
+		t.Fatalf("got %v, want ErrBadProof", err)
+	}
+}
+
+func TestPingRejectsSpoofedPeerID(t *testing.T) {
+	_, remote, remoteRT := handshake(t, "peer-mallory")
+
+	// The relay stamps the real sender, which does not match the claim
+	if err := SendPing("peer-local", remote); err == nil {
+		t.Fatal("handshake under another peer's ID succeeded")
+	}
+	if remoteRT.Size() != 0 {
+		t.Fatal("spoofed node made it into the table")
+	}
+}
+
+func TestPingRejectsReplayedProof(t *testing.T) {
+	nodeID, remote, remoteRT := handshake(t, "")
+
+	challenge := node.NewChallenge("peer-local")
+	proof, err := node.Prove(challenge, "peer-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := base64.StdEncoding.EncodeToString(nodeID[:])
+
+	// A captured second leg does not work for another peer ID, and a
+	// challenge this node never issued is refused outright
+	for _, req := range []PingRequest{
+		{NodeID: id, PeerID: "peer-mallory", Challenge: challenge, Proof: &proof},
+		{NodeID: id, PeerID: "peer-local", Challenge: node.NewNonce(), Proof: &proof},
+	} {
+		var res PingResponse
+		if err := json.Unmarshal(HandlePing(req, "", remote, remoteRT), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != "rejected" {
+			t.Fatalf("replayed proof for %s got status %q", req.PeerID, res.Status)
+		}
+	}
+	if remoteRT.Size() != 0 {
+		t.Fatal("replayed proof put a node into the table")
+	}
+}
+
+func TestSyncHandlerPages(t *testing.T) {
+	store := storage.NewMemoryStore()
+	var held []string
+	total := 2*config.MaxFindValuePageSize + 3
+	for i := 0; i < total; i++ {
+		cert := &models.MsgCert{
+			PublicKey: "alice",
+			Msg:       models.Msg{Content: "hello", Ts: minute + int64(i%60)},
+			ModCerts:  []models.ModCert{{Sign: "mod-sign", PublicKey: "mod-key", Status: "1"}},
+			Sign:      fmt.Sprintf("sig-%03d", i),
+		}
+		if err := store.Store(cert); err != nil {
+			t.Fatal(err)
+		}
+		// The requester already holds every third cert
+		if i%3 == 0 {
+			held = append(held, storage.DigestEntry(models.RetMsgCert{Sign: cert.Sign, Deleted: "0"}))
+		}
+	}
+	unknown := storage.DigestEntry(models.RetMsgCert{Sign: "sig-elsewhere", Deleted: "0"})
+	req := SyncRequest{Ts: minute, Digest: append(held, unknown)}
+
+	seen := make(map[string]bool)
+	for pages := 0; ; pages++ {
+		var resp SyncResponse
+		if err := json.Unmarshal(SyncHandler(req, &models.Node{PeerId: "peer-local"}, store), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Type != "sync" || len(resp.Values) > config.MaxFindValuePageSize {
+			t.Fatalf("page %d: %s with %d values", pages, resp.Type, len(resp.Values))
+		}
+		if pages == 0 && (len(resp.Want) != 1 || resp.Want[0] != unknown) {
+			t.Fatalf("first page wants %v, want only %s", resp.Want, unknown)
+		}
+		if pages > 0 && len(resp.Want) != 0 {
+			t.Fatalf("page %d repeats the wanted entries", pages)
+		}
+		for _, v := range resp.Values {
+			if seen[v.Sign] {
+				t.Fatalf("%s served twice", v.Sign)
+			}
+			seen[v.Sign] = true
+		}
+		if resp.Next == "" {
+			break
+		}
+		req.Cursor = resp.Next
+	}
+	if want := total - len(held); len(seen) != want {
+		t.Fatalf("served %d certs over all pages, want %d", len(seen), want)
+	}
+
+	req.Cursor = "garbage!"
+	var resp SyncResponse
+	if err := json.Unmarshal(SyncHandler(req, &models.Node{PeerId: "peer-local"}, store), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != "error" {
+		t.Fatalf("bad cursor answered with %s", resp.Type)
+	}
+}
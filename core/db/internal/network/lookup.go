@@ -0,0 +1,63 @@
+package network
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/crypto/lookup"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// FindNode runs an iterative find_node for target on behalf of localNode,
+// starting from seeds.
+func FindNode(ctx context.Context, localNode *models.Node, target keyspace.ID, seeds []*models.Node) *lookup.Result {
+	cfg := lookup.Config{Alpha: config.Alpha, K: config.K, Self: localNode.NodeId}
+	return lookup.FindNode(ctx, cfg, target, ToContacts(seeds), findNodeQuery(localNode, target))
+}
+
+// findNodeQuery sends one find_node RPC for target.
+func findNodeQuery(localNode *models.Node, target keyspace.ID) lookup.QueryFunc {
+	jsonMap := map[string]string{
+		"peer_id":      localNode.PeerId,
+		"node_id":      base64.StdEncoding.EncodeToString(localNode.NodeId[:]),
+		"find_node_id": base64.StdEncoding.EncodeToString(target[:]),
+	}
+	jsonBytes, _ := json.Marshal(jsonMap)
+
+	return func(ctx context.Context, c lookup.Contact) (lookup.Reply, error) {
+		if GlobalPostFunc == nil {
+			return lookup.Reply{}, errors.New("POST function not registered in network")
+		}
+		resp, err := GlobalPostFunc(c.PeerID, "/route=find_node", jsonBytes)
+		if err != nil {
+			return lookup.Reply{}, err
+		}
+		var nodes []*models.Node
+		if err := json.Unmarshal(resp, &nodes); err != nil {
+			return lookup.Reply{}, err
+		}
+		return lookup.Reply{Closer: ToContacts(nodes)}, nil
+	}
+}
+
+func ToContacts(nodes []*models.Node) []lookup.Contact {
+	contacts := make([]lookup.Contact, 0, len(nodes))
+	for _, n := range nodes {
+		if n != nil {
+			contacts = append(contacts, lookup.Contact{NodeID: n.NodeId, PeerID: n.PeerId})
+		}
+	}
+	return contacts
+}
+
+func ToNodes(contacts []lookup.Contact) []*models.Node {
+	nodes := make([]*models.Node, 0, len(contacts))
+	for _, c := range contacts {
+		nodes = append(nodes, &models.Node{NodeId: c.NodeID, PeerId: c.PeerID})
+	}
+	return nodes
+}
@@ -4,6 +4,7 @@ import (
 	// ...
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"errors"
 
 	//"encoding/base64"
@@ -12,34 +13,43 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libr-forum/Libr/core/crypto/framing"
+	"github.com/libr-forum/Libr/core/crypto/signedrpc"
 	"github.com/libr-forum/Libr/core/db/config"
 	"github.com/libr-forum/Libr/core/db/internal/keycache"
 	"github.com/libr-forum/Libr/core/db/internal/models"
 	"github.com/libr-forum/Libr/core/db/internal/network"
 	"github.com/libr-forum/Libr/core/db/internal/network/bootstrap"
+	"github.com/libr-forum/Libr/core/db/internal/network/replica"
 	"github.com/libr-forum/Libr/core/db/internal/node"
 	"github.com/libr-forum/Libr/core/db/internal/routing"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
 	"github.com/libr-forum/Libr/core/db/internal/utils"
 )
 
 var Peer *ChatPeer
 var globalLocalNode *models.Node
 var GlobalRT *routing.RoutingTable
+var globalStore storage.MsgCertStore
 
 type RelayDist struct {
 	relayID string
 	dist    *big.Int
 }
 
-func RegisterLocalState(n *models.Node, rt *routing.RoutingTable) {
+func RegisterLocalState(n *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) {
 	globalLocalNode = n
 	GlobalRT = rt
+	globalStore = store
 
-	// ✅ Register POST handler
+	// ✅ Register POST and GET handlers
 	network.RegisterPOST(POST)
+	network.RegisterGET(GET)
 
 	// ✅ Register RealPinger
 	network.RegisterPinger(&network.RealPinger{})
@@ -57,26 +67,36 @@ func initDHT() {
 	}
 	nodeIdStr := base64.StdEncoding.EncodeToString(nodeId[:])
 	fmt.Println("Node ID:", nodeIdStr)
-	rt := routing.GetOrCreateRoutingTable(localNode.NodeId)
-	RegisterLocalState(localNode, rt)
+	rt := routing.GetOrCreateRoutingTable(localNode.NodeId, routing.NewTableStore(config.Driver, config.DB))
+	store := storage.NewStore(config.Driver, config.DB)
+	RegisterLocalState(localNode, rt, store)
+
+	// Keep stored certs on their K closest nodes as the network changes
+	republisher := replica.NewRepublisher(localNode, rt, store)
+	rt.SetOnNodeAdded(republisher.HandOff)
 
 	fmt.Println("🌐 Starting Kademlia node at")
 
-	empty := true
-	for _, b := range rt.Buckets {
-		if b != nil && len(b.Nodes) > 0 {
-			empty = false
-			break
-		}
-	}
-	if empty {
+	if rt.Size() == 0 {
 		// All buckets are nil
 		fmt.Println("❗ No buckets found in routing table, bootstrapping from peers...")
 		bootstrap.BootstrapFromPeers(bootstrapAddrs, localNode, rt)
+		// An empty table means the node is new or its table was dropped (as
+		// on the move to the 256-bit keyspace), so the certs it holds may
+		// sit under keys nobody looks up yet. Re-home them now rather than
+		// on the first republish tick.
+		go republisher.RepublishAll()
 	} else {
 		bootstrap.NodeUpdate(localNode, rt)
 	}
 
+	go republisher.Run(context.Background(), config.RepublishInterval)
+	go republisher.RunAntiEntropy(context.Background(), config.SyncInterval)
+	go bootstrap.Maintain(context.Background(), localNode, rt, config.MaintenanceInterval)
+	go rt.PersistEvery(context.Background(), config.RoutingTableFlushInterval)
+	go utils.RunPresence(context.Background(), PeerID, config.PresenceInterval)
+	go utils.RunModSetSync(context.Background(), config.ModSetSyncInterval)
+
 	data, _ := json.MarshalIndent(rt, "", "  ")
 	fmt.Println(string(data))
 	fmt.Println("Peer ID:", PeerID)
@@ -125,9 +145,10 @@ func GET(targetPeerID string, route string) ([]byte, error) { //"/ts=123&&id=123
 
 	GetResp, err := Peer.Send(ctx, targetPeerID, jsonReq, nil)
 	if err != nil {
-		fmt.Println("Error Sending trial get message")
+		fmt.Println("Error Sending trial get message:", err)
+		return nil, err
 	}
-	GetResp = bytes.TrimRight(GetResp, "\x00")
+	markSeen(targetPeerID)
 	return GetResp, nil //this will be json bytes with resp encoded in form of resp from the server and can be used according to utility
 }
 
@@ -154,20 +175,35 @@ func POST(targetPeerID string, route string, body []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to marshal req params: %w", err)
 	}
 
+	// Mutating routes travel in an envelope signed by this node's key
+	if signedrpc.Mutating(reqparams["route"]) {
+		body, err = signedrpc.Seal(keycache.PrivKey, reqparams["route"], targetPeerID, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign %s request: %w", reqparams["route"], err)
+		}
+	}
+
 	GetResp, err := Peer.Send(ctx, targetPeerID, jsonReq, body)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
+		if errors.Is(err, framing.ErrTimeout) {
 			fmt.Println("⏳ POST request timed out after 5s")
 		} else {
 			fmt.Println("❌ POST request failed:", err)
 		}
 		return nil, err
 	}
-	if bytes.Equal(GetResp, []byte("Target peer not found")) || GetResp == nil || len(GetResp) == 0 {
-		return nil, errors.New("ping failed: empty response")
+	if len(GetResp) == 0 {
+		return nil, errors.New("empty response")
 	}
+	markSeen(targetPeerID)
+	return GetResp, nil
+}
 
-	return bytes.TrimRight(GetResp, "\x00"), nil
+// markSeen credits a successful RPC to the routing table entry of peerId.
+func markSeen(peerId string) {
+	if GlobalRT != nil {
+		GlobalRT.MarkSeen(peerId)
+	}
 }
 
 func ServeGetReq(paramsBytes []byte) []byte {
@@ -184,7 +220,18 @@ func ServeGetReq(paramsBytes []byte) []byte {
 			fmt.Println("ts is not a string")
 		}
 		fmt.Printf("Timestamp to retrieve: %s", keyStr)
-		return network.FindValueHandler(keyStr, globalLocalNode, GlobalRT)
+		cursor, _ := params["cursor"].(string)
+		limitStr, _ := params["limit"].(string)
+		limit, _ := strconv.Atoi(limitStr)
+		return network.FindValueHandler(keyStr, cursor, limit, globalLocalNode, GlobalRT, globalStore)
+
+	case "find_range":
+		fromStr, _ := params["from"].(string)
+		toStr, _ := params["to"].(string)
+		cursor, _ := params["cursor"].(string)
+		limitStr, _ := params["limit"].(string)
+		limit, _ := strconv.Atoi(limitStr)
+		return network.FindRangeHandler(fromStr, toStr, cursor, limit, globalLocalNode, GlobalRT, globalStore)
 	}
 
 	var resp []byte
@@ -211,20 +258,24 @@ func ServePostReq(peerId string, paramsBytes []byte, bodyBytes []byte) []byte {
 
 	switch route {
 	case "ping":
-		var body map[string]interface{}
-		if err := json.Unmarshal(bodyBytes, &body); err != nil {
-			fmt.Println("Failed to unmarshal body:", err)
+		var pingReq network.PingRequest
+		if err := json.Unmarshal(bodyBytes, &pingReq); err != nil {
+			fmt.Println("Error unmarshaling into PingRequest:", err)
 			return nil
 		}
-		return network.HandlePing(body, globalLocalNode, GlobalRT)
+		return network.HandlePing(pingReq, peerId, globalLocalNode, GlobalRT)
 
 	case "store":
+		env, errResp := openSigned(route, peerId, bodyBytes)
+		if errResp != nil {
+			return errResp
+		}
 		var msgCert models.MsgCert
-		if err := json.Unmarshal(bodyBytes, &msgCert); err != nil {
+		if err := json.Unmarshal(env.Body, &msgCert); err != nil {
 			fmt.Println("Error unmarshaling into MsgCert:", err)
 			return nil
 		}
-		return network.StoreHandler(msgCert, globalLocalNode, GlobalRT)
+		return network.StoreHandler(msgCert, globalLocalNode, GlobalRT, globalStore)
 
 	case "find_node":
 		var body map[string]interface{}
@@ -235,12 +286,24 @@ func ServePostReq(peerId string, paramsBytes []byte, bodyBytes []byte) []byte {
 		return network.FindNodeHandler(body, globalLocalNode, GlobalRT)
 
 	case "delete":
+		env, errResp := openSigned(route, peerId, bodyBytes)
+		if errResp != nil {
+			return errResp
+		}
 		var repCert models.ReportCert
-		if err := json.Unmarshal(bodyBytes, &repCert); err != nil {
+		if err := json.Unmarshal(env.Body, &repCert); err != nil {
 			fmt.Println("Error unmarshaling into ReportCert:", err)
 			return nil
 		}
-		return network.DeleteHandler(repCert, globalLocalNode, GlobalRT)
+		return network.DeleteHandler(repCert, globalLocalNode, GlobalRT, globalStore)
+
+	case "sync":
+		var syncReq network.SyncRequest
+		if err := json.Unmarshal(bodyBytes, &syncReq); err != nil {
+			fmt.Println("Error unmarshaling into SyncRequest:", err)
+			return nil
+		}
+		return network.SyncHandler(syncReq, globalLocalNode, globalStore)
 
 	default:
 		fmt.Println("Unknown POST route:", route)
@@ -248,6 +311,46 @@ func ServePostReq(peerId string, paramsBytes []byte, bodyBytes []byte) []byte {
 	}
 }
 
+// rpcNonces remembers the nonces of signed requests inside config.RPCWindow.
+// It is created at startup, so requests signed before this process came up
+// are refused rather than risk replaying one the last run accepted.
+var rpcNonces = signedrpc.NewNonceCache(config.RPCWindow, time.Now())
+
+// openSigned checks the envelope of a mutating request. If it is unsigned,
+// signed by a key peerId does not own, expired, meant for another node or
+// replayed, the reason is logged and returned as an error response.
+func openSigned(route, peerId string, bodyBytes []byte) (*signedrpc.Envelope, []byte) {
+	env, err := signedrpc.Open(bodyBytes, route, globalLocalNode.PeerId, ownedBy(peerId), time.Now(), config.RPCWindow, rpcNonces)
+	if err != nil {
+		fmt.Printf("🚫 Rejected %s from %s: %v\n", route, peerId, err)
+		type ErrorResponse struct {
+			Type  string `json:"type"`
+			Error string `json:"error"`
+		}
+		resp, _ := json.Marshal(ErrorResponse{Type: "error", Error: err.Error()})
+		return nil, resp
+	}
+	return env, nil
+}
+
+// ownedBy reports whether a key belongs to peerId, the sender the relay
+// stamped on the request. Peers run under their ed25519 signing key, and
+// the peer ID of an ed25519 key embeds the key itself.
+func ownedBy(peerId string) func(ed25519.PublicKey) bool {
+	return func(pub ed25519.PublicKey) bool {
+		id, err := peer.Decode(peerId)
+		if err != nil {
+			return false
+		}
+		key, err := id.ExtractPublicKey()
+		if err != nil {
+			return false
+		}
+		raw, err := key.Raw()
+		return err == nil && bytes.Equal(raw, pub)
+	}
+}
+
 // package peer
 
 // import (
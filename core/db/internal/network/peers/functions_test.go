@@ -0,0 +1,35 @@
+package peer
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	libp2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestOwnedBy(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := libp2pcrypto.UnmarshalEd25519PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+
+	if !ownedBy(id.String())(pub) {
+		t.Fatal("key rejected for the peer it runs under")
+	}
+	if ownedBy(id.String())(other) {
+		t.Fatal("another key accepted for the peer")
+	}
+	if ownedBy("not-a-peer-id")(pub) {
+		t.Fatal("key accepted for a malformed peer ID")
+	}
+}
@@ -0,0 +1,194 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/network"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+	"github.com/libr-forum/Libr/core/db/internal/routing"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
+)
+
+// Republisher keeps the certs a db node holds replicated on the K nodes
+// closest to their minute key. It republishes everything periodically and
+// hands certs off to closer nodes as they join the routing table.
+type Republisher struct {
+	localNode *models.Node
+	rt        *routing.RoutingTable
+	store     storage.MsgCertStore
+}
+
+func NewRepublisher(localNode *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) *Republisher {
+	return &Republisher{localNode: localNode, rt: rt, store: store}
+}
+
+// Run republishes every interval until ctx is cancelled.
+func (r *Republisher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RepublishAll()
+		}
+	}
+}
+
+// RepublishAll pushes every minute the store holds to its current K closest nodes.
+func (r *Republisher) RepublishAll() {
+	minutes, err := r.store.Minutes()
+	if err != nil {
+		fmt.Println("⚠ Republish: failed to list stored minutes:", err)
+		return
+	}
+	fmt.Printf("🔁 Republishing %d minute(s)\n", len(minutes))
+	for _, minute := range minutes {
+		r.Republish(minute)
+	}
+}
+
+// Republish looks up the K nodes closest to minute's key and pushes the
+// locally held certs of that minute to each of them.
+func (r *Republisher) Republish(minute int64) {
+	if network.GlobalPostFunc == nil {
+		fmt.Println("❌ POST function not registered in network")
+		return
+	}
+
+	local, err := r.store.Get(minute)
+	if err != nil || len(local) == 0 {
+		return
+	}
+
+	key := node.GenerateNodeID(strconv.FormatInt(minute, 10))
+	for _, n := range r.lookup(key) {
+		if n.NodeId == r.localNode.NodeId {
+			continue
+		}
+		r.push(n, minute, local)
+	}
+}
+
+// HandOff pushes to n every minute for which n is now among the K closest
+// nodes known to the routing table. It is meant as RoutingTable.OnNodeAdded.
+func (r *Republisher) HandOff(n *models.Node) {
+	if n == nil || n.PeerId == "" || n.NodeId == r.localNode.NodeId {
+		return
+	}
+
+	minutes, err := r.store.Minutes()
+	if err != nil {
+		fmt.Println("⚠ HandOff: failed to list stored minutes:", err)
+		return
+	}
+
+	for _, minute := range minutes {
+		key := node.GenerateNodeID(strconv.FormatInt(minute, 10))
+		for _, c := range r.rt.FindClosest(key, config.K) {
+			if c.NodeId != n.NodeId {
+				continue
+			}
+			local, err := r.store.Get(minute)
+			if err == nil && len(local) > 0 {
+				r.push(n, minute, local)
+			}
+			break
+		}
+	}
+}
+
+// push sends target whatever it is missing out of local: certs it does not
+// hold are stored, and deletions it has not seen yet are replayed with the
+// ReportCert that caused them.
+func (r *Republisher) push(target *models.Node, minute int64, local []models.RetMsgCert) {
+	if network.GlobalGetFunc == nil || network.GlobalPostFunc == nil {
+		fmt.Println("❌ GET/POST functions not registered in network")
+		return
+	}
+
+	remote := make(map[string]bool) // sign -> deleted
+	route := fmt.Sprintf("/route=find_value&&ts=%d&&limit=%d", minute, config.MaxFindValuePageSize)
+	cursor := ""
+	for {
+		pageRoute := route
+		if cursor != "" {
+			pageRoute += "&&cursor=" + cursor
+		}
+		resp, err := network.GlobalGetFunc(target.PeerId, pageRoute)
+		if err != nil {
+			break
+		}
+		var found struct {
+			Type   string              `json:"type"`
+			Values []models.RetMsgCert `json:"values"`
+			Next   string              `json:"next"`
+		}
+		if err := json.Unmarshal(resp, &found); err != nil || found.Type != "found" {
+			break
+		}
+		for _, v := range found.Values {
+			remote[v.Sign] = v.Deleted == "1"
+		}
+		if found.Next == "" || found.Next == cursor {
+			break
+		}
+		cursor = found.Next
+	}
+
+	stored, deleted := 0, 0
+	for _, cert := range local {
+		remoteDeleted, held := remote[cert.Sign]
+		s, d := sendCert(target, cert, !held, !remoteDeleted)
+		stored += s
+		deleted += d
+	}
+
+	if stored > 0 || deleted > 0 {
+		fmt.Printf("✅ Pushed minute %d to %s: %d stored, %d deleted\n", minute, target.PeerId, stored, deleted)
+	}
+}
+
+// sendCert stores cert on target when store is set, and replays its
+// deletion when del is set and the cert is deleted locally. It reports how
+// many of each went through.
+func sendCert(target *models.Node, cert models.RetMsgCert, store, del bool) (stored, deleted int) {
+	if store {
+		msgcert := storage.ToMsgCert(cert)
+		body, _ := json.Marshal(msgcert)
+		if _, err := network.GlobalPostFunc(target.PeerId, "/route=store", body); err != nil {
+			fmt.Printf("⚠ Republish store failed: %v (PeerId: %s)\n", err, target.PeerId)
+			return 0, 0
+		}
+		stored = 1
+	}
+	if !del {
+		return stored, 0
+	}
+	repCert, ok := storage.ToReportCert(cert)
+	if !ok {
+		return stored, 0
+	}
+	body, _ := json.Marshal(repCert)
+	if _, err := network.GlobalPostFunc(target.PeerId, "/route=delete", body); err != nil {
+		fmt.Printf("⚠ Republish delete failed: %v (PeerId: %s)\n", err, target.PeerId)
+		return stored, 0
+	}
+	return stored, 1
+}
+
+// lookup runs an iterative find_node for key starting from the routing
+// table and returns the K closest nodes that answered.
+func (r *Republisher) lookup(key keyspace.ID) []*models.Node {
+	res := network.FindNode(context.Background(), r.localNode, key, r.rt.FindClosest(key, config.K))
+	return network.ToNodes(res.Closest)
+}
@@ -0,0 +1,264 @@
+package replica
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/network"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+	"github.com/libr-forum/Libr/core/db/internal/routing"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
+)
+
+const minute int64 = 1700000040
+
+// fakeNet stands in for the peers a republisher talks to: each one is a
+// MemoryStore behind the store, delete and find_value routes, and every
+// find_node is answered with all of nodes.
+type fakeNet struct {
+	mu      sync.Mutex
+	nodes   []*models.Node
+	stores  map[string]*storage.MemoryStore
+	stored  map[string]int
+	deleted map[string]int
+}
+
+// newFakeNet routes the network package's GET and POST to a fakeNet over
+// nodes for the rest of the test.
+func newFakeNet(t *testing.T, nodes []*models.Node) *fakeNet {
+	t.Helper()
+	f := &fakeNet{
+		nodes:   nodes,
+		stores:  make(map[string]*storage.MemoryStore),
+		stored:  make(map[string]int),
+		deleted: make(map[string]int),
+	}
+	prevGet, prevPost := network.GlobalGetFunc, network.GlobalPostFunc
+	t.Cleanup(func() { network.GlobalGetFunc, network.GlobalPostFunc = prevGet, prevPost })
+	network.GlobalGetFunc, network.GlobalPostFunc = f.get, f.post
+	return f
+}
+
+func (f *fakeNet) store(peerId string) *storage.MemoryStore {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stores[peerId] == nil {
+		f.stores[peerId] = storage.NewMemoryStore()
+	}
+	return f.stores[peerId]
+}
+
+func (f *fakeNet) post(peerId, route string, body []byte) ([]byte, error) {
+	switch route {
+	case "/route=find_node":
+		return json.Marshal(f.nodes)
+	case "/route=store":
+		var msgcert models.MsgCert
+		if err := json.Unmarshal(body, &msgcert); err != nil {
+			return nil, err
+		}
+		f.store(peerId).Store(&msgcert)
+		f.mu.Lock()
+		f.stored[peerId]++
+		f.mu.Unlock()
+		return []byte(`{"type":"stored","status":"ok"}`), nil
+	case "/route=delete":
+		var repCert models.ReportCert
+		if err := json.Unmarshal(body, &repCert); err != nil {
+			return nil, err
+		}
+		f.store(peerId).Delete(&repCert)
+		f.mu.Lock()
+		f.deleted[peerId]++
+		f.mu.Unlock()
+		return []byte(`{"type":"deleted","status":"ok"}`), nil
+	}
+	return nil, fmt.Errorf("unexpected route %s", route)
+}
+
+func (f *fakeNet) get(peerId, route string) ([]byte, error) {
+	params := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(route, "/"), "&&") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			params[k] = v
+		}
+	}
+	if params["route"] != "find_value" {
+		return nil, fmt.Errorf("unexpected route %s", route)
+	}
+	ts, _ := strconv.ParseInt(params["ts"], 10, 64)
+	limit, _ := strconv.Atoi(params["limit"])
+	page, next, err := storage.GetMsgCertPage(f.store(peerId), ts, params["cursor"], limit)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{"type": "found", "values": page, "next": next})
+}
+
+// holds returns the digest entries peerId holds for minute.
+func (f *fakeNet) holds(peerId string, minute int64) []string {
+	return storage.Digest(storage.GetMsgCert(f.store(peerId), minute))
+}
+
+func testCert(sender string, ts int64, sign string) *models.MsgCert {
+	return &models.MsgCert{
+		PublicKey: sender,
+		Msg:       models.Msg{Content: "hello from " + sender, Ts: ts},
+		ModCerts:  []models.ModCert{{Sign: "mod-sign", PublicKey: "mod-key", Status: "1"}},
+		Sign:      sign,
+	}
+}
+
+func deletion(cert *models.MsgCert) *models.ReportCert {
+	return &models.ReportCert{
+		Msgcert:     *cert,
+		RepModCerts: []models.ModCert{{Sign: "author-sign", PublicKey: cert.PublicKey, Status: "1"}},
+		Mode:        "delete",
+	}
+}
+
+// localStore returns a store holding a live cert and a deleted one in each
+// of minutes.
+func localStore(t *testing.T, minutes ...int64) *storage.MemoryStore {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	for _, m := range minutes {
+		live, gone := testCert("alice", m+1, fmt.Sprintf("sig-live-%d", m)), testCert("bob", m+2, fmt.Sprintf("sig-gone-%d", m))
+		for _, c := range []*models.MsgCert{live, gone} {
+			if err := store.Store(c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.Delete(deletion(gone)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func minuteKey(m int64) keyspace.ID {
+	return node.GenerateNodeID(strconv.FormatInt(m, 10))
+}
+
+func TestRepublish(t *testing.T) {
+	self := &models.Node{NodeId: keyspace.Sum("self"), PeerId: "self"}
+	rt := routing.NewRoutingTable(self.NodeId)
+	var world []*models.Node
+	for i := 0; i < 3*config.K; i++ {
+		world = append(world, &models.Node{NodeId: keyspace.Sum(fmt.Sprintf("peer-%d", i)), PeerId: fmt.Sprintf("peer-%d", i), Verified: true})
+	}
+	// The table only knows a few of them; the lookup finds the rest
+	for _, n := range world[:2] {
+		rt.InsertNode(self, n, nil)
+	}
+	net := newFakeNet(t, world)
+
+	store := localStore(t, minute)
+	want := storage.Digest(storage.GetMsgCert(store, minute))
+
+	key := minuteKey(minute)
+	sort.Slice(world, func(i, j int) bool { return keyspace.CompareDistance(key, world[i].NodeId, world[j].NodeId) < 0 })
+	closest, far := world[:config.K], world[config.K:]
+
+	// The closest node already holds both certs live: it only lacks the delete
+	first := closest[0].PeerId
+	for _, c := range storage.GetMsgCert(store, minute) {
+		cert := storage.ToMsgCert(c)
+		net.store(first).Store(&cert)
+	}
+
+	NewRepublisher(self, rt, store).Republish(minute)
+
+	for _, n := range closest {
+		if got := net.holds(n.PeerId, minute); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("%s holds %v, want %v", n.PeerId, got, want)
+		}
+	}
+	if net.stored[first] != 0 || net.deleted[first] != 1 {
+		t.Fatalf("%s got %d stores and %d deletes, want only the delete", first, net.stored[first], net.deleted[first])
+	}
+	for _, n := range far {
+		if got := net.holds(n.PeerId, minute); len(got) != 0 {
+			t.Fatalf("%s is not among the %d closest but holds %v", n.PeerId, config.K, got)
+		}
+	}
+	if got := storage.Digest(storage.GetMsgCert(store, minute)); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("local store changed to %v", got)
+	}
+
+	// A second round finds nothing missing
+	before := net.stored[closest[1].PeerId] + net.deleted[closest[1].PeerId]
+	NewRepublisher(self, rt, store).Republish(minute)
+	if after := net.stored[closest[1].PeerId] + net.deleted[closest[1].PeerId]; after != before {
+		t.Fatalf("republishing an up to date minute sent %d more certs", after-before)
+	}
+}
+
+func TestHandOff(t *testing.T) {
+	self := &models.Node{NodeId: keyspace.Sum("self"), PeerId: "self"}
+	rt := routing.NewRoutingTable(self.NodeId)
+
+	// K nodes right next to the key of far, all in one bucket, so that far
+	// stays with them whoever joins elsewhere
+	far := minute
+	var near []*models.Node
+	for i := 0; i < config.K; i++ {
+		id := minuteKey(far)
+		id[keyspace.Size-1] ^= byte(i + 1)
+		near = append(near, &models.Node{NodeId: id, PeerId: fmt.Sprintf("peer-near-%d", i), Verified: true})
+	}
+	for _, n := range near {
+		rt.InsertNode(self, n, nil)
+	}
+
+	// The newcomer sits on the key of handed, in another bucket than near
+	handed := far + 60
+	for routing.GetBucketIndex(self.NodeId, minuteKey(handed)) == routing.GetBucketIndex(self.NodeId, minuteKey(far)) {
+		handed += 60
+	}
+	newcomer := &models.Node{NodeId: minuteKey(handed), PeerId: "peer-newcomer", Verified: true}
+
+	net := newFakeNet(t, nil)
+	store := localStore(t, far, handed)
+	r := NewRepublisher(self, rt, store)
+	done := make(chan struct{})
+	rt.SetOnNodeAdded(func(n *models.Node) {
+		r.HandOff(n)
+		close(done)
+	})
+
+	rt.InsertNode(self, newcomer, nil)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HandOff did not run when the newcomer was added")
+	}
+
+	want := storage.Digest(storage.GetMsgCert(store, handed))
+	if got := net.holds(newcomer.PeerId, handed); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("newcomer holds %v for its minute, want %v", got, want)
+	}
+	if got := net.holds(newcomer.PeerId, far); len(got) != 0 {
+		t.Fatalf("newcomer is not among the %d closest to %d but was handed %v", config.K, far, got)
+	}
+	for _, n := range near {
+		if net.stored[n.PeerId]+net.deleted[n.PeerId] != 0 {
+			t.Fatalf("%s was pushed certs although it did not join", n.PeerId)
+		}
+	}
+	// HandOff only adds replicas: the local copies stay
+	for _, m := range []int64{far, handed} {
+		if got := storage.GetMsgCert(store, m); len(got) != 2 {
+			t.Fatalf("local store holds %d certs for %d after the hand off, want 2", len(got), m)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/network"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
+)
+
+// RunAntiEntropy syncs every held minute with the other replicas every
+// interval until ctx is cancelled.
+func (r *Republisher) RunAntiEntropy(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.SyncAll()
+		}
+	}
+}
+
+// SyncAll syncs every minute the store holds with the other nodes among the
+// K closest to it in the routing table.
+func (r *Republisher) SyncAll() {
+	minutes, err := r.store.Minutes()
+	if err != nil {
+		fmt.Println("⚠ Sync: failed to list stored minutes:", err)
+		return
+	}
+	for _, minute := range minutes {
+		key := node.GenerateNodeID(strconv.FormatInt(minute, 10))
+		for _, n := range r.rt.FindClosest(key, config.K) {
+			if n.NodeId == r.localNode.NodeId {
+				continue
+			}
+			if err := r.Sync(n, minute); err != nil {
+				fmt.Printf("⚠ Sync of minute %d with %s failed: %v\n", minute, n.PeerId, err)
+			}
+		}
+	}
+}
+
+// Sync reconciles one minute with target. Both sides exchange only the
+// (sign, deleted) digest first; target answers, a page at a time, with the
+// certs we are missing or hold in a different state, which are merged after
+// validation, and with the digest entries it lacks, which are pushed back
+// through the store and delete routes so target validates them the same way.
+func (r *Republisher) Sync(target *models.Node, minute int64) error {
+	if network.GlobalPostFunc == nil {
+		return fmt.Errorf("POST function not registered")
+	}
+
+	local, err := r.store.Get(minute)
+	if err != nil {
+		return err
+	}
+
+	req := network.SyncRequest{Ts: minute, Digest: storage.Digest(local)}
+	pulled := 0
+	var wanted []string
+	for {
+		body, _ := json.Marshal(req)
+		resp, err := network.GlobalPostFunc(target.PeerId, "/route=sync", body)
+		if err != nil {
+			return err
+		}
+
+		var syncResp network.SyncResponse
+		if err := json.Unmarshal(resp, &syncResp); err != nil {
+			return fmt.Errorf("decoding sync response: %w", err)
+		}
+		switch syncResp.Type {
+		case "sync":
+		case "error":
+			return fmt.Errorf("sync refused: %s", syncResp.Error)
+		default:
+			return fmt.Errorf("unexpected sync response type %q", syncResp.Type)
+		}
+
+		pulled += network.MergeValues(syncResp.Values, r.store)
+		if req.Cursor == "" {
+			wanted = syncResp.Want
+		}
+		if syncResp.Next == "" || syncResp.Next == req.Cursor {
+			break
+		}
+		req.Cursor = syncResp.Next
+	}
+
+	want := make(map[string]bool, len(wanted))
+	for _, entry := range wanted {
+		want[entry] = true
+	}
+	pushed := 0
+	for _, cert := range local {
+		if !want[storage.DigestEntry(cert)] {
+			continue
+		}
+		s, d := sendCert(target, cert, true, true)
+		pushed += s + d
+	}
+
+	if pulled > 0 || pushed > 0 {
+		fmt.Printf("✅ Synced minute %d with %s: pulled %d, pushed %d\n", minute, target.PeerId, pulled, pushed)
+	}
+	return nil
+}
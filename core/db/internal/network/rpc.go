@@ -1,22 +1,22 @@
 package network
 
 import (
-	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
 	"github.com/libr-forum/Libr/core/db/config"
 	"github.com/libr-forum/Libr/core/db/internal/models"
 	"github.com/libr-forum/Libr/core/db/internal/node"
 	"github.com/libr-forum/Libr/core/db/internal/routing"
 	"github.com/libr-forum/Libr/core/db/internal/storage"
-	"github.com/libr-forum/Libr/core/db/internal/utils"
 )
 
 var GlobalPinger Pinger
 var GlobalPostFunc func(peerId, route string, body []byte) ([]byte, error)
+var GlobalGetFunc func(peerId, route string) ([]byte, error)
 
 func RegisterPinger(p Pinger) {
 	GlobalPinger = p
@@ -26,6 +26,10 @@ func RegisterPOST(f func(peerId string, route string, body []byte) ([]byte, erro
 	GlobalPostFunc = f
 }
 
+func RegisterGET(f func(peerId string, route string) ([]byte, error)) {
+	GlobalGetFunc = f
+}
+
 type Pinger interface {
 	Ping(peerId string, target *models.Node) error
 }
@@ -36,83 +40,103 @@ func (p *RealPinger) Ping(peerId string, target *models.Node) error {
 	return SendPing(peerId, target)
 }
 
+// SendPing pings target and runs the node ID handshake with it: target
+// proves it owns target.NodeId under target.PeerId by signing our nonce, and
+// we answer the challenge it sends back with a proof of our own.
 func SendPing(peerId string, target *models.Node) error {
 	if GlobalPostFunc == nil {
 		return fmt.Errorf("POST function not registered")
 	}
 
 	nodeIDStr := node.GenerateNodeIDFromPublicKey()
-	fmt.Println("node_id in sendping:", nodeIDStr)
-	jsonMap := map[string]string{
-		"node_id": nodeIDStr,
-		"peer_id": peerId,
+	nonce := node.NewNonce()
+	res, err := postPing(target, PingRequest{NodeID: nodeIDStr, PeerID: peerId, Challenge: nonce})
+	if err != nil {
+		return err
+	}
+	if res.Status != "challenge" || res.Proof == nil {
+		return fmt.Errorf("ping failed: expected a challenge, got status %q %s", res.Status, res.Error)
+	}
+	if err := node.VerifyProof(*res.Proof, nonce, target.PeerId, target.NodeId); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
 	}
-	jsonBytes, _ := json.Marshal(jsonMap)
 
-	resp, err := GlobalPostFunc(target.PeerId, "/route=ping", jsonBytes)
+	proof, err := node.Prove(res.Challenge, peerId)
+	if err != nil {
+		return err
+	}
+	res, err = postPing(target, PingRequest{NodeID: nodeIDStr, PeerID: peerId, Challenge: res.Challenge, Proof: &proof})
 	if err != nil {
-		fmt.Println("Ping failed:", err)
 		return err
 	}
+	if res.Status != "ok" {
+		return fmt.Errorf("ping failed: status %q %s", res.Status, res.Error)
+	}
+	return nil
+}
+
+func postPing(target *models.Node, req PingRequest) (*PingResponse, error) {
+	jsonBytes, _ := json.Marshal(req)
 
-	fmt.Println("Ping Response: ", string(resp))
+	resp, err := GlobalPostFunc(target.PeerId, "/route=ping", jsonBytes)
+	if err != nil {
+		fmt.Println("Ping failed:", err)
+		return nil, err
+	}
 	if resp == nil {
-		return errors.New("ping failed: empty response")
+		return nil, errors.New("ping failed: empty response")
 	}
 
 	var res PingResponse
 	if err := json.Unmarshal(resp, &res); err != nil {
-		return err
-	}
-
-	if res.Status != "ok" {
-		return errors.New("ping failed: not ok")
+		return nil, err
 	}
-
-	return nil
+	return &res, nil
 }
 
-func SendFindNode(targetId [20]byte, rt *routing.RoutingTable) []*models.Node {
+func SendFindNode(targetId keyspace.ID, rt *routing.RoutingTable) []*models.Node {
 	ClosestNodes := rt.FindClosest(targetId, config.K)
 	return ClosestNodes
 }
 
-func StoreValue(key [20]byte, cert *models.MsgCert, self *models.Node, rt *routing.RoutingTable) ([]*models.Node, bool) {
-	closest := rt.FindClosest(key, config.K)
-	fmt.Println(closest)
-
-	selfDist := node.XORBigInt(self.NodeId, key)
-	fmt.Println(selfDist)
+// StoreValue stores cert locally when self is among the k closest nodes to
+// key. responsible reports whether that was the case; err carries the
+// storage outcome, storage.ErrAlreadyStored included.
+func StoreValue(key keyspace.ID, cert *models.MsgCert, self *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) (closest []*models.Node, responsible bool, err error) {
+	closest = rt.FindClosest(key, config.K)
 
-	if len(closest) < config.K {
-		storage.StoreMsgCert(cert)
-		return closest, true
+	responsible = len(closest) < config.K
+	for i := 0; !responsible && i < len(closest); i++ {
+		responsible = keyspace.CompareDistance(key, self.NodeId, closest[i].NodeId) < 0
 	}
-
-	for _, n := range closest {
-		if selfDist.Cmp(node.XORBigInt(n.NodeId, key)) < 0 {
-			storage.StoreMsgCert(cert)
-			return closest, true
-		}
+	if !responsible {
+		return closest, false, nil
 	}
 
-	return closest, false
+	_, err = storage.StoreMsgCert(store, cert)
+	return closest, true, err
 }
 
-func SendFindValue(key string, self *models.Node, rt *routing.RoutingTable) ([]models.RetMsgCert, []*models.Node) {
+// SendFindValue serves one page of the minute containing key. Without a
+// cursor an empty minute yields the k closest nodes instead; with one, the
+// (possibly empty) page is always returned.
+func SendFindValue(key, cursor string, limit int, self *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) (values []models.RetMsgCert, next string, closest []*models.Node, err error) {
 	ts, err := (strconv.ParseInt(key, 10, 64))
 	if err != nil {
-		return nil, nil
+		return nil, "", nil, err
 	}
 
-	found := storage.GetMsgCert(ts)
-	if len(found) > 0 {
-		return found, nil
+	found, next, err := storage.GetMsgCertPage(store, ts, cursor, limit)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if len(found) > 0 || cursor != "" {
+		return found, next, nil, nil
 	}
 
 	// Not found locally — return k closest to forward request
-	keyBytes := sha1.Sum([]byte(key))
-	return nil, rt.FindClosest(keyBytes, config.K)
+	keyBytes := node.GenerateNodeID(key)
+	return nil, "", rt.FindClosest(keyBytes, config.K), nil
 }
 
 // func DeleteValue(key *[20]byte, repCert *models.ReportCert, self *models.Node, rt *routing.RoutingTable) ([]*models.Node, error) {
@@ -144,7 +168,7 @@ func SendFindValue(key string, self *models.Node, rt *routing.RoutingTable) ([]m
 // 			return nil, nil
 // 		}
 // 	case "report":
-// 		if err := storage.ValidateRepCert(repCert, validMods); err != nil {
+// 		if err := storage.ValidateRepCert(repCert); err != nil {
 // 			return nil, err
 // 		}
 // 		closest = rt.FindClosest(*key, config.K)
@@ -188,29 +212,19 @@ func SendFindValue(key string, self *models.Node, rt *routing.RoutingTable) ([]m
 // 	return closest, nil
 // }
 
-func DeleteValue(key *[20]byte, repCert *models.ReportCert, self *models.Node, rt *routing.RoutingTable) ([]*models.Node, error) {
-	validMods, _ := utils.GetOnlineMods()
-
-	selfDist := node.XORBigInt(self.NodeId, *key)
+func DeleteValue(key *keyspace.ID, repCert *models.ReportCert, self *models.Node, rt *routing.RoutingTable, store storage.MsgCertStore) ([]*models.Node, error) {
 	closest := rt.FindClosest(*key, config.K)
 
-	close := false
-	if len(closest) < config.K {
-		close = true
-	} else {
-		lastDist := node.XORBigInt(closest[len(closest)-1].NodeId, *key)
-		if selfDist.Cmp(lastDist) < 0 {
-			close = true
-		}
-	}
+	close := len(closest) < config.K ||
+		keyspace.CompareDistance(*key, self.NodeId, closest[len(closest)-1].NodeId) < 0
 	if close {
 		// 🔒 Validate the full ReportCert (including MsgCert & RepModCerts)
 
-		if err := storage.ValidateRepCert(repCert, validMods); err != nil {
+		if err := storage.ValidateRepCert(repCert); err != nil {
 			return nil, fmt.Errorf("repCert validation failed: %v", err)
 		}
-		err := storage.DeleteMsgCert(repCert)
-		if err != nil && err.Error() != "MsgCert not found" {
+		err := storage.DeleteMsgCert(store, repCert)
+		if err != nil && !errors.Is(err, storage.ErrMsgCertNotFound) {
 			return nil, fmt.Errorf("deletion failed: %v", err)
 		}
 		return nil, nil
@@ -218,3 +232,51 @@ func DeleteValue(key *[20]byte, repCert *models.ReportCert, self *models.Node, r
 	// 📡 Forward to closest peers
 	return closest, nil
 }
+
+// MergeValues applies certs fetched from another replica to the local store.
+// Every cert goes through the same validation as the store and delete routes;
+// certs that fail it are skipped. A deleted cert is only taken with a valid
+// ReportCert, so it is never stored live on its own. It returns how many
+// certs changed locally.
+func MergeValues(values []models.RetMsgCert, store storage.MsgCertStore) int {
+	applied := 0
+	for _, value := range values {
+		var repCert *models.ReportCert
+		if value.Deleted == "1" {
+			rc, ok := storage.ToReportCert(value)
+			if !ok {
+				fmt.Println("⚠ Sync: skipped deleted MsgCert without a ReportCert:", value.Sign)
+				continue
+			}
+			if err := storage.ValidateRepCert(&rc); err != nil {
+				fmt.Println("⚠ Sync: rejected ReportCert:", err)
+				continue
+			}
+			repCert = &rc
+		}
+
+		changed := false
+		msgcert := storage.ToMsgCert(value)
+		_, err := storage.StoreMsgCert(store, &msgcert)
+		switch {
+		case err == nil:
+			changed = true
+		case errors.Is(err, storage.ErrAlreadyStored):
+		default:
+			fmt.Println("⚠ Sync: rejected MsgCert:", err)
+			continue
+		}
+
+		if repCert != nil {
+			if err := storage.DeleteMsgCert(store, repCert); err != nil {
+				fmt.Println("⚠ Sync: delete failed:", err)
+			} else {
+				changed = true
+			}
+		}
+		if changed {
+			applied++
+		}
+	}
+	return applied
+}
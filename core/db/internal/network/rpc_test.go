@@ -0,0 +1,212 @@
+package network
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/modset"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/storage"
+	"github.com/libr-forum/Libr/core/db/internal/utils"
+)
+
+// minute is the minute key every test cert is sent in.
+const minute int64 = 1700000040
+
+// signer holds the keys a test signs certs with: the author's, and those
+// of a moderator set that utils.ModSets trusts from minute on.
+type signer struct {
+	author ed25519.PrivateKey
+	mods   []ed25519.PrivateKey
+}
+
+func newSigner(t *testing.T) *signer {
+	t.Helper()
+	key := func() ed25519.PrivateKey {
+		_, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return priv
+	}
+	s := &signer{author: key()}
+	root := key()
+	set := modset.Set{ActivatesAt: minute}
+	for i := 0; i < 3; i++ {
+		s.mods = append(s.mods, key())
+		pub, _, _ := cryptoutils.SignMessage(s.mods[i], "")
+		set.Members = append(set.Members, pub)
+	}
+	if err := set.Sign(root); err != nil {
+		t.Fatal(err)
+	}
+	rootPub, _, _ := cryptoutils.SignMessage(root, "")
+	registry := modset.NewRegistry([]string{rootPub})
+	if err := registry.Add(set); err != nil {
+		t.Fatal(err)
+	}
+
+	prev := utils.ModSets
+	t.Cleanup(func() { utils.ModSets = prev })
+	utils.ModSets = registry
+	return s
+}
+
+// cert returns a MsgCert for content sent at minute+offset, approved by
+// every moderator and signed by the author.
+func (s *signer) cert(t *testing.T, content string, offset int64) *models.MsgCert {
+	t.Helper()
+	msg := models.Msg{Content: content, Ts: minute + offset}
+	var modCerts []models.ModCert
+	for _, mod := range s.mods {
+		pub, sign, err := cryptoutils.SignMessage(mod, msg.Content+strconv.FormatInt(msg.Ts, 10)+"1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		modCerts = append(modCerts, models.ModCert{Sign: sign, PublicKey: pub, Status: "1"})
+	}
+	sort.Slice(modCerts, func(i, j int) bool { return modCerts[i].PublicKey < modCerts[j].PublicKey })
+
+	data, _ := json.Marshal(models.DataToSign{Content: msg.Content, Ts: msg.Ts, ModCerts: modCerts})
+	pub, sign, err := cryptoutils.SignMessage(s.author, string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.MsgCert{PublicKey: pub, Msg: msg, ModCerts: modCerts, Sign: sign}
+}
+
+// deletion returns the ReportCert with which the author takes msgcert down.
+func (s *signer) deletion(t *testing.T, msgcert *models.MsgCert) *models.ReportCert {
+	t.Helper()
+	pub, sign, err := cryptoutils.SignMessage(s.author, msgcert.Sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.ReportCert{
+		Msgcert:     *msgcert,
+		RepModCerts: []models.ModCert{{Sign: sign, PublicKey: pub, Status: "1"}},
+		Mode:        "delete",
+	}
+}
+
+func storeAll(t *testing.T, s storage.MsgCertStore, certs ...*models.MsgCert) {
+	t.Helper()
+	for _, c := range certs {
+		if _, err := storage.StoreMsgCert(s, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// syncMinute runs one SyncHandler exchange for minute as seen by the replica
+// holding from, served by the one holding to.
+func syncMinute(t *testing.T, from, to storage.MsgCertStore) SyncResponse {
+	t.Helper()
+	req := SyncRequest{Ts: minute, Digest: storage.Digest(storage.GetMsgCert(from, minute))}
+	var resp SyncResponse
+	if err := json.Unmarshal(SyncHandler(req, &models.Node{PeerId: "peer-to"}, to), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestMergeValuesRoundTrip(t *testing.T) {
+	s := newSigner(t)
+	a, b := storage.NewMemoryStore(), storage.NewMemoryStore()
+
+	shared, gone := s.cert(t, "shared", 1), s.cert(t, "gone", 2)
+	onlyA, onlyB := s.cert(t, "only on a", 3), s.cert(t, "only on b", 4)
+	storeAll(t, a, shared, gone, onlyA)
+	storeAll(t, b, shared, gone, onlyB)
+	if err := storage.DeleteMsgCert(a, s.deletion(t, gone)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := syncMinute(t, b, a)
+	if resp.Type != "sync" || len(resp.Values) != 2 {
+		t.Fatalf("a answered %s with %d values, want sync with 2", resp.Type, len(resp.Values))
+	}
+	if got := MergeValues(resp.Values, b); got != 2 {
+		t.Fatalf("b applied %d values, want 2", got)
+	}
+
+	// b pushes back what a asked for
+	want := make(map[string]bool)
+	for _, entry := range resp.Want {
+		want[entry] = true
+	}
+	var pushed []models.RetMsgCert
+	for _, cert := range storage.GetMsgCert(b, minute) {
+		if want[storage.DigestEntry(cert)] {
+			pushed = append(pushed, cert)
+		}
+	}
+	if len(pushed) != 1 || pushed[0].Sign != onlyB.Sign {
+		t.Fatalf("a wants %d certs, want only %s", len(pushed), onlyB.Msg.Content)
+	}
+	if got := MergeValues(pushed, a); got != 1 {
+		t.Fatalf("a applied %d values, want 1", got)
+	}
+
+	digestA, digestB := storage.Digest(storage.GetMsgCert(a, minute)), storage.Digest(storage.GetMsgCert(b, minute))
+	if len(digestA) != 4 || !reflect.DeepEqual(digestA, digestB) {
+		t.Fatalf("replicas still differ:\na %v\nb %v", digestA, digestB)
+	}
+	for _, cert := range storage.GetMsgCert(b, minute) {
+		if cert.Sign != gone.Sign {
+			continue
+		}
+		if _, ok := storage.ToReportCert(cert); !ok {
+			t.Fatalf("b holds %s as %+v, want it deleted with its ReportCert", gone.Msg.Content, cert)
+		}
+	}
+	if resp = syncMinute(t, b, a); len(resp.Values) != 0 || len(resp.Want) != 0 {
+		t.Fatalf("second sync sends %d and wants %d", len(resp.Values), len(resp.Want))
+	}
+}
+
+func TestMergeValuesSkipsUnprovenDelete(t *testing.T) {
+	s := newSigner(t)
+	live := s.cert(t, "live", 1)
+
+	// A delete signed by someone other than the author
+	_, strangerKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger := &signer{author: strangerKey}
+	forged := stranger.deletion(t, live)
+
+	bare := models.RetMsgCert{PublicKey: live.PublicKey, Msg: live.Msg, ModCerts: live.ModCerts, Sign: live.Sign, Deleted: "1"}
+	badRep := bare
+	badRep.RepModCerts, badRep.RepMode = forged.RepModCerts, forged.Mode
+
+	for name, value := range map[string]models.RetMsgCert{
+		"no ReportCert":      bare,
+		"invalid ReportCert": badRep,
+	} {
+		// Neither stored live where the cert is missing...
+		empty := storage.NewMemoryStore()
+		if got := MergeValues([]models.RetMsgCert{value}, empty); got != 0 {
+			t.Fatalf("%s: applied %d values to an empty store", name, got)
+		}
+		if held := storage.GetMsgCert(empty, minute); len(held) != 0 {
+			t.Fatalf("%s: store holds %+v", name, held)
+		}
+
+		// ...nor deleted where it is held live
+		held := storage.NewMemoryStore()
+		storeAll(t, held, live)
+		if got := MergeValues([]models.RetMsgCert{value}, held); got != 0 {
+			t.Fatalf("%s: applied %d values to a store holding the cert", name, got)
+		}
+		if certs := storage.GetMsgCert(held, minute); len(certs) != 1 || certs[0].Deleted != "0" {
+			t.Fatalf("%s: store holds %+v", name, certs)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/keycache"
+)
+
+var (
+	ErrBadChallenge = errors.New("invalid or expired challenge")
+	ErrBadProof     = errors.New("node ID proof does not verify")
+)
+
+// Proof shows that a peer holds the ed25519 key its node ID is the hash of.
+// The signature covers the verifier's challenge, the prover's peer ID and its
+// node ID, so a proof can neither be replayed to another verifier nor reused
+// under another peer ID.
+type Proof struct {
+	PublicKey   string `json:"public_key"`
+	Signature   string `json:"signature"`
+	PuzzleNonce uint64 `json:"puzzle_nonce,omitempty"`
+}
+
+func proofMessage(challenge, peerID string, nodeID keyspace.ID) string {
+	return "libr-node-proof|" + challenge + "|" + peerID + "|" + base64.StdEncoding.EncodeToString(nodeID[:])
+}
+
+var localPuzzle struct {
+	once  sync.Once
+	nonce uint64
+}
+
+// Prove answers challenge for the local node running as peerID.
+func Prove(challenge, peerID string) (Proof, error) {
+	pubKeyB64 := base64.StdEncoding.EncodeToString(keycache.PubKey)
+	nodeID := GenerateNodeID(pubKeyB64)
+
+	// Solve the ID puzzle once; the nonce stays valid for the node's lifetime
+	localPuzzle.once.Do(func() {
+		localPuzzle.nonce = keyspace.SolvePuzzle(nodeID, config.NodeIDDifficulty)
+	})
+
+	_, sign, err := cryptoutils.SignMessage(keycache.PrivKey, proofMessage(challenge, peerID, nodeID))
+	if err != nil {
+		return Proof{}, err
+	}
+	return Proof{PublicKey: pubKeyB64, Signature: sign, PuzzleNonce: localPuzzle.nonce}, nil
+}
+
+// VerifyProof checks that p answers challenge for the node claiming nodeID
+// under peerID, and that it solves the ID puzzle at config.NodeIDDifficulty.
+func VerifyProof(p Proof, challenge, peerID string, nodeID keyspace.ID) error {
+	if GenerateNodeID(p.PublicKey) != nodeID {
+		return fmt.Errorf("%w: node ID is not the hash of the public key", ErrBadProof)
+	}
+	if !cryptoutils.VerifySignature(p.PublicKey, proofMessage(challenge, peerID, nodeID), p.Signature) {
+		return fmt.Errorf("%w: bad signature", ErrBadProof)
+	}
+	if !keyspace.CheckPuzzle(nodeID, p.PuzzleNonce, config.NodeIDDifficulty) {
+		return fmt.Errorf("%w: ID puzzle not solved", ErrBadProof)
+	}
+	return nil
+}
+
+// challengeKey authenticates the challenges this node hands out, so it can
+// check them later without remembering each one.
+var challengeKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// NewChallenge issues a challenge for peerID. It is only good for answers
+// from that peer, for config.ChallengeTTL.
+func NewChallenge(peerID string) string {
+	var buf [8 + 16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(time.Now().Unix()))
+	rand.Read(buf[8:])
+	return base64.RawURLEncoding.EncodeToString(append(buf[:], challengeMAC(buf[:], peerID)...))
+}
+
+// CheckChallenge reports whether challenge was issued by this node to peerID
+// and has not expired.
+func CheckChallenge(challenge, peerID string) error {
+	raw, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(raw) != 8+16+sha256.Size {
+		return ErrBadChallenge
+	}
+	if !hmac.Equal(raw[24:], challengeMAC(raw[:24], peerID)) {
+		return ErrBadChallenge
+	}
+	issued := time.Unix(int64(binary.BigEndian.Uint64(raw[:8])), 0)
+	if time.Since(issued) > config.ChallengeTTL {
+		return ErrBadChallenge
+	}
+	return nil
+}
+
+func challengeMAC(data []byte, peerID string) []byte {
+	mac := hmac.New(sha256.New, challengeKey)
+	mac.Write(data)
+	mac.Write([]byte(peerID))
+	return mac.Sum(nil)
+}
+
+// NewNonce returns a random challenge for a peer we ping, which it has to
+// answer with its own Proof.
+func NewNonce() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return base64.RawURLEncoding.EncodeToString(buf[:])
+}
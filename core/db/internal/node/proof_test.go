@@ -0,0 +1,117 @@
+package node
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/keycache"
+)
+
+func useTestKeys(t *testing.T) keyspace.ID {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keycache.PubKey, keycache.PrivKey = pub, priv
+	return GenerateNodeID(base64.StdEncoding.EncodeToString(pub))
+}
+
+func TestProofVerifies(t *testing.T) {
+	nodeID := useTestKeys(t)
+	challenge := NewNonce()
+
+	proof, err := Prove(challenge, "peer-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyProof(proof, challenge, "peer-a", nodeID); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+}
+
+func TestProofRejectsReuse(t *testing.T) {
+	nodeID := useTestKeys(t)
+	challenge := NewNonce()
+	proof, err := Prove(challenge, "peer-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := proof
+	tampered.Signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+
+	cases := []struct {
+		name      string
+		proof     Proof
+		challenge string
+		peerID    string
+		nodeID    keyspace.ID
+	}{
+		{"other challenge", proof, NewNonce(), "peer-a", nodeID},
+		{"other peer ID", proof, challenge, "peer-b", nodeID},
+		{"other node ID", proof, challenge, "peer-a", keyspace.Sum("someone else")},
+		{"bad signature", tampered, challenge, "peer-a", nodeID},
+	}
+	for _, c := range cases {
+		if err := VerifyProof(c.proof, c.challenge, c.peerID, c.nodeID); !errors.Is(err, ErrBadProof) {
+			t.Errorf("%s: got %v, want ErrBadProof", c.name, err)
+		}
+	}
+}
+
+func TestProofChecksPuzzle(t *testing.T) {
+	nodeID := useTestKeys(t)
+	defer func(d int) { config.NodeIDDifficulty = d }(config.NodeIDDifficulty)
+	config.NodeIDDifficulty = 8
+
+	challenge := NewNonce()
+	proof, err := Prove(challenge, "peer-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof.PuzzleNonce = keyspace.SolvePuzzle(nodeID, config.NodeIDDifficulty)
+	if err := VerifyProof(proof, challenge, "peer-a", nodeID); err != nil {
+		t.Fatalf("solved puzzle rejected: %v", err)
+	}
+
+	for keyspace.CheckPuzzle(nodeID, proof.PuzzleNonce, config.NodeIDDifficulty) {
+		proof.PuzzleNonce++
+	}
+	if err := VerifyProof(proof, challenge, "peer-a", nodeID); !errors.Is(err, ErrBadProof) {
+		t.Fatalf("unsolved puzzle: got %v, want ErrBadProof", err)
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	challenge := NewChallenge("peer-a")
+	if err := CheckChallenge(challenge, "peer-a"); err != nil {
+		t.Fatalf("fresh challenge rejected: %v", err)
+	}
+	if err := CheckChallenge(challenge, "peer-b"); !errors.Is(err, ErrBadChallenge) {
+		t.Fatalf("challenge accepted from another peer: %v", err)
+	}
+	if err := CheckChallenge(NewNonce(), "peer-a"); !errors.Is(err, ErrBadChallenge) {
+		t.Fatalf("challenge this node never issued accepted: %v", err)
+	}
+
+	raw, _ := base64.RawURLEncoding.DecodeString(challenge)
+	raw[len(raw)-1] ^= 0xff
+	if err := CheckChallenge(base64.RawURLEncoding.EncodeToString(raw), "peer-a"); !errors.Is(err, ErrBadChallenge) {
+		t.Fatalf("tampered challenge accepted: %v", err)
+	}
+
+	var buf [8 + 16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(time.Now().Add(-config.ChallengeTTL-time.Second).Unix()))
+	expired := base64.RawURLEncoding.EncodeToString(append(buf[:], challengeMAC(buf[:], "peer-a")...))
+	if err := CheckChallenge(expired, "peer-a"); !errors.Is(err, ErrBadChallenge) {
+		t.Fatalf("expired challenge accepted: %v", err)
+	}
+}
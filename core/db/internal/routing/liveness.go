@@ -0,0 +1,140 @@
+package routing
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// MarkSeen records a successful RPC with the node holding peerId: its
+// LastSeen is bumped, its failed-ping count reset, it moves to the tail of
+// its bucket and the bucket counts as refreshed. It reports whether the
+// table holds such a node.
+func (rt *RoutingTable) MarkSeen(peerId string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	bucket, i := rt.findPeerLocked(peerId)
+	if bucket == nil {
+		return false
+	}
+	now := time.Now().Unix()
+	n := bucket.Nodes[i]
+	n.LastSeen = now
+	n.FailedPings = 0
+	bucket.Nodes = append(bucket.Nodes[:i], bucket.Nodes[i+1:]...)
+	bucket.Nodes = append(bucket.Nodes, n)
+	bucket.LastRefreshed = now
+	return true
+}
+
+// MarkFailed records a failed ping of the node holding peerId and evicts it
+// once it has failed config.MaxFailedPings times in a row, promoting the
+// freshest candidate of the bucket's replacement cache into its slot.
+func (rt *RoutingTable) MarkFailed(peerId string) (evicted bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	bucket, i := rt.findPeerLocked(peerId)
+	if bucket == nil {
+		return false
+	}
+	n := bucket.Nodes[i]
+	n.FailedPings++
+	if n.FailedPings < config.MaxFailedPings {
+		return false
+	}
+	bucket.Nodes = append(bucket.Nodes[:i], bucket.Nodes[i+1:]...)
+	fmt.Printf("🗑️ Evicted node %x after %d failed pings\n", n.NodeId, n.FailedPings)
+
+	if promoted := promoteReplacementLocked(bucket); promoted != nil {
+		fmt.Printf("⬆️ Promoted replacement %x into the bucket\n", promoted.NodeId)
+		if rt.onNodeAdded != nil {
+			added := *promoted
+			go rt.onNodeAdded(&added)
+		}
+	}
+	return true
+}
+
+// StaleBuckets returns the indices of non-empty buckets without activity for
+// longer than maxIdle.
+func (rt *RoutingTable) StaleBuckets(maxIdle time.Duration) []int {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	cutoff := time.Now().Add(-maxIdle).Unix()
+	var stale []int
+	for i, bucket := range rt.Buckets {
+		if bucket != nil && len(bucket.Nodes) > 0 && bucket.LastRefreshed < cutoff {
+			stale = append(stale, i)
+		}
+	}
+	return stale
+}
+
+// TouchBucket marks bucket index as refreshed now.
+func (rt *RoutingTable) TouchBucket(index int) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if index < 0 || index >= len(rt.Buckets) {
+		return
+	}
+	if rt.Buckets[index] == nil {
+		rt.Buckets[index] = &models.KBucket{}
+	}
+	rt.Buckets[index].LastRefreshed = time.Now().Unix()
+}
+
+// RandomIDInBucket returns a random ID that GetBucketIndex places in bucket
+// index, i.e. whose XOR distance to SelfID has bit length index+1.
+func (rt *RoutingTable) RandomIDInBucket(index int) keyspace.ID {
+	var dist keyspace.ID
+	rand.Read(dist[:])
+
+	// Clear every bit above index, then set bit index itself
+	byteIdx := len(dist) - 1 - index/8
+	for i := 0; i < byteIdx; i++ {
+		dist[i] = 0
+	}
+	bit := byte(1) << (index % 8)
+	dist[byteIdx] &= bit - 1
+	dist[byteIdx] |= bit
+
+	var id keyspace.ID
+	for i := range id {
+		id[i] = rt.SelfID[i] ^ dist[i]
+	}
+	return id
+}
+
+// StaleNodes returns copies of the nodes not seen for longer than maxAge.
+func (rt *RoutingTable) StaleNodes(maxAge time.Duration) []*models.Node {
+	cutoff := time.Now().Add(-maxAge).Unix()
+	var stale []*models.Node
+	for _, n := range rt.Nodes() {
+		if n.LastSeen < cutoff {
+			stale = append(stale, n)
+		}
+	}
+	return stale
+}
+
+func (rt *RoutingTable) findPeerLocked(peerId string) (*models.KBucket, int) {
+	for _, bucket := range rt.Buckets {
+		if bucket == nil {
+			continue
+		}
+		for i, n := range bucket.Nodes {
+			if n.PeerId == peerId {
+				return bucket, i
+			}
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,155 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// pingerFunc adapts a function to Pinger.
+type pingerFunc func(peerId string, target *models.Node) error
+
+func (f pingerFunc) Ping(peerId string, target *models.Node) error { return f(peerId, target) }
+
+var silence = pingerFunc(func(string, *models.Node) error { return errors.New("no answer") })
+
+// bucketNode returns a verified node that falls in bucket index of rt.
+func bucketNode(rt *RoutingTable, index, i int) *models.Node {
+	return &models.Node{
+		NodeId:   rt.RandomIDInBucket(index),
+		PeerId:   fmt.Sprintf("peer-%d-%d", index, i),
+		Verified: true,
+	}
+}
+
+func peerIDs(nodes []*models.Node) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.PeerId
+	}
+	return ids
+}
+
+func TestMarkFailedEvicts(t *testing.T) {
+	rt := NewRoutingTable(keyspace.Sum("self"))
+	local := &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+	n := testNode(1)
+	rt.InsertNode(local, n, nil)
+
+	for i := 1; i < config.MaxFailedPings; i++ {
+		if rt.MarkFailed(n.PeerId) {
+			t.Fatalf("evicted after %d failed pings, want %d", i, config.MaxFailedPings)
+		}
+	}
+
+	// An answer in between starts the count over
+	if !rt.MarkSeen(n.PeerId) {
+		t.Fatal("MarkSeen lost the node")
+	}
+	for i := 1; i < config.MaxFailedPings; i++ {
+		if rt.MarkFailed(n.PeerId) {
+			t.Fatalf("evicted after %d failed pings following an answer", i)
+		}
+	}
+	if !rt.MarkFailed(n.PeerId) {
+		t.Fatalf("not evicted after %d failed pings", config.MaxFailedPings)
+	}
+	if rt.Size() != 0 {
+		t.Fatalf("table holds %d nodes after the eviction", rt.Size())
+	}
+	if rt.MarkFailed(n.PeerId) || rt.MarkSeen(n.PeerId) {
+		t.Fatal("evicted node is still known")
+	}
+}
+
+func TestFullBucketPingsOldest(t *testing.T) {
+	rt := NewRoutingTable(keyspace.Sum("self"))
+	local := &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+	const index = 200
+
+	var members []*models.Node
+	for i := 0; i < config.K; i++ {
+		n := bucketNode(rt, index, i)
+		members = append(members, n)
+		if got := rt.InsertNode(local, n, nil); got != resultAppended {
+			t.Fatalf("filling the bucket: %s", got)
+		}
+	}
+	oldest, next := members[0], members[1]
+
+	// The oldest node answers, so it stays and moves to the tail
+	first := bucketNode(rt, index, config.K)
+	var pinged []string
+	record := pingerFunc(func(_ string, target *models.Node) error {
+		pinged = append(pinged, target.PeerId)
+		return nil
+	})
+	if got := rt.InsertNode(local, first, record); got != resultRejected {
+		t.Fatalf("insert into a full bucket with a live head: %s", got)
+	}
+	if len(pinged) != 1 || pinged[0] != oldest.PeerId {
+		t.Fatalf("pinged %v, want only the oldest node %s", pinged, oldest.PeerId)
+	}
+	nodes := rt.Buckets[index].Nodes
+	if len(nodes) != config.K || nodes[len(nodes)-1].PeerId != oldest.PeerId {
+		t.Fatalf("bucket is %v, want %s at the tail", peerIDs(nodes), oldest.PeerId)
+	}
+
+	// The new head stops answering: it keeps its slot until it has missed
+	// MaxFailedPings pings, then the newcomer takes it
+	second := bucketNode(rt, index, config.K+1)
+	for i := 1; i < config.MaxFailedPings; i++ {
+		if got := rt.InsertNode(local, second, silence); got != resultRejected {
+			t.Fatalf("miss %d of the head: %s", i, got)
+		}
+	}
+	if got := rt.InsertNode(local, second, silence); got != resultReplaced {
+		t.Fatalf("miss %d of the head: %s", config.MaxFailedPings, got)
+	}
+	for _, n := range rt.Nodes() {
+		if n.PeerId == next.PeerId {
+			t.Fatalf("unresponsive %s kept", next.PeerId)
+		}
+	}
+	if !rt.holds(second.NodeId, second.PeerId) || len(rt.Buckets[index].Nodes) != config.K {
+		t.Fatalf("bucket is %v, want %s in it", peerIDs(rt.Buckets[index].Nodes), second.PeerId)
+	}
+}
+
+func TestStaleBuckets(t *testing.T) {
+	rt := NewRoutingTable(keyspace.Sum("self"))
+	local := &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+
+	for _, index := range []int{0, 17, 200, 255} {
+		for i := 0; i < 20; i++ {
+			if got := GetBucketIndex(rt.SelfID, rt.RandomIDInBucket(index)); got != index {
+				t.Fatalf("random ID for bucket %d lands in bucket %d", index, got)
+			}
+		}
+	}
+
+	for _, index := range []int{10, 20} {
+		rt.InsertNode(local, bucketNode(rt, index, 0), nil)
+	}
+	if stale := rt.StaleBuckets(time.Minute); len(stale) != 0 {
+		t.Fatalf("freshly filled buckets %v reported stale", stale)
+	}
+
+	// Empty buckets are never stale: there is nothing to refresh
+	rt.Buckets[10].LastRefreshed = time.Now().Add(-2 * time.Hour).Unix()
+	rt.Buckets[30].LastRefreshed = time.Now().Add(-2 * time.Hour).Unix()
+	stale := rt.StaleBuckets(time.Hour)
+	if len(stale) != 1 || stale[0] != 10 {
+		t.Fatalf("stale buckets are %v, want [10]", stale)
+	}
+
+	rt.TouchBucket(10)
+	if stale := rt.StaleBuckets(time.Hour); len(stale) != 0 {
+		t.Fatalf("stale buckets after a refresh are %v", stale)
+	}
+}
@@ -0,0 +1,104 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// persistKey identifies a stored row: the primary key of the routing table.
+type persistKey struct {
+	bucketIdx int
+	nodeID    keyspace.ID
+}
+
+type persistedRow struct {
+	peerID      string
+	lastSeen    int64
+	replacement bool
+}
+
+// Flush writes the changes since the last successful flush to the table
+// store: new and updated nodes are upserted, nodes that have left the table
+// (or its replacement caches) are deleted. It blocks until the store has
+// committed.
+func (rt *RoutingTable) Flush() error {
+	if rt.store == nil {
+		return fmt.Errorf("no table store set")
+	}
+
+	rt.persistMu.Lock()
+	defer rt.persistMu.Unlock()
+
+	current := make(map[persistKey]persistedRow)
+	var nodes, replacements, removed []*models.Node
+	collect := func(list []*models.Node, replacement bool) {
+		for _, n := range list {
+			key := persistKey{bucketIdx: n.BucketIdx, nodeID: n.NodeId}
+			row := persistedRow{peerID: n.PeerId, lastSeen: n.LastSeen, replacement: replacement}
+			current[key] = row
+			if old, ok := rt.persisted[key]; ok && old == row {
+				continue
+			}
+			if replacement {
+				replacements = append(replacements, n)
+			} else {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	collect(rt.Nodes(), false)
+	collect(rt.ReplacementNodes(), true)
+
+	for key := range rt.persisted {
+		if _, ok := current[key]; !ok {
+			removed = append(removed, &models.Node{NodeId: key.nodeID, BucketIdx: key.bucketIdx})
+		}
+	}
+
+	if len(nodes) == 0 && len(replacements) == 0 && len(removed) == 0 {
+		return nil
+	}
+	if err := rt.store.SaveNodes(nodes, replacements, removed); err != nil {
+		return err
+	}
+	rt.persisted = current
+	return nil
+}
+
+// PersistEvery flushes the table every interval until ctx is cancelled.
+func (rt *RoutingTable) PersistEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := rt.Flush(); err != nil {
+				fmt.Println("❌ Error saving routing table:", err)
+			}
+		}
+	}
+}
+
+// markPersisted records rows just read from the store as already persisted.
+func (rt *RoutingTable) markPersisted(nodes []*models.Node, replacement bool) {
+	rt.persistMu.Lock()
+	defer rt.persistMu.Unlock()
+
+	if rt.persisted == nil {
+		rt.persisted = make(map[persistKey]persistedRow)
+	}
+	for _, n := range nodes {
+		rt.persisted[persistKey{bucketIdx: n.BucketIdx, nodeID: n.NodeId}] = persistedRow{
+			peerID:      n.PeerId,
+			lastSeen:    n.LastSeen,
+			replacement: replacement,
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/crypto/schema"
+	"github.com/libr-forum/Libr/core/db/config"
+)
+
+func newSQLTableStore(t *testing.T) *SQLTableStore {
+	t.Helper()
+	db, err := sql.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "libr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := schema.Migrate(db, schema.Question, config.SQLiteMigrations); err != nil {
+		t.Fatal(err)
+	}
+	return NewTableStore(config.DriverSQLite, db)
+}
+
+func TestFlushPersistsEviction(t *testing.T) {
+	store := newSQLTableStore(t)
+	self := keyspace.Sum("self")
+	local := testNode(0)
+
+	rt, err := LoadRoutingTable(self, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		rt.InsertNode(local, testNode(i), nil)
+	}
+	if err := rt.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	gone := testNode(2)
+	for i := 0; i < config.MaxFailedPings; i++ {
+		rt.MarkFailed(gone.PeerId)
+	}
+	if err := rt.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadRoutingTable(self, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := reloaded.Nodes()
+	if len(nodes) != 2 {
+		t.Fatalf("reloaded table holds %d nodes, want 2", len(nodes))
+	}
+	for _, n := range nodes {
+		if n.NodeId == gone.NodeId {
+			t.Fatalf("evicted node %s is back after a reload", gone.PeerId)
+		}
+	}
+
+	// Flushing the reloaded table leaves the evicted row deleted
+	if err := reloaded.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	stored, _, err := store.LoadNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("store holds %d rows, want 2", len(stored))
+	}
+}
@@ -0,0 +1,150 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// TableStore persists the nodes of a RoutingTable, and the replacement
+// caches of its buckets, between restarts.
+type TableStore interface {
+	LoadNodes() (nodes, replacements []*models.Node, err error)
+	// SaveNodes upserts nodes and replacements and deletes removed, all in
+	// one transaction. Rows are keyed by (BucketIdx, NodeId).
+	SaveNodes(nodes, replacements, removed []*models.Node) error
+}
+
+// SQLTableStore is a TableStore backed by the node's SQL database. It speaks
+// both the SQLite and the PostgreSQL dialect.
+type SQLTableStore struct {
+	db     *sql.DB
+	driver string
+}
+
+func NewTableStore(driver string, db *sql.DB) *SQLTableStore {
+	return &SQLTableStore{db: db, driver: driver}
+}
+
+func (s *SQLTableStore) loadQuery() string {
+	if s.driver == config.DriverPostgres {
+		return `
+		SELECT bucket_idx, node_id, peer_id, last_seen, replacement
+		FROM routing_table
+		ORDER BY bucket_idx ASC
+	`
+	}
+	return `
+		SELECT bucket_idx, NodeID, PeerID, LastSeen, Replacement
+		FROM RoutingTable
+		ORDER BY bucket_idx ASC
+	`
+}
+
+func (s *SQLTableStore) saveQuery() string {
+	if s.driver == config.DriverPostgres {
+		return `
+			INSERT INTO routing_table (bucket_idx, node_id, peer_id, last_seen, replacement)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (bucket_idx, node_id)
+			DO UPDATE SET peer_id = EXCLUDED.peer_id, last_seen = EXCLUDED.last_seen, replacement = EXCLUDED.replacement
+		`
+	}
+	return `
+			INSERT OR REPLACE INTO RoutingTable (bucket_idx, NodeID, PeerID, LastSeen, Replacement)
+			VALUES (?, ?, ?, ?, ?)
+		`
+}
+
+func (s *SQLTableStore) deleteQuery() string {
+	if s.driver == config.DriverPostgres {
+		return `DELETE FROM routing_table WHERE bucket_idx = $1 AND node_id = $2`
+	}
+	return `DELETE FROM RoutingTable WHERE bucket_idx = ? AND NodeID = ?`
+}
+
+func (s *SQLTableStore) LoadNodes() (nodes, replacements []*models.Node, err error) {
+	rows, err := s.db.Query(s.loadQuery())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bucketIdx   int
+			nodeIDRaw   []byte
+			peerID      string
+			lastSeen    int64
+			replacement bool
+		)
+
+		if err := rows.Scan(&bucketIdx, &nodeIDRaw, &peerID, &lastSeen, &replacement); err != nil {
+			return nil, nil, err
+		}
+
+		if len(nodeIDRaw) != keyspace.Size {
+			fmt.Printf("[WARN] NodeID length is %d (expected %d), skipping\n", len(nodeIDRaw), keyspace.Size)
+			continue
+		}
+
+		var nodeID keyspace.ID
+		copy(nodeID[:], nodeIDRaw)
+
+		n := &models.Node{
+			NodeId:    nodeID,
+			PeerId:    peerID,
+			BucketIdx: bucketIdx,
+			LastSeen:  lastSeen,
+		}
+		if replacement {
+			replacements = append(replacements, n)
+		} else {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes, replacements, rows.Err()
+}
+
+func (s *SQLTableStore) SaveNodes(nodes, replacements, removed []*models.Node) error {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, s.saveQuery())
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("preparing statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, n := range nodes {
+		if _, err := stmt.ExecContext(ctx, n.BucketIdx, n.NodeId[:], n.PeerId, n.LastSeen, false); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("inserting node: %w", err)
+		}
+	}
+	for _, n := range replacements {
+		if _, err := stmt.ExecContext(ctx, n.BucketIdx, n.NodeId[:], n.PeerId, n.LastSeen, true); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("inserting replacement: %w", err)
+		}
+	}
+
+	for _, n := range removed {
+		if _, err := tx.ExecContext(ctx, s.deleteQuery(), n.BucketIdx, n.NodeId[:]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("deleting node: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -2,14 +2,16 @@ package routing
 
 import (
 	"bytes"
-	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
+	"math/bits"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
 	"github.com/libr-forum/Libr/core/db/config"
 	"github.com/libr-forum/Libr/core/db/internal/models"
-	"github.com/libr-forum/Libr/core/db/internal/node"
 )
 
 // Pinger interface allows us to inject ping logic from the network package.
@@ -17,18 +19,43 @@ type Pinger interface {
 	Ping(peerId string, target *models.Node) error
 }
 
+// RoutingTable is safe for concurrent use. Its methods hand out copies of
+// the nodes they hold; Buckets is only exported for JSON and must not be
+// touched directly once the table is shared.
 type RoutingTable struct {
-	SelfID  [20]byte             `json:"self_id"`
-	Buckets [160]*models.KBucket `json:"buckets"`
+	SelfID  keyspace.ID                    `json:"self_id"`
+	Buckets [keyspace.Bits]*models.KBucket `json:"buckets"`
+
+	mu    sync.RWMutex
+	store TableStore
+
+	// persisted mirrors what the store holds after the last Flush
+	persistMu sync.Mutex
+	persisted map[persistKey]persistedRow
+
+	// onNodeAdded is called in its own goroutine whenever InsertNode puts a
+	// node into the table that was not there before.
+	onNodeAdded func(n *models.Node)
 }
 
-func GetBucketIndex(selfID, targetID [20]byte) int {
-	xor := node.XORBigInt(selfID, targetID)
-	index := xor.BitLen() - 1
-	if index < 0 {
-		index = 0
+// GetBucketIndex returns the index of the highest bit selfID and targetID
+// differ in, which is the bucket targetID belongs in, or 0 for equal IDs.
+// It does not allocate.
+func GetBucketIndex(selfID, targetID keyspace.ID) int {
+	for i := 0; i < keyspace.Size; i++ {
+		if x := selfID[i] ^ targetID[i]; x != 0 {
+			return keyspace.Bits - 1 - i*8 - bits.LeadingZeros8(x)
+		}
 	}
-	return index
+	return 0
+}
+
+// SetOnNodeAdded registers f to be called with a copy of every node InsertNode
+// adds to the table.
+func (rt *RoutingTable) SetOnNodeAdded(f func(n *models.Node)) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.onNodeAdded = f
 }
 
 func (rt *RoutingTable) InsertNode(localNode *models.Node, newNode *models.Node, pinger Pinger) string {
@@ -37,20 +64,185 @@ func (rt *RoutingTable) InsertNode(localNode *models.Node, newNode *models.Node,
 	}
 
 	index := GetBucketIndex(rt.SelfID, newNode.NodeId)
-	newNode.BucketIdx = index
+	candidate := *newNode
+	candidate.BucketIdx = index
+	candidate.LastSeen = time.Now().Unix()
 
+	// ✅ Log incoming node details
+	fmt.Printf("📥 InsertNode: %x | PeerID: %s\n", candidate.NodeId, candidate.PeerId)
+
+	// Only nodes that proved they own their ID get in. The pinger runs the
+	// proof handshake; nodes already held under the same peer ID passed it
+	// when they were added.
+	if !candidate.Verified && !rt.holds(candidate.NodeId, candidate.PeerId) {
+		if pinger == nil {
+			return resultUnverified
+		}
+		if err := pinger.Ping(localNode.PeerId, &candidate); err != nil {
+			fmt.Printf("🚫 Node %x failed the ID proof: %v\n", candidate.NodeId, err)
+			return resultUnverified
+		}
+		candidate.Verified = true
+	}
+
+	rt.mu.Lock()
 	if rt.Buckets[index] == nil {
 		rt.Buckets[index] = &models.KBucket{}
 	}
-	newNode.LastSeen = time.Now().Unix()
+	bucket := rt.Buckets[index]
+	if result, done := insertWithoutEviction(bucket, &candidate); done {
+		rt.mu.Unlock()
+		rt.notifyAdded(result, &candidate)
+		return result
+	}
+	oldest := *bucket.Nodes[0]
+	rt.mu.Unlock()
+
+	// The bucket is full. Ping its oldest node without holding the lock and
+	// only evict it if the bucket has not changed under us meanwhile.
+	if err := pinger.Ping(localNode.PeerId, &oldest); err == nil {
+		rt.MarkSeen(oldest.PeerId)
+		rt.mu.Lock()
+		addReplacementLocked(bucket, &candidate)
+		rt.mu.Unlock()
+		fmt.Println("🚫 New node rejected (bucket full, oldest still active), kept as replacement")
+		return resultRejected
+	}
 
-	// ✅ Log incoming node details
-	fmt.Printf("📥 InsertNode: %x | PeerID: %s\n", newNode.NodeId, newNode.PeerId)
+	rt.mu.Lock()
+	result, done := insertWithoutEviction(bucket, &candidate)
+	if !done {
+		result = resultRejected
+		if head := bucket.Nodes[0]; head.NodeId == oldest.NodeId {
+			head.FailedPings++
+			if head.FailedPings >= config.MaxFailedPings {
+				fmt.Printf("⚠️ Oldest node unresponsive. Replacing with: %x | Port: %s\n", candidate.NodeId, candidate.PeerId)
+				bucket.Nodes = append(bucket.Nodes[1:], &candidate)
+				bucket.LastRefreshed = candidate.LastSeen
+				removeReplacementLocked(bucket, candidate.NodeId)
+				result = resultReplaced
+			} else {
+				fmt.Printf("⚠️ Oldest node missed ping %d/%d, keeping it\n", head.FailedPings, config.MaxFailedPings)
+			}
+		}
+		if result == resultRejected {
+			addReplacementLocked(bucket, &candidate)
+		}
+	}
+	rt.mu.Unlock()
+
+	rt.notifyAdded(result, &candidate)
+	return result
+}
+
+// holds reports whether the table or a replacement cache has id under peerID.
+func (rt *RoutingTable) holds(id keyspace.ID, peerID string) bool {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	bucket := rt.Buckets[GetBucketIndex(rt.SelfID, id)]
+	if bucket == nil {
+		return false
+	}
+	for _, n := range bucket.Nodes {
+		if n.NodeId == id && n.PeerId == peerID {
+			return true
+		}
+	}
+	for _, n := range bucket.Replacements {
+		if n.NodeId == id && n.PeerId == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+// insertWithoutEviction refreshes n if the bucket holds it or appends it if
+// there is room. done is false when the bucket is full. Callers hold rt.mu.
+func insertWithoutEviction(bucket *models.KBucket, n *models.Node) (result string, done bool) {
+	for i, existing := range bucket.Nodes {
+		// ✅ Update existing node info including PeerID/LastSeen
+		if bytes.Equal(existing.NodeId[:], n.NodeId[:]) {
+			existing.LastSeen = n.LastSeen
+			existing.PeerId = n.PeerId
+			existing.FailedPings = 0
+			bucket.LastRefreshed = n.LastSeen
+
+			bucket.Nodes = append(bucket.Nodes[:i], bucket.Nodes[i+1:]...)
+			bucket.Nodes = append(bucket.Nodes, existing)
+
+			fmt.Printf("🔁 Updated node in K-bucket: %x | Port: %s\n", n.NodeId, n.PeerId)
+			return resultRefreshed, true
+		}
+	}
+
+	if len(bucket.Nodes) < config.K {
+		bucket.Nodes = append(bucket.Nodes, n)
+		bucket.LastRefreshed = n.LastSeen
+		removeReplacementLocked(bucket, n.NodeId)
+		fmt.Printf("➕ Appended new node: %x | Port: %s\n", n.NodeId, n.PeerId)
+		return resultAppended, true
+	}
+	return "", false
+}
+
+// addReplacementLocked records n as the freshest replacement candidate of
+// bucket, dropping the stalest one if the cache is full. Callers hold rt.mu.
+func addReplacementLocked(bucket *models.KBucket, n *models.Node) {
+	removeReplacementLocked(bucket, n.NodeId)
+	bucket.Replacements = append(bucket.Replacements, n)
+	if len(bucket.Replacements) > config.ReplacementCacheSize {
+		bucket.Replacements = bucket.Replacements[len(bucket.Replacements)-config.ReplacementCacheSize:]
+	}
+}
+
+func removeReplacementLocked(bucket *models.KBucket, id keyspace.ID) {
+	for i, r := range bucket.Replacements {
+		if r.NodeId == id {
+			bucket.Replacements = append(bucket.Replacements[:i], bucket.Replacements[i+1:]...)
+			return
+		}
+	}
+}
 
-	return InsertNodeKBucket(rt.SelfID, localNode, newNode, rt.Buckets[index], pinger)
+// promoteReplacementLocked moves the freshest replacement candidate into
+// bucket and returns it, or nil if the cache is empty. Callers hold rt.mu.
+func promoteReplacementLocked(bucket *models.KBucket) *models.Node {
+	if len(bucket.Replacements) == 0 || len(bucket.Nodes) >= config.K {
+		return nil
+	}
+	last := len(bucket.Replacements) - 1
+	n := bucket.Replacements[last]
+	bucket.Replacements = bucket.Replacements[:last]
+	n.FailedPings = 0
+	bucket.Nodes = append(bucket.Nodes, n)
+	return n
 }
 
-func InsertNodeKBucket(selfID [20]byte, localNode *models.Node, newNode *models.Node, bucket *models.KBucket, pinger Pinger) string {
+func (rt *RoutingTable) notifyAdded(result string, n *models.Node) {
+	if result != resultAppended && result != resultReplaced {
+		return
+	}
+	rt.mu.RLock()
+	f := rt.onNodeAdded
+	rt.mu.RUnlock()
+	if f != nil {
+		added := *n
+		go f(&added)
+	}
+}
+
+const (
+	resultRefreshed  = "Updated K-Bucket (refreshed existing node)"
+	resultAppended   = "Appended new node (bucket had space)"
+	resultReplaced   = "Replaced unresponsive node with new node"
+	resultRejected   = "New node rejected (bucket full, oldest still active)"
+	resultUnverified = "New node rejected (node ID not proven)"
+)
+
+// InsertNodeKBucket inserts into a bare bucket. It does no locking and is
+// not used by RoutingTable, which has to release its lock while pinging.
+func InsertNodeKBucket(selfID keyspace.ID, localNode *models.Node, newNode *models.Node, bucket *models.KBucket, pinger Pinger) string {
 	for i, existing := range bucket.Nodes {
 		// ✅ Update existing node info including PeerID/LastSeen
 		if bytes.Equal(existing.NodeId[:], newNode.NodeId[:]) {
@@ -60,49 +252,152 @@ func InsertNodeKBucket(selfID [20]byte, localNode *models.Node, newNode *models.
 			bucket.Nodes = append(bucket.Nodes, existing)
 
 			fmt.Printf("🔁 Updated node in K-bucket: %x | Port: %s\n", newNode.NodeId, newNode.PeerId)
-			return "Updated K-Bucket (refreshed existing node)"
+			return resultRefreshed
 		}
 	}
 
 	if len(bucket.Nodes) < config.K {
 		bucket.Nodes = append(bucket.Nodes, newNode)
 		fmt.Printf("➕ Appended new node: %x | Port: %s\n", newNode.NodeId, newNode.PeerId)
-		return "Appended new node (bucket had space)"
+		return resultAppended
 	}
 
 	// Ping the oldest node to check if it’s alive
 	if err := pinger.Ping(localNode.PeerId, bucket.Nodes[0]); err != nil {
 		fmt.Printf("⚠️ Oldest node unresponsive. Replacing with: %x | Port: %s\n", newNode.NodeId, newNode.PeerId)
 		bucket.Nodes = append(bucket.Nodes[1:], newNode)
-		return "Replaced unresponsive node with new node"
+		return resultReplaced
 	}
 
 	fmt.Println("🚫 New node rejected (bucket full, oldest still active)")
-	return "New node rejected (bucket full, oldest still active)"
+	return resultRejected
 }
 
-func (rt *RoutingTable) FindClosest(targetID [20]byte, count int) []*models.Node {
-	var allNodes []*models.Node
-	for _, bucket := range rt.Buckets {
+// FindClosest returns copies of the count nodes closest to targetID.
+//
+// With t the bucket targetID falls in, every node in bucket t is closer to
+// targetID than any node in buckets below t, and those in turn are closer
+// than any node in bucket t+1, t+2 and so on up the table. So only the
+// buckets needed to reach count are visited, and only they are sorted.
+func (rt *RoutingTable) FindClosest(targetID keyspace.ID, count int) []*models.Node {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	if count <= 0 {
+		return nil
+	}
+
+	closest := make([]*models.Node, 0, count)
+	var group []*models.Node
+	addGroup := func() {
+		slices.SortFunc(group, func(a, b *models.Node) int {
+			return keyspace.CompareDistance(targetID, a.NodeId, b.NodeId)
+		})
+		closest = append(closest, group...)
+		group = group[:0]
+	}
+	collect := func(bucketIdx int) {
+		bucket := rt.Buckets[bucketIdx]
+		if bucket == nil {
+			return
+		}
+		for _, n := range bucket.Nodes {
+			cp := *n
+			cp.BucketIdx = bucketIdx
+			group = append(group, &cp)
+		}
+	}
+
+	t := GetBucketIndex(rt.SelfID, targetID)
+	collect(t)
+	addGroup()
+	if len(closest) < count {
+		for i := 0; i < t; i++ {
+			collect(i)
+		}
+		addGroup()
+	}
+	for i := t + 1; i < len(rt.Buckets) && len(closest) < count; i++ {
+		collect(i)
+		addGroup()
+	}
+
+	if len(closest) > count {
+		closest = closest[:count]
+	}
+	return closest
+}
+
+// Nodes returns a snapshot of every node in the table, with BucketIdx set.
+func (rt *RoutingTable) Nodes() []*models.Node {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	var nodes []*models.Node
+	for bucketIdx, bucket := range rt.Buckets {
+		if bucket == nil {
+			continue
+		}
+		for _, n := range bucket.Nodes {
+			cp := *n
+			cp.BucketIdx = bucketIdx
+			nodes = append(nodes, &cp)
+		}
+	}
+	return nodes
+}
+
+// ReplacementNodes returns a snapshot of every bucket's replacement cache,
+// with BucketIdx set.
+func (rt *RoutingTable) ReplacementNodes() []*models.Node {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	var nodes []*models.Node
+	for bucketIdx, bucket := range rt.Buckets {
 		if bucket == nil {
 			continue
 		}
-		allNodes = append(allNodes, bucket.Nodes...)
+		for _, n := range bucket.Replacements {
+			cp := *n
+			cp.BucketIdx = bucketIdx
+			nodes = append(nodes, &cp)
+		}
 	}
+	return nodes
+}
+
+// Size returns the number of nodes in the table.
+func (rt *RoutingTable) Size() int {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	size := 0
+	for _, bucket := range rt.Buckets {
+		if bucket != nil {
+			size += len(bucket.Nodes)
+		}
+	}
+	return size
+}
 
-	sort.Slice(allNodes, func(i, j int) bool {
-		distI := node.XORBigInt(allNodes[i].NodeId, targetID)
-		distJ := node.XORBigInt(allNodes[j].NodeId, targetID)
-		return distI.Cmp(distJ) < 0
-	})
+// MarshalJSON encodes a snapshot of the table taken under its lock.
+func (rt *RoutingTable) MarshalJSON() ([]byte, error) {
+	snapshot := struct {
+		SelfID  keyspace.ID                    `json:"self_id"`
+		Buckets [keyspace.Bits]*models.KBucket `json:"buckets"`
+	}{SelfID: rt.SelfID}
 
-	if len(allNodes) > count {
-		return allNodes[:count]
+	for _, n := range rt.Nodes() {
+		if snapshot.Buckets[n.BucketIdx] == nil {
+			snapshot.Buckets[n.BucketIdx] = &models.KBucket{}
+		}
+		snapshot.Buckets[n.BucketIdx].Nodes = append(snapshot.Buckets[n.BucketIdx].Nodes, n)
 	}
-	return allNodes
+	return json.Marshal(snapshot)
 }
 
-func NewRoutingTable(selfID [20]byte) *RoutingTable {
+func NewRoutingTable(selfID keyspace.ID) *RoutingTable {
 	rt := &RoutingTable{
 		SelfID: selfID,
 	}
@@ -118,182 +413,60 @@ func (rt *RoutingTable) SelfIDHex() string {
 
 var memoryCache *RoutingTable
 
-func GetOrCreateRoutingTable(selfID [20]byte) *RoutingTable {
+func GetOrCreateRoutingTable(selfID keyspace.ID, store TableStore) *RoutingTable {
 	if memoryCache != nil {
 		return memoryCache
 	}
 
-	dbRT, err := LoadRoutingTable(selfID)
+	dbRT, err := LoadRoutingTable(selfID, store)
 	if err == nil {
 		memoryCache = dbRT
 		return memoryCache
 	}
 
 	memoryCache = NewRoutingTable(selfID)
+	memoryCache.store = store
 	go memoryCache.SaveToDBAsync()
 	return memoryCache
 }
 
-// func (rt *RoutingTable) SaveToDBAsync() {
-// 	go func() {
-// 		jsonBytes, err := json.Marshal(rt)
-// 		if err != nil {
-// 			fmt.Println("❌ Error marshaling routing table:", err)
-// 			return
-// 		}
-
-// 		_, err = config.DB.ExecContext(context.Background(),
-// 			`INSERT INTO RoutingTable (rt) VALUES (?)`, jsonBytes)
-// 		if err != nil {
-// 			fmt.Println("❌ Error saving routing table to SQLite:", err)
-// 		}
-// 	}()
-// }
-
-// func LoadRoutingTableFromDB() (*RoutingTable, error) {
-// 	var jsonBytes []byte
-// 	row := config.DB.QueryRowContext(context.Background(),
-// 		`SELECT rt FROM RoutingTable ORDER BY id DESC LIMIT 1`)
-// 	err := row.Scan(&jsonBytes)
-// 	if err != nil {
-// 		if err == sql.ErrNoRows {
-// 			return nil, fmt.Errorf("no routing table in DB")
-// 		}
-// 		return nil, err
-// 	}
-
-// 	var rt RoutingTable
-// 	if err := json.Unmarshal(jsonBytes, &rt); err != nil {
-// 		return nil, fmt.Errorf("error unmarshaling routing table: %v", err)
-// 	}
-// 	return &rt, nil
-// }
-
-func LoadRoutingTable(selfID [20]byte) (*RoutingTable, error) {
-	fmt.Println("[DEBUG] LoadRoutingTable called")
-
-	rt := &RoutingTable{
-		SelfID: selfID,
-	}
-	fmt.Println("[DEBUG] Created empty RoutingTable with SelfID:", selfID)
-
-	// Initialize empty buckets
-	for i := 0; i < len(rt.Buckets); i++ {
-		rt.Buckets[i] = &models.KBucket{Nodes: []*models.Node{}}
-	}
-	fmt.Println("[DEBUG] Initialized", len(rt.Buckets), "empty buckets")
-
-	query := `
-		SELECT bucket_idx, NodeID, PeerID, LastSeen
-		FROM RoutingTable
-		ORDER BY bucket_idx ASC
-	`
-	fmt.Println("[DEBUG] Executing query:", query)
+func LoadRoutingTable(selfID keyspace.ID, store TableStore) (*RoutingTable, error) {
+	rt := NewRoutingTable(selfID)
+	rt.store = store
 
-	rows, err := config.DB.Query(query)
+	nodes, replacements, err := store.LoadNodes()
 	if err != nil {
-		fmt.Println("[ERROR] DB.Query failed:", err)
+		fmt.Println("[ERROR] Loading routing table failed:", err)
 		return nil, err
 	}
-	defer func() {
-		fmt.Println("[DEBUG] Closing DB rows")
-		rows.Close()
-	}()
-
-	rowCount := 0
-	for rows.Next() {
-		fmt.Println("[DEBUG] Processing next row...")
-		var (
-			bucketIdx int
-			nodeIDRaw []byte
-			peerID    string
-			lastSeen  int64
-		)
-
-		if err := rows.Scan(&bucketIdx, &nodeIDRaw, &peerID, &lastSeen); err != nil {
-			fmt.Println("[ERROR] rows.Scan failed:", err)
-			return nil, err
-		}
-
-		fmt.Printf("[DEBUG] Scanned row #%d - bucketIdx: %d, nodeIDRaw(len=%d): %x, peerID: %s, lastSeen: %d\n",
-			rowCount, bucketIdx, len(nodeIDRaw), nodeIDRaw, peerID, lastSeen)
 
-		if bucketIdx < 0 || bucketIdx >= len(rt.Buckets) {
-			fmt.Printf("[WARN] Invalid bucket index %d, skipping this row\n", bucketIdx)
+	for _, n := range nodes {
+		if n.BucketIdx < 0 || n.BucketIdx >= len(rt.Buckets) {
+			fmt.Printf("[WARN] Invalid bucket index %d, skipping this row\n", n.BucketIdx)
 			continue
 		}
+		rt.Buckets[n.BucketIdx].Nodes = append(rt.Buckets[n.BucketIdx].Nodes, n)
+	}
 
-		if len(nodeIDRaw) != 20 {
-			fmt.Printf("[WARN] NodeID length is %d (expected 20), skipping\n", len(nodeIDRaw))
+	for _, n := range replacements {
+		if n.BucketIdx < 0 || n.BucketIdx >= len(rt.Buckets) {
 			continue
 		}
-
-		var nodeID [20]byte
-		copy(nodeID[:], nodeIDRaw)
-
-		node := &models.Node{
-			NodeId:    nodeID,
-			PeerId:    peerID,
-			BucketIdx: bucketIdx,
-			LastSeen:  lastSeen,
-		}
-
-		rt.Buckets[bucketIdx].Nodes = append(rt.Buckets[bucketIdx].Nodes, node)
-		rowCount++
+		addReplacementLocked(rt.Buckets[n.BucketIdx], n)
 	}
 
-	if err := rows.Err(); err != nil {
-		fmt.Println("[ERROR] rows.Err() returned:", err)
-		return nil, err
-	}
+	// Rows that did not make it into the table are deleted on the next flush
+	rt.markPersisted(nodes, false)
+	rt.markPersisted(replacements, true)
 
-	fmt.Printf("[DEBUG] Loaded %d nodes into routing table\n", rowCount)
 	return rt, nil
 }
 
+// SaveToDBAsync flushes the table in the background.
 func (rt *RoutingTable) SaveToDBAsync() {
 	go func() {
-		ctx := context.Background()
-
-		tx, err := config.DB.BeginTx(ctx, nil)
-		if err != nil {
-			fmt.Println("❌ Error starting transaction:", err)
-			return
-		}
-
-		stmt, err := tx.PrepareContext(ctx, `
-			INSERT OR REPLACE INTO RoutingTable (bucket_idx, NodeID, PeerID, LastSeen)
-			VALUES (?, ?, ?, ?)
-		`)
-		if err != nil {
-			fmt.Println("❌ Error preparing statement:", err)
-			_ = tx.Rollback()
-			return
-		}
-		defer stmt.Close()
-
-		for bucketIdx, bucket := range rt.Buckets {
-			if bucket == nil {
-				continue
-			}
-			for _, node := range bucket.Nodes {
-				_, err := stmt.ExecContext(
-					ctx,
-					bucketIdx,
-					node.NodeId[:],
-					node.PeerId,
-					node.LastSeen,
-				)
-				if err != nil {
-					fmt.Println("❌ Error inserting node:", err)
-					_ = tx.Rollback()
-					return
-				}
-			}
-		}
-
-		if err := tx.Commit(); err != nil {
-			fmt.Println("❌ Error committing transaction:", err)
+		if err := rt.Flush(); err != nil {
+			fmt.Println("❌ Error saving routing table:", err)
 		}
 	}()
 }
@@ -0,0 +1,365 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+)
+
+// memTableStore is an in-memory TableStore keyed like the SQL one.
+type memTableStore struct {
+	mu   sync.Mutex
+	rows map[persistKey]models.Node
+}
+
+func newMemTableStore() *memTableStore {
+	return &memTableStore{rows: make(map[persistKey]models.Node)}
+}
+
+func (s *memTableStore) LoadNodes() (nodes, replacements []*models.Node, err error) {
+	return nil, nil, nil
+}
+
+func (s *memTableStore) SaveNodes(nodes, replacements, removed []*models.Node) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, list := range [][]*models.Node{nodes, replacements} {
+		for _, n := range list {
+			s.rows[persistKey{bucketIdx: n.BucketIdx, nodeID: n.NodeId}] = *n
+		}
+	}
+	for _, n := range removed {
+		delete(s.rows, persistKey{bucketIdx: n.BucketIdx, nodeID: n.NodeId})
+	}
+	return nil
+}
+
+func (s *memTableStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rows)
+}
+
+// flakyPinger fails every other ping, so full buckets both keep and evict
+// their oldest node.
+type flakyPinger struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (p *flakyPinger) Ping(peerId string, target *models.Node) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count++
+	if p.count%2 == 0 {
+		return errors.New("no answer")
+	}
+	return nil
+}
+
+func testNode(i int) *models.Node {
+	return &models.Node{
+		NodeId:   keyspace.Sum(fmt.Sprintf("node-%d", i)),
+		PeerId:   fmt.Sprintf("peer-%d", i),
+		Verified: true,
+	}
+}
+
+// TestConcurrentAccess runs every entry point of the table at once; run it
+// with -race.
+func TestConcurrentAccess(t *testing.T) {
+	store := newMemTableStore()
+	rt, err := LoadRoutingTable(keyspace.Sum("self"), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+	pinger := &flakyPinger{}
+
+	const workers = 8
+	const perWorker = 200
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(5)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				rt.InsertNode(local, testNode(w*perWorker+i), pinger)
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				for _, n := range rt.FindClosest(keyspace.Sum(fmt.Sprintf("key-%d-%d", w, i)), config.K) {
+					n.LastSeen++ // copies are the caller's to change
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				for _, n := range rt.Nodes() {
+					n.PeerId = ""
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := rt.MarshalJSON(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker/10; i++ {
+				if err := rt.Flush(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[keyspace.ID]bool)
+	for _, n := range rt.Nodes() {
+		if seen[n.NodeId] {
+			t.Fatalf("node %x is in the table twice", n.NodeId)
+		}
+		seen[n.NodeId] = true
+		if n.PeerId == "" {
+			t.Fatalf("a snapshot change reached node %x in the table", n.NodeId)
+		}
+		if want := GetBucketIndex(rt.SelfID, n.NodeId); n.BucketIdx != want {
+			t.Fatalf("node %x is in bucket %d, want %d", n.NodeId, n.BucketIdx, want)
+		}
+	}
+	for i, bucket := range rt.Buckets {
+		if len(bucket.Nodes) > config.K {
+			t.Fatalf("bucket %d holds %d nodes, more than K", i, len(bucket.Nodes))
+		}
+	}
+
+	if err := rt.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := len(rt.Nodes()) + len(rt.ReplacementNodes()); store.len() != want {
+		t.Fatalf("store holds %d rows after the last flush, want %d", store.len(), want)
+	}
+}
+
+// fullBucket returns a table whose bucket index holds K nodes that all
+// answer pings.
+func fullBucket(t *testing.T, index int) (rt *RoutingTable, local *models.Node, pinger Pinger) {
+	t.Helper()
+	rt = NewRoutingTable(keyspace.Sum("self"))
+	local = &models.Node{NodeId: rt.SelfID, PeerId: "self"}
+	for i := 0; i < config.K; i++ {
+		if got := rt.InsertNode(local, bucketNode(rt, index, i), nil); got != resultAppended {
+			t.Fatalf("filling the bucket: %s", got)
+		}
+	}
+	return rt, local, pingerFunc(func(string, *models.Node) error { return nil })
+}
+
+func TestReplacementCache(t *testing.T) {
+	const index = 200
+	rt, local, pinger := fullBucket(t, index)
+
+	var candidates []*models.Node
+	for i := 0; i < config.ReplacementCacheSize+2; i++ {
+		n := bucketNode(rt, index, config.K+i)
+		candidates = append(candidates, n)
+		if got := rt.InsertNode(local, n, pinger); got != resultRejected {
+			t.Fatalf("candidate %d: %s", i, got)
+		}
+	}
+
+	// The cache keeps the freshest candidates, freshest last
+	want := peerIDs(candidates[2:])
+	if got := peerIDs(rt.ReplacementNodes()); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("replacement cache is %v, want %v", got, want)
+	}
+
+	// A candidate seen again is not cached twice but becomes the freshest
+	again := candidates[2]
+	if got := rt.InsertNode(local, again, pinger); got != resultRejected {
+		t.Fatalf("repeated candidate: %s", got)
+	}
+	want = append(peerIDs(candidates[3:]), again.PeerId)
+	if got := peerIDs(rt.ReplacementNodes()); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("replacement cache is %v, want %v", got, want)
+	}
+}
+
+func TestEvictionPromotesReplacement(t *testing.T) {
+	const index = 200
+	rt, local, pinger := fullBucket(t, index)
+	added := make(chan *models.Node, 2)
+	rt.SetOnNodeAdded(func(n *models.Node) { added <- n })
+
+	older, fresher := bucketNode(rt, index, config.K), bucketNode(rt, index, config.K+1)
+	for _, n := range []*models.Node{older, fresher} {
+		rt.InsertNode(local, n, pinger)
+	}
+
+	victim := rt.Buckets[index].Nodes[0]
+	for i := 0; i < config.MaxFailedPings; i++ {
+		rt.MarkFailed(victim.PeerId)
+	}
+
+	nodes := peerIDs(rt.Buckets[index].Nodes)
+	if len(nodes) != config.K || nodes[len(nodes)-1] != fresher.PeerId {
+		t.Fatalf("bucket is %v, want %s promoted into the freed slot", nodes, fresher.PeerId)
+	}
+	if cache := peerIDs(rt.ReplacementNodes()); len(cache) != 1 || cache[0] != older.PeerId {
+		t.Fatalf("replacement cache is %v, want only %s", cache, older.PeerId)
+	}
+	select {
+	case n := <-added:
+		if n.PeerId != fresher.PeerId {
+			t.Fatalf("added hook got %s, want %s", n.PeerId, fresher.PeerId)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("added hook not called for the promoted node")
+	}
+
+	// With the bucket full again a further eviction drains the cache
+	for i := 0; i < config.MaxFailedPings; i++ {
+		rt.MarkFailed(rt.Buckets[index].Nodes[0].PeerId)
+	}
+	if len(rt.Buckets[index].Nodes) != config.K || len(rt.ReplacementNodes()) != 0 {
+		t.Fatalf("bucket %v, cache %v after the second eviction", peerIDs(rt.Buckets[index].Nodes), peerIDs(rt.ReplacementNodes()))
+	}
+
+	// An empty cache leaves the slot free
+	for i := 0; i < config.MaxFailedPings; i++ {
+		rt.MarkFailed(rt.Buckets[index].Nodes[0].PeerId)
+	}
+	if len(rt.Buckets[index].Nodes) != config.K-1 {
+		t.Fatalf("bucket holds %d nodes, want %d", len(rt.Buckets[index].Nodes), config.K-1)
+	}
+}
+
+// bigIntBucketIndex and findClosestBigInt are the big.Int versions
+// GetBucketIndex and FindClosest replaced, kept as a reference.
+func bigIntBucketIndex(selfID, targetID keyspace.ID) int {
+	index := node.XORBigInt(selfID, targetID).BitLen() - 1
+	if index < 0 {
+		index = 0
+	}
+	return index
+}
+
+func findClosestBigInt(rt *RoutingTable, targetID keyspace.ID, count int) []*models.Node {
+	allNodes := rt.Nodes()
+	sort.Slice(allNodes, func(i, j int) bool {
+		distI := node.XORBigInt(allNodes[i].NodeId, targetID)
+		distJ := node.XORBigInt(allNodes[j].NodeId, targetID)
+		return distI.Cmp(distJ) < 0
+	})
+	if len(allNodes) > count {
+		return allNodes[:count]
+	}
+	return allNodes
+}
+
+// largeTable fills the buckets of a table directly, past K, so it holds
+// size nodes.
+func largeTable(size int) *RoutingTable {
+	rt := NewRoutingTable(keyspace.Sum("self"))
+	for i := 0; i < size; i++ {
+		n := testNode(i)
+		n.BucketIdx = GetBucketIndex(rt.SelfID, n.NodeId)
+		rt.Buckets[n.BucketIdx].Nodes = append(rt.Buckets[n.BucketIdx].Nodes, n)
+	}
+	return rt
+}
+
+func TestGetBucketIndex(t *testing.T) {
+	self := keyspace.Sum("self")
+	if got := GetBucketIndex(self, self); got != 0 {
+		t.Fatalf("bucket of own ID is %d, want 0", got)
+	}
+	for bit := 0; bit < keyspace.Bits; bit++ {
+		target := self
+		target[keyspace.Size-1-bit/8] ^= 1 << (bit % 8)
+		if got := GetBucketIndex(self, target); got != bit {
+			t.Fatalf("flipping bit %d gives bucket %d", bit, got)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		target := keyspace.Sum(fmt.Sprintf("target-%d", i))
+		if got, want := GetBucketIndex(self, target), bigIntBucketIndex(self, target); got != want {
+			t.Fatalf("bucket of target-%d is %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestFindClosestMatchesFullSort(t *testing.T) {
+	rt := largeTable(2000)
+	for i := 0; i < 500; i++ {
+		target := keyspace.Sum(fmt.Sprintf("target-%d", i))
+		got, want := rt.FindClosest(target, config.K), findClosestBigInt(rt, target, config.K)
+		if len(got) != len(want) {
+			t.Fatalf("got %d nodes, want %d", len(got), len(want))
+		}
+		for j := range want {
+			if got[j].NodeId != want[j].NodeId {
+				t.Fatalf("target-%d: node %d is %x, want %x", i, j, got[j].NodeId, want[j].NodeId)
+			}
+		}
+	}
+}
+
+func BenchmarkFindClosest(b *testing.B) {
+	for _, size := range []int{1000, 5000} {
+		rt := largeTable(size)
+		targets := make([]keyspace.ID, 256)
+		for i := range targets {
+			targets[i] = keyspace.Sum(fmt.Sprintf("target-%d", i))
+		}
+
+		b.Run(fmt.Sprintf("buckets/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				rt.FindClosest(targets[i%len(targets)], config.K)
+			}
+		})
+		b.Run(fmt.Sprintf("bigint/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				findClosestBigInt(rt, targets[i%len(targets)], config.K)
+			}
+		})
+	}
+}
+
+func BenchmarkGetBucketIndex(b *testing.B) {
+	self, target := keyspace.Sum("self"), keyspace.Sum("target")
+	b.Run("leadingzeros", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			GetBucketIndex(self, target)
+		}
+	})
+	b.Run("bigint", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bigIntBucketIndex(self, target)
+		}
+	})
+}
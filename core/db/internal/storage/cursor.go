@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// Cursor marks the last cert of a page in the (ts, sign) order pages are
+// served in. Clients only ever see it encoded.
+type Cursor struct {
+	Ts   int64  `json:"ts"`
+	Sign string `json:"sign"`
+}
+
+// EncodeCursor renders a cursor as URL-safe base64 so it survives the
+// "&&"/"=" route syntax.
+func EncodeCursor(c *Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an encoded cursor. The empty string decodes to nil.
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := &Cursor{Ts: 1700000042, Sign: "a+b/c=="}
+	encoded := EncodeCursor(c)
+	if strings.ContainsAny(encoded, "&=") {
+		t.Fatalf("encoded cursor %q would break the route syntax", encoded)
+	}
+	back, err := DecodeCursor(encoded)
+	if err != nil || !reflect.DeepEqual(back, c) {
+		t.Fatalf("decoded %+v (%v), want %+v", back, err, c)
+	}
+
+	if back, err := DecodeCursor(""); back != nil || err != nil {
+		t.Fatalf("empty cursor decoded to %+v, %v", back, err)
+	}
+	for _, bad := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("not json"))} {
+		if _, err := DecodeCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("%q: got %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
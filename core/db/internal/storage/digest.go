@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// DigestEntry hashes the (sign, deleted) pair of a stored cert. Two replicas
+// agree on a cert exactly when their entries for it are equal.
+func DigestEntry(cert models.RetMsgCert) string {
+	deleted := "0"
+	if cert.Deleted == "1" {
+		deleted = "1"
+	}
+	sum := sha256.Sum256([]byte(cert.Sign + ":" + deleted))
+	return hex.EncodeToString(sum[:])
+}
+
+// Digest returns the sorted digest entries of certs.
+func Digest(certs []models.RetMsgCert) []string {
+	digest := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		digest = append(digest, DigestEntry(cert))
+	}
+	sort.Strings(digest)
+	return digest
+}
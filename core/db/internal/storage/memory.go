@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// MemoryStore is a MsgCertStore that lives only in process memory. It is
+// meant for throwaway nodes and tests.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	certs map[int64][]models.RetMsgCert // minute -> certs
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{certs: make(map[int64][]models.RetMsgCert)}
+}
+
+func (m *MemoryStore) Store(msgcert *models.MsgCert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	minute := MinuteOf(msgcert.Msg.Ts)
+	for _, existing := range m.certs[minute] {
+		if existing.Sign == msgcert.Sign {
+			return ErrAlreadyStored
+		}
+	}
+	m.certs[minute] = append(m.certs[minute], models.RetMsgCert{
+		PublicKey: msgcert.PublicKey,
+		Msg:       msgcert.Msg,
+		ModCerts:  append([]models.ModCert(nil), msgcert.ModCerts...),
+		Sign:      msgcert.Sign,
+		Deleted:   "0",
+	})
+	return nil
+}
+
+func (m *MemoryStore) Delete(repCert *models.ReportCert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	certs := m.certs[MinuteOf(repCert.Msgcert.Msg.Ts)]
+	for i := range certs {
+		if certs[i].Sign != repCert.Msgcert.Sign {
+			continue
+		}
+		if certs[i].Deleted == "1" {
+			return ErrAlreadyDeleted
+		}
+		certs[i].Deleted = "1"
+		certs[i].RepModCerts = append([]models.ModCert(nil), repCert.RepModCerts...)
+		certs[i].RepMode = repCert.Mode
+		return nil
+	}
+	return ErrMsgCertNotFound
+}
+
+func (m *MemoryStore) Get(ts int64) ([]models.RetMsgCert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	certs := m.certs[MinuteOf(ts)]
+	if len(certs) == 0 {
+		return nil, nil
+	}
+	return append([]models.RetMsgCert(nil), certs...), nil
+}
+
+func (m *MemoryStore) GetPage(ts int64, after *Cursor, limit int) ([]models.RetMsgCert, error) {
+	m.mu.RLock()
+	certs := append([]models.RetMsgCert(nil), m.certs[MinuteOf(ts)]...)
+	m.mu.RUnlock()
+
+	return sortedPage(certs, after, limit), nil
+}
+
+func (m *MemoryStore) Range(from, to int64, after *Cursor, limit int) ([]models.RetMsgCert, error) {
+	m.mu.RLock()
+	var certs []models.RetMsgCert
+	for minute := MinuteOf(from); minute <= MinuteOf(to); minute += 60 {
+		certs = append(certs, m.certs[minute]...)
+	}
+	m.mu.RUnlock()
+
+	return sortedPage(certs, after, limit), nil
+}
+
+// sortedPage sorts certs by (ts, sign) and returns up to limit of those after the
+// cursor.
+func sortedPage(certs []models.RetMsgCert, after *Cursor, limit int) []models.RetMsgCert {
+	sort.Slice(certs, func(i, j int) bool {
+		if certs[i].Msg.Ts != certs[j].Msg.Ts {
+			return certs[i].Msg.Ts < certs[j].Msg.Ts
+		}
+		return certs[i].Sign < certs[j].Sign
+	})
+
+	var out []models.RetMsgCert
+	for _, cert := range certs {
+		if after != nil && (cert.Msg.Ts < after.Ts || (cert.Msg.Ts == after.Ts && cert.Sign <= after.Sign)) {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, cert)
+	}
+	return out
+}
+
+func (m *MemoryStore) List() ([]models.RetMsgCert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var all []models.RetMsgCert
+	for _, certs := range m.certs {
+		all = append(all, certs...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Msg.Ts < all[j].Msg.Ts
+	})
+	return all, nil
+}
+
+func (m *MemoryStore) Minutes() ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	minutes := make([]int64, 0, len(m.certs))
+	for minute, certs := range m.certs {
+		if len(certs) > 0 {
+			minutes = append(minutes, minute)
+		}
+	}
+	sort.Slice(minutes, func(i, j int) bool { return minutes[i] < minutes[j] })
+	return minutes, nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// PgStore keeps MsgCerts in the msgcerts table of a PostgreSQL database,
+// with mod_certs held as JSONB.
+type PgStore struct {
+	db *sql.DB
+}
+
+func NewPgStore(db *sql.DB) *PgStore {
+	return &PgStore{db: db}
+}
+
+// NewStore returns the MsgCertStore matching the driver the DB was opened with.
+func NewStore(driver string, db *sql.DB) MsgCertStore {
+	if driver == config.DriverPostgres {
+		return NewPgStore(db)
+	}
+	return NewSQLiteStore(db)
+}
+
+func (s *PgStore) Store(msgcert *models.MsgCert) error {
+	modCertsJSON, err := json.Marshal(msgcert.ModCerts)
+	if err != nil {
+		return fmt.Errorf("marshaling modCerts: %w", err)
+	}
+
+	query := "INSERT INTO msgcerts(sender, content, ts, mod_certs, sign) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (sign) DO NOTHING"
+	result, err := s.db.Exec(query,
+		msgcert.PublicKey,
+		msgcert.Msg.Content,
+		msgcert.Msg.Ts,
+		string(modCertsJSON),
+		msgcert.Sign,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fetching rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAlreadyStored
+	}
+	return nil
+}
+
+func (s *PgStore) Delete(repCert *models.ReportCert) error {
+	repModCertsJSON, err := json.Marshal(repCert.RepModCerts)
+	if err != nil {
+		return fmt.Errorf("marshaling repModCerts: %w", err)
+	}
+
+	query := "UPDATE msgcerts SET deleted = 1, repmod_certs = $1, rep_mode = $2 WHERE sign = $3 AND deleted = 0"
+
+	result, err := s.db.Exec(query, string(repModCertsJSON), repCert.Mode, repCert.Msgcert.Sign)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fetching rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		var held bool
+		if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM msgcerts WHERE sign = $1)", repCert.Msgcert.Sign).Scan(&held); err != nil {
+			return err
+		}
+		if held {
+			return ErrAlreadyDeleted
+		}
+		return ErrMsgCertNotFound
+	}
+	return nil
+}
+
+func (s *PgStore) Get(ts int64) ([]models.RetMsgCert, error) {
+	minute := MinuteOf(ts)
+
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcerts
+	WHERE ts >= $1 AND ts < $2
+`
+	rows, err := s.db.Query(query, minute, minute+60)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *PgStore) GetPage(ts int64, after *Cursor, limit int) ([]models.RetMsgCert, error) {
+	minute := MinuteOf(ts)
+	if after == nil {
+		after = &Cursor{Ts: minute - 1}
+	}
+
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcerts
+	WHERE ts >= $1 AND ts < $2 AND (ts > $3 OR (ts = $3 AND sign > $4))
+	ORDER BY ts ASC, sign ASC
+	LIMIT $5
+`
+	rows, err := s.db.Query(query, minute, minute+60, after.Ts, after.Sign, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *PgStore) Range(from, to int64, after *Cursor, limit int) ([]models.RetMsgCert, error) {
+	if after == nil {
+		after = &Cursor{Ts: MinuteOf(from) - 1}
+	}
+
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcerts
+	WHERE ts >= $1 AND ts < $2 AND (ts > $3 OR (ts = $3 AND sign > $4))
+	ORDER BY ts ASC, sign ASC
+	LIMIT $5
+`
+	rows, err := s.db.Query(query, MinuteOf(from), MinuteOf(to)+60, after.Ts, after.Sign, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *PgStore) List() ([]models.RetMsgCert, error) {
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcerts
+	ORDER BY ts ASC
+`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *PgStore) Minutes() ([]int64, error) {
+	rows, err := s.db.Query("SELECT DISTINCT (ts / 60) * 60 AS minute FROM msgcerts ORDER BY minute ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var minutes []int64
+	for rows.Next() {
+		var minute int64
+		if err := rows.Scan(&minute); err != nil {
+			return nil, err
+		}
+		minutes = append(minutes, minute)
+	}
+	return minutes, rows.Err()
+}
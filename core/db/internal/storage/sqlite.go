@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// SQLiteStore keeps MsgCerts in the msgcert table of the node's SQLite DB.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+func (s *SQLiteStore) Store(msgcert *models.MsgCert) error {
+	modCertsJSON, err := json.Marshal(msgcert.ModCerts)
+	if err != nil {
+		return fmt.Errorf("marshaling modCerts: %w", err)
+	}
+
+	query := "INSERT INTO msgcert(sender, content, ts, mod_certs, sign) VALUES (?, ?, ?, ?, ?) ON CONFLICT(sign) DO NOTHING"
+	result, err := s.db.Exec(query,
+		msgcert.PublicKey,
+		msgcert.Msg.Content,
+		msgcert.Msg.Ts,
+		string(modCertsJSON),
+		msgcert.Sign,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fetching rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAlreadyStored
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Delete(repCert *models.ReportCert) error {
+	repModCertsJSON, err := json.Marshal(repCert.RepModCerts)
+	if err != nil {
+		return fmt.Errorf("marshaling repModCerts: %w", err)
+	}
+
+	query := "UPDATE msgcert SET deleted = 1, repmod_certs = ?, rep_mode = ? WHERE sign = ? AND deleted = 0;"
+
+	result, err := s.db.Exec(query, string(repModCertsJSON), repCert.Mode, repCert.Msgcert.Sign)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fetching rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		var held bool
+		if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM msgcert WHERE sign = ?)", repCert.Msgcert.Sign).Scan(&held); err != nil {
+			return err
+		}
+		if held {
+			return ErrAlreadyDeleted
+		}
+		return ErrMsgCertNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Get(ts int64) ([]models.RetMsgCert, error) {
+	minute := MinuteOf(ts)
+
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcert
+	WHERE ts >= ? AND ts < ?
+`
+	rows, err := s.db.Query(query, minute, minute+60)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *SQLiteStore) GetPage(ts int64, after *Cursor, limit int) ([]models.RetMsgCert, error) {
+	minute := MinuteOf(ts)
+	if after == nil {
+		after = &Cursor{Ts: minute - 1}
+	}
+
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcert
+	WHERE ts >= ? AND ts < ? AND (ts > ? OR (ts = ? AND sign > ?))
+	ORDER BY ts ASC, sign ASC
+	LIMIT ?
+`
+	rows, err := s.db.Query(query, minute, minute+60, after.Ts, after.Ts, after.Sign, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *SQLiteStore) Range(from, to int64, after *Cursor, limit int) ([]models.RetMsgCert, error) {
+	if after == nil {
+		after = &Cursor{Ts: MinuteOf(from) - 1}
+	}
+
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcert
+	WHERE ts >= ? AND ts < ? AND (ts > ? OR (ts = ? AND sign > ?))
+	ORDER BY ts ASC, sign ASC
+	LIMIT ?
+`
+	rows, err := s.db.Query(query, MinuteOf(from), MinuteOf(to)+60, after.Ts, after.Ts, after.Sign, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *SQLiteStore) List() ([]models.RetMsgCert, error) {
+	query := `
+	SELECT sender, content, ts, mod_certs, sign, deleted, repmod_certs, rep_mode
+	FROM msgcert
+	ORDER BY ts ASC
+`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRetMsgCerts(rows)
+}
+
+func (s *SQLiteStore) Minutes() ([]int64, error) {
+	rows, err := s.db.Query("SELECT DISTINCT (ts / 60) * 60 AS minute FROM msgcert ORDER BY minute ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var minutes []int64
+	for rows.Next() {
+		var minute int64
+		if err := rows.Scan(&minute); err != nil {
+			return nil, err
+		}
+		minutes = append(minutes, minute)
+	}
+	return minutes, rows.Err()
+}
+
+func scanRetMsgCerts(rows *sql.Rows) ([]models.RetMsgCert, error) {
+	var retMsgCerts []models.RetMsgCert
+	for rows.Next() {
+		var retMsgCert models.RetMsgCert
+		var modCertsJSON string
+		var repModCertsJSON, repMode sql.NullString
+
+		if err := rows.Scan(&retMsgCert.PublicKey, &retMsgCert.Msg.Content, &retMsgCert.Msg.Ts, &modCertsJSON, &retMsgCert.Sign, &retMsgCert.Deleted, &repModCertsJSON, &repMode); err != nil {
+			log.Printf("Error scanning row: %v", err)
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(modCertsJSON), &retMsgCert.ModCerts); err != nil {
+			log.Printf("Error unmarshaling modCerts: %v", err)
+			continue
+		}
+
+		if repModCertsJSON.Valid && repModCertsJSON.String != "" {
+			if err := json.Unmarshal([]byte(repModCertsJSON.String), &retMsgCert.RepModCerts); err != nil {
+				log.Printf("Error unmarshaling repModCerts: %v", err)
+				continue
+			}
+		}
+		retMsgCert.RepMode = repMode.String
+		retMsgCerts = append(retMsgCerts, retMsgCert)
+	}
+	return retMsgCerts, rows.Err()
+}
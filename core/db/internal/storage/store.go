@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
@@ -9,7 +9,72 @@ import (
 	"github.com/libr-forum/Libr/core/db/internal/models"
 )
 
-func StoreMsgCert(msgcert *models.MsgCert) (string, error) {
+var (
+	ErrMsgCertNotFound = errors.New("MsgCert not found")
+	ErrAlreadyStored   = errors.New("MsgCert already stored")
+	ErrAlreadyDeleted  = errors.New("MsgCert already deleted")
+)
+
+// MsgCertStore is the persistence backend a db node keeps its MsgCerts in.
+// Implementations only persist; validation happens in StoreMsgCert before
+// anything reaches the store.
+type MsgCertStore interface {
+	// Store persists a validated MsgCert. Stores are keyed by signature:
+	// storing a cert that is already present leaves it untouched (including
+	// its deleted flag) and returns ErrAlreadyStored.
+	Store(msgcert *models.MsgCert) error
+	// Delete soft-deletes the MsgCert whose signature matches the one in
+	// the ReportCert and returns ErrMsgCertNotFound if nothing matched. A
+	// cert is deleted once: any later ReportCert for it, replayed or not,
+	// changes nothing and returns ErrAlreadyDeleted.
+	Delete(repCert *models.ReportCert) error
+	// Get returns every MsgCert (deleted or not) whose ts falls in the
+	// minute containing ts.
+	Get(ts int64) ([]models.RetMsgCert, error)
+	// GetPage returns up to limit certs of the minute containing ts that
+	// sort after the cursor, ordered by (ts, sign). A nil cursor starts at
+	// the beginning of the minute.
+	GetPage(ts int64, after *Cursor, limit int) ([]models.RetMsgCert, error)
+	// Range returns up to limit MsgCerts (deleted or not) whose minute lies
+	// in [MinuteOf(from), MinuteOf(to)] that sort after the cursor, ordered
+	// by (ts, sign). A nil cursor starts at the beginning of the span.
+	Range(from, to int64, after *Cursor, limit int) ([]models.RetMsgCert, error)
+	// List returns every MsgCert held by the store.
+	List() ([]models.RetMsgCert, error)
+	// Minutes returns the distinct minute keys the store holds certs for.
+	Minutes() ([]int64, error)
+}
+
+// MinuteOf truncates a unix timestamp to the start of its minute.
+func MinuteOf(ts int64) int64 {
+	return (ts / 60) * 60
+}
+
+// ToMsgCert strips the storage fields off a stored cert.
+func ToMsgCert(cert models.RetMsgCert) models.MsgCert {
+	return models.MsgCert{
+		PublicKey: cert.PublicKey,
+		Msg:       cert.Msg,
+		ModCerts:  cert.ModCerts,
+		Sign:      cert.Sign,
+	}
+}
+
+// ToReportCert rebuilds the ReportCert that deleted a stored cert. ok is
+// false when the cert is not deleted or was deleted before ReportCerts
+// were kept.
+func ToReportCert(cert models.RetMsgCert) (repCert models.ReportCert, ok bool) {
+	if cert.Deleted != "1" || len(cert.RepModCerts) == 0 {
+		return models.ReportCert{}, false
+	}
+	return models.ReportCert{
+		Msgcert:     ToMsgCert(cert),
+		RepModCerts: cert.RepModCerts,
+		Mode:        cert.RepMode,
+	}, true
+}
+
+func StoreMsgCert(store MsgCertStore, msgcert *models.MsgCert) (string, error) {
 	fmt.Println("Storing MsgCert")
 	if err := ValidateMsgCert(msgcert); err != nil {
 		log.Printf("Error validating MsgCert: %v", err)
@@ -21,91 +86,99 @@ func StoreMsgCert(msgcert *models.MsgCert) (string, error) {
 		return "", err
 	}
 
-	query := "INSERT INTO msgcert(sender, content, ts, mod_certs, sign) VALUES (?, ?, ?, ?, ?)"
-
-	modCertsJSON, err := json.Marshal(msgcert.ModCerts)
-	if err != nil {
-		log.Printf("Error marshaling modCerts: %v", err)
-		return "Error marshaling modCerts", err
-	}
-
-	_, err = config.DB.Exec(query,
-		msgcert.PublicKey,
-		msgcert.Msg.Content,
-		msgcert.Msg.Ts,
-		string(modCertsJSON),
-		msgcert.Sign,
-	)
-
-	fmt.Println("Probably stored???")
-	if err != nil {
+	if err := store.Store(msgcert); err != nil {
+		if errors.Is(err, ErrAlreadyStored) {
+			return "Message certificate already stored", err
+		}
 		log.Printf("Error inserting MsgCert: %v", err)
 		return "Error inserting MsgCert", err
 	}
 	return "Message certificate successfully inserted", nil
 }
 
-func DeleteMsgCert(repCert *models.ReportCert) error {
+func DeleteMsgCert(store MsgCertStore, repCert *models.ReportCert) error {
 	fmt.Println("Deleting MsgCert :]")
-	query := "UPDATE msgcert SET deleted = 1 WHERE ts = ? AND sender = ?;"
+	if err := store.Delete(repCert); err != nil {
+		if !errors.Is(err, ErrMsgCertNotFound) && !errors.Is(err, ErrAlreadyDeleted) {
+			log.Printf("Error soft-deleting MsgCert: %v", err)
+		}
+		return err
+	}
+	return nil
+}
 
-	result, err := config.DB.Exec(query, repCert.Msgcert.Msg.Ts, repCert.Msgcert.PublicKey)
+// GetMsgCertRange returns one page of the certs in [from, to], starting
+// after the opaque cursor (empty for the first page). next is empty once the
+// span is exhausted. A cursor from outside the span is ErrInvalidCursor.
+func GetMsgCertRange(store MsgCertStore, from, to int64, cursor string, limit int) (page []models.RetMsgCert, next string, err error) {
+	after, err := DecodeCursor(cursor)
 	if err != nil {
-		log.Printf("Error soft-deleting MsgCert: %v", err)
-		return err
+		return nil, "", err
+	}
+	if after != nil && (MinuteOf(after.Ts) < MinuteOf(from) || MinuteOf(after.Ts) > MinuteOf(to)) {
+		return nil, "", ErrInvalidCursor
 	}
+	limit = pageLimit(limit)
 
-	rowsAffected, err := result.RowsAffected()
+	page, err = store.Range(from, to, after, limit+1)
 	if err != nil {
-		log.Printf("Error fetching rows affected: %v", err)
-		return err
+		log.Printf("Error fetching MsgCert range: %v", err)
+		return nil, "", err
 	}
+	page, next = trimPage(page, limit)
+	return page, next, nil
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("MsgCert not found")
+// GetMsgCertPage returns one page of the minute containing ts, starting after
+// the opaque cursor (empty for the first page). next is empty once the
+// minute is exhausted. A cursor from another minute is ErrInvalidCursor.
+func GetMsgCertPage(store MsgCertStore, ts int64, cursor string, limit int) (page []models.RetMsgCert, next string, err error) {
+	after, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
 	}
+	if after != nil && MinuteOf(after.Ts) != MinuteOf(ts) {
+		return nil, "", ErrInvalidCursor
+	}
+	limit = pageLimit(limit)
 
-	return nil
+	// Fetch one extra row to learn whether another page follows
+	page, err = store.GetPage(ts, after, limit+1)
+	if err != nil {
+		log.Printf("Error fetching MsgCert page: %v", err)
+		return nil, "", err
+	}
+	page, next = trimPage(page, limit)
+	return page, next, nil
 }
 
-func GetMsgCert(ts int64) []models.RetMsgCert {
-	// Truncate the timestamp to the minute
+// pageLimit applies the default and the cap to a requested page size.
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return config.FindValuePageSize
+	}
+	if limit > config.MaxFindValuePageSize {
+		return config.MaxFindValuePageSize
+	}
+	return limit
+}
 
-	minute := (ts / 60) * 60
-	nextMinute := minute + 60
+// trimPage cuts a page fetched with one extra row down to limit and returns
+// the cursor of the next page, or "" when the extra row was not there.
+func trimPage(page []models.RetMsgCert, limit int) ([]models.RetMsgCert, string) {
+	if len(page) <= limit {
+		return page, ""
+	}
+	page = page[:limit]
+	last := page[len(page)-1]
+	return page, EncodeCursor(&Cursor{Ts: last.Msg.Ts, Sign: last.Sign})
+}
 
-	query := `
-	SELECT sender, content, ts, mod_certs, sign, deleted
-	FROM msgcert
-	WHERE ts >= ? AND ts < ?
-`
-	rows, err := config.DB.Query(query, minute, nextMinute)
+func GetMsgCert(store MsgCertStore, ts int64) []models.RetMsgCert {
+	retMsgCerts, err := store.Get(ts)
 	if err != nil {
 		log.Printf("Error fetching MsgCert: %v", err)
 		return nil
 	}
-	defer rows.Close()
-
-	var retMsgCerts []models.RetMsgCert
-	for rows.Next() {
-		var retMsgCert models.RetMsgCert
-		var modCertsJSON string
-		var tsVal int64
-
-		if err := rows.Scan(&retMsgCert.PublicKey, &retMsgCert.Msg.Content, &tsVal, &modCertsJSON, &retMsgCert.Sign, &retMsgCert.Deleted); err != nil {
-			log.Printf("Error scanning row: %v", err)
-			continue
-		}
-
-		retMsgCert.Msg.Ts = tsVal
-
-		if err := json.Unmarshal([]byte(modCertsJSON), &retMsgCert.ModCerts); err != nil {
-			log.Printf("Error unmarshaling modCerts: %v", err)
-			continue
-		}
-		fmt.Println("retMsgCert:", retMsgCert)
-		retMsgCerts = append(retMsgCerts, retMsgCert)
-	}
-
 	return retMsgCerts
 }
@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/libr-forum/Libr/core/crypto/schema"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+)
+
+// backends returns a fresh instance of every store that runs without a
+// server: the in-memory one and SQLite on a migrated temp file.
+func backends(t *testing.T) map[string]MsgCertStore {
+	t.Helper()
+	db, err := sql.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "libr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := schema.Migrate(db, schema.Question, config.SQLiteMigrations); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]MsgCertStore{
+		"memory": NewMemoryStore(),
+		"sqlite": NewSQLiteStore(db),
+	}
+}
+
+func testCert(sender string, ts int64, sign string) *models.MsgCert {
+	return &models.MsgCert{
+		PublicKey: sender,
+		Msg:       models.Msg{Content: "hello from " + sender, Ts: ts},
+		ModCerts:  []models.ModCert{{Sign: "mod-sign", PublicKey: "mod-key", Status: "1"}},
+		Sign:      sign,
+	}
+}
+
+func signs(certs []models.RetMsgCert) []string {
+	var out []string
+	for _, c := range certs {
+		out = append(out, c.Sign)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestStore(t *testing.T) {
+	for name, store := range backends(t) {
+		cert := testCert("alice", 125, "sig-a")
+		if err := store.Store(cert); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := store.Store(cert); !errors.Is(err, ErrAlreadyStored) {
+			t.Fatalf("%s: second store returned %v, want ErrAlreadyStored", name, err)
+		}
+
+		got, err := store.Get(cert.Msg.Ts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%s: got %d certs, want 1", name, len(got))
+		}
+		if got[0].Deleted != "0" || !reflect.DeepEqual(ToMsgCert(got[0]), *cert) {
+			t.Fatalf("%s: stored %+v, read back %+v", name, *cert, got[0])
+		}
+	}
+}
+
+func TestStoreKeepsDeletion(t *testing.T) {
+	for name, store := range backends(t) {
+		cert := testCert("alice", 125, "sig-a")
+		if err := store.Store(cert); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := store.Delete(&models.ReportCert{Msgcert: *cert, Mode: "delete"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// A replica pushing the live cert again must not undo the delete
+		if err := store.Store(cert); !errors.Is(err, ErrAlreadyStored) {
+			t.Fatalf("%s: restore returned %v, want ErrAlreadyStored", name, err)
+		}
+		got, _ := store.Get(cert.Msg.Ts)
+		if len(got) != 1 || got[0].Deleted != "1" {
+			t.Fatalf("%s: cert after restore is %+v", name, got)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	for name, store := range backends(t) {
+		// Same sender and second, told apart only by the signature
+		first, second := testCert("alice", 125, "sig-a"), testCert("alice", 125, "sig-b")
+		for _, c := range []*models.MsgCert{first, second} {
+			if err := store.Store(c); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		repCert := &models.ReportCert{
+			Msgcert:     *first,
+			RepModCerts: []models.ModCert{{Sign: "rep-sign", PublicKey: "mod-key", Status: "0"}},
+			Mode:        "report",
+		}
+		if err := store.Delete(repCert); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		got, _ := store.Get(first.Msg.Ts)
+		if len(got) != 2 {
+			t.Fatalf("%s: got %d certs, want 2", name, len(got))
+		}
+		for _, c := range got {
+			switch c.Sign {
+			case first.Sign:
+				back, ok := ToReportCert(c)
+				if !ok || !reflect.DeepEqual(back, *repCert) {
+					t.Fatalf("%s: deleted cert carries %+v, want %+v", name, back, *repCert)
+				}
+			case second.Sign:
+				if c.Deleted != "0" {
+					t.Fatalf("%s: deleting %s also deleted %s", name, first.Sign, second.Sign)
+				}
+			}
+		}
+
+		// The first ReportCert is the one kept; another for the same cert,
+		// replayed or not, changes nothing
+		again := &models.ReportCert{
+			Msgcert:     *first,
+			RepModCerts: []models.ModCert{{Sign: "author-sign", PublicKey: "alice", Status: "1"}},
+			Mode:        "delete",
+		}
+		for _, rc := range []*models.ReportCert{repCert, again} {
+			if err := store.Delete(rc); !errors.Is(err, ErrAlreadyDeleted) {
+				t.Fatalf("%s: deleting a deleted cert returned %v, want ErrAlreadyDeleted", name, err)
+			}
+		}
+		got, _ = store.Get(first.Msg.Ts)
+		for _, c := range got {
+			if back, _ := ToReportCert(c); c.Sign == first.Sign && !reflect.DeepEqual(back, *repCert) {
+				t.Fatalf("%s: second delete replaced the ReportCert with %+v", name, back)
+			}
+		}
+
+		missing := &models.ReportCert{Msgcert: *testCert("alice", 125, "sig-unknown"), Mode: "report"}
+		if err := store.Delete(missing); !errors.Is(err, ErrMsgCertNotFound) {
+			t.Fatalf("%s: deleting an unknown cert returned %v, want ErrMsgCertNotFound", name, err)
+		}
+	}
+}
+
+func TestGetListMinutes(t *testing.T) {
+	for name, store := range backends(t) {
+		certs := []*models.MsgCert{
+			testCert("alice", 61, "sig-a"),
+			testCert("bob", 120, "sig-b"),
+			testCert("carol", 179, "sig-c"),
+			testCert("dave", 600, "sig-d"),
+		}
+		for _, c := range certs {
+			if err := store.Store(c); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		cases := []struct {
+			ts   int64
+			want []string
+		}{
+			{90, []string{"sig-a"}},
+			{150, []string{"sig-b", "sig-c"}},
+			{300, nil},
+		}
+		for _, c := range cases {
+			got, err := store.Get(c.ts)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !reflect.DeepEqual(signs(got), c.want) {
+				t.Fatalf("%s: minute of %d holds %v, want %v", name, c.ts, signs(got), c.want)
+			}
+		}
+
+		all, err := store.List()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := []string{"sig-a", "sig-b", "sig-c", "sig-d"}; !reflect.DeepEqual(signs(all), want) {
+			t.Fatalf("%s: list holds %v, want %v", name, signs(all), want)
+		}
+
+		minutes, err := store.Minutes()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		sort.Slice(minutes, func(i, j int) bool { return minutes[i] < minutes[j] })
+		if want := []int64{60, 120, 600}; !reflect.DeepEqual(minutes, want) {
+			t.Fatalf("%s: minutes are %v, want %v", name, minutes, want)
+		}
+	}
+}
+
+// pageAll follows next cursors from the first page of a listing until it
+// runs out and returns every cert in the order served.
+func pageAll(t *testing.T, name string, fetch func(cursor string) ([]models.RetMsgCert, string, error)) []models.RetMsgCert {
+	t.Helper()
+	var all []models.RetMsgCert
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("%s: paging does not end", name)
+		}
+		page, next, err := fetch(cursor)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		all = append(all, page...)
+		if next == "" {
+			return all
+		}
+		cursor = next
+	}
+}
+
+func inOrder(certs []models.RetMsgCert) bool {
+	return sort.SliceIsSorted(certs, func(i, j int) bool {
+		if certs[i].Msg.Ts != certs[j].Msg.Ts {
+			return certs[i].Msg.Ts < certs[j].Msg.Ts
+		}
+		return certs[i].Sign < certs[j].Sign
+	})
+}
+
+func TestGetMsgCertPage(t *testing.T) {
+	for name, store := range backends(t) {
+		// Stored out of order, with two certs sharing a second
+		for _, c := range []*models.MsgCert{
+			testCert("carol", 150, "sig-c"),
+			testCert("alice", 130, "sig-z"),
+			testCert("bob", 130, "sig-b"),
+			testCert("dave", 179, "sig-d"),
+			testCert("erin", 121, "sig-e"),
+			testCert("frank", 200, "sig-f"),
+		} {
+			if err := store.Store(c); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		got := pageAll(t, name, func(cursor string) ([]models.RetMsgCert, string, error) {
+			page, next, err := GetMsgCertPage(store, 150, cursor, 2)
+			if err == nil && len(page) > 2 {
+				t.Fatalf("%s: page of %d certs, limit 2", name, len(page))
+			}
+			return page, next, err
+		})
+		if want := []string{"sig-b", "sig-c", "sig-d", "sig-e", "sig-z"}; !reflect.DeepEqual(signs(got), want) {
+			t.Fatalf("%s: pages hold %v, want %v", name, signs(got), want)
+		}
+		if !inOrder(got) {
+			t.Fatalf("%s: pages not in (ts, sign) order: %v", name, got)
+		}
+
+		// The same cursor serves the same page
+		_, next, _ := GetMsgCertPage(store, 150, "", 2)
+		first, _, _ := GetMsgCertPage(store, 150, next, 2)
+		again, _, _ := GetMsgCertPage(store, 150, next, 2)
+		if !reflect.DeepEqual(signs(first), signs(again)) {
+			t.Fatalf("%s: cursor served %v, then %v", name, signs(first), signs(again))
+		}
+
+		// A cursor for another minute or one that does not decode is refused
+		foreign := EncodeCursor(&Cursor{Ts: 200, Sign: "sig-f"})
+		for _, cursor := range []string{foreign, "garbage!"} {
+			if _, _, err := GetMsgCertPage(store, 150, cursor, 2); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("%s: cursor %q: got %v, want ErrInvalidCursor", name, cursor, err)
+			}
+		}
+	}
+}
+
+func TestGetMsgCertPageLimit(t *testing.T) {
+	for name, store := range backends(t) {
+		for i := 0; i < config.MaxFindValuePageSize+5; i++ {
+			if err := store.Store(testCert("alice", 120+int64(i%60), fmt.Sprintf("sig-%03d", i))); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		cases := []struct {
+			limit, want int
+		}{
+			{0, config.FindValuePageSize},
+			{-1, config.FindValuePageSize},
+			{7, 7},
+			{1000, config.MaxFindValuePageSize},
+		}
+		for _, c := range cases {
+			page, next, err := GetMsgCertPage(store, 120, "", c.limit)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(page) != c.want || next == "" {
+				t.Fatalf("%s: limit %d served %d certs (next %q), want %d and a next page", name, c.limit, len(page), next, c.want)
+			}
+		}
+	}
+}
+
+func TestGetMsgCertRange(t *testing.T) {
+	for name, store := range backends(t) {
+		for _, c := range []*models.MsgCert{
+			testCert("alice", 59, "sig-before"),
+			testCert("bob", 60, "sig-b"),
+			testCert("carol", 119, "sig-c"),
+			testCert("dave", 130, "sig-d"),
+			testCert("erin", 130, "sig-a"),
+			testCert("frank", 239, "sig-f"),
+			testCert("grace", 240, "sig-after"),
+		} {
+			if err := store.Store(c); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		got := pageAll(t, name, func(cursor string) ([]models.RetMsgCert, string, error) {
+			return GetMsgCertRange(store, 60, 200, cursor, 2)
+		})
+		if len(got) != 5 || !inOrder(got) {
+			t.Fatalf("%s: range served %v, want 5 certs in (ts, sign) order", name, signs(got))
+		}
+		for _, c := range got {
+			if c.Sign == "sig-before" || c.Sign == "sig-after" {
+				t.Fatalf("%s: range served %s from outside the span", name, c.Sign)
+			}
+		}
+
+		for _, cursor := range []string{
+			EncodeCursor(&Cursor{Ts: 30, Sign: "sig-x"}),
+			EncodeCursor(&Cursor{Ts: 240, Sign: "sig-after"}),
+			"garbage!",
+		} {
+			if _, _, err := GetMsgCertRange(store, 60, 200, cursor, 2); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("%s: cursor %q: got %v, want ErrInvalidCursor", name, cursor, err)
+			}
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/modset"
 	"github.com/libr-forum/Libr/core/db/config"
 	"github.com/libr-forum/Libr/core/db/internal/models"
 	"github.com/libr-forum/Libr/core/db/internal/utils"
@@ -77,7 +78,9 @@ func ValidateRepCertFields(repCert *models.ReportCert) error {
 	return nil
 }
 
-func ValidateRepCert(repCert *models.ReportCert, validMods []*models.Mod) error {
+// ValidateRepCert checks a ReportCert. Report mode takes approvals from the
+// moderator set active when the reported message was sent.
+func ValidateRepCert(repCert *models.ReportCert) error {
 	if err := ValidateRepCertFields(repCert); err != nil {
 		return err
 	}
@@ -104,16 +107,27 @@ func ValidateRepCert(repCert *models.ReportCert, validMods []*models.Mod) error
 	}
 
 	// Validate associated moderator certs
-	return ValidateRepModCerts(repCert, validMods)
+	var set *modset.Set
+	if repCert.Mode != "delete" {
+		set, err = utils.ModSetAt(repCert.Msgcert.Msg.Ts)
+		if err != nil {
+			return fmt.Errorf("❌ No moderator set for the message: %w", err)
+		}
+	}
+	return ValidateRepModCerts(repCert, set)
 }
 
-func ValidateRepModCerts(repCert *models.ReportCert, validMods []*models.Mod) error {
-	validMap := make(map[string]struct{})
-	for _, mod := range validMods {
-		validMap[mod.PublicKey] = struct{}{}
+// ValidateRepModCerts checks the certs of a ReportCert: the author's own in
+// delete mode, otherwise a majority of set's members.
+func ValidateRepModCerts(repCert *models.ReportCert, set *modset.Set) error {
+	if repCert.Mode != "delete" && set == nil {
+		return errors.New("❌ No moderator set to check report certs against")
 	}
-	fmt.Println("Valid mods map:", validMap)
-	totalMods := len(validMods)
+	totalMods := 0
+	if set != nil {
+		totalMods = len(set.Members)
+	}
+	counted := make(map[string]bool)
 
 	msgCertSign := repCert.Msgcert.Sign
 	msgCertPubKey := repCert.Msgcert.PublicKey
@@ -130,14 +144,17 @@ func ValidateRepModCerts(repCert *models.ReportCert, validMods []*models.Mod) er
 			if !cryptoutils.VerifySignature(repmodcert.PublicKey, msgCertSign, repmodcert.Sign) {
 				return fmt.Errorf("❌ Delete mode: invalid signature from original user %s", repmodcert.PublicKey)
 			}
-			approveCount = totalMods
-			break
+			return nil
 		}
 
 		// Manual (report) mode
-		if _, ok := validMap[repmodcert.PublicKey]; !ok {
-			return fmt.Errorf("❌ Unauthorized moderator: %s", repmodcert.PublicKey)
+		if !set.Has(repmodcert.PublicKey) {
+			return fmt.Errorf("❌ Unauthorized moderator for epoch %d: %s", set.Epoch, repmodcert.PublicKey)
+		}
+		if counted[repmodcert.PublicKey] {
+			return fmt.Errorf("❌ Duplicate cert from mod: %s", repmodcert.PublicKey)
 		}
+		counted[repmodcert.PublicKey] = true
 
 		payload := repCert.Msgcert.Sign + repmodcert.Status
 		if !cryptoutils.VerifySignature(repmodcert.PublicKey, payload, repmodcert.Sign) {
@@ -162,12 +179,25 @@ func ValidateRepModCerts(repCert *models.ReportCert, validMods []*models.Mod) er
 	}
 }
 
+// ValidateModCert checks the mod certs of a MsgCert against the moderator
+// set active at the message's timestamp.
 func ValidateModCert(msgCert *models.MsgCert) error {
 	apprCount := 0
 	rejCount := 0
-	validMods, _ := utils.GetOnlineMods()
-	totalMods := len(validMods)
+	set, err := utils.ModSetAt(msgCert.Msg.Ts)
+	if err != nil {
+		return fmt.Errorf("❌ No moderator set for the message: %w", err)
+	}
+	totalMods := len(set.Members)
+	counted := make(map[string]bool)
 	for _, modcert := range msgCert.ModCerts {
+		if !set.Has(modcert.PublicKey) {
+			return fmt.Errorf("❌ Unauthorized moderator for epoch %d: %s", set.Epoch, modcert.PublicKey)
+		}
+		if counted[modcert.PublicKey] {
+			return fmt.Errorf("❌ Duplicate ModCert from mod: %s", modcert.PublicKey)
+		}
+		counted[modcert.PublicKey] = true
 		payload := msgCert.Msg.Content + strconv.FormatInt(msgCert.Msg.Ts, 10) + modcert.Status
 		fmt.Println("Payload:", payload)
 		if !cryptoutils.VerifySignature(modcert.PublicKey, payload, modcert.Sign) {
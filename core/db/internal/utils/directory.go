@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/directory"
+	"github.com/libr-forum/Libr/core/db/config"
+	"github.com/libr-forum/Libr/core/db/internal/keycache"
+	"github.com/libr-forum/Libr/core/db/internal/models"
+	"github.com/libr-forum/Libr/core/db/internal/node"
+)
+
+// Dir is the directory opened by SetupDirectory
+var Dir directory.Directory
+
+var errNoDirectory = errors.New("directory not set up")
+
+// SetupDirectory opens the directory at uri (see directory.Open)
+func SetupDirectory(uri string) error {
+	d, err := directory.Open(context.Background(), uri)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+
+	Dir = d
+	log.Printf("✅ Directory ready (%T)", d)
+	return nil
+}
+
+// CloseDirectory releases the directory's connection, if any
+func CloseDirectory() {
+	if Dir != nil {
+		if err := Dir.Close(); err != nil {
+			log.Println("⚠️ Error closing directory:", err)
+		} else {
+			log.Println("🛑 Directory closed")
+		}
+	}
+}
+
+// GetDbAddr lists the bootstrap db nodes
+func GetDbAddr() ([]*models.Node, error) {
+	if Dir == nil {
+		return nil, errNoDirectory
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entries, err := Dir.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var nodeList []*models.Node
+	for _, e := range entries {
+		if err := e.Verify(now); err != nil {
+			log.Printf("Skipping node %s: %v", e.PeerID, err)
+			continue
+		}
+		nodeId, err := node.DecodeNodeID(e.NodeID)
+		if err != nil {
+			log.Printf("Skipping node %s with invalid node_id: %v", e.PeerID, err)
+			continue
+		}
+		nodeList = append(nodeList, &models.Node{
+			NodeId: nodeId,
+			PeerId: e.PeerID,
+		})
+	}
+	return nodeList, nil
+}
+
+// PublishPresence signs this node's presence record for peerId, valid for
+// ttl, and publishes it to the directory. A ttl of 0 withdraws the node for
+// config.PresenceTTL, the longest a record of it can still be live.
+func PublishPresence(peerId string, ttl time.Duration) error {
+	if Dir == nil {
+		return errNoDirectory
+	}
+	sign := directory.SignPresence
+	if ttl == 0 {
+		sign, ttl = directory.SignWithdrawal, config.PresenceTTL
+	}
+	rec, err := sign(keycache.PrivKey, peerId, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to sign presence record: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return Dir.Publish(ctx, rec)
+}
+
+// RunPresence publishes this node's presence now and every interval until
+// ctx is done.
+func RunPresence(ctx context.Context, peerId string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := PublishPresence(peerId, config.PresenceTTL); err != nil {
+			fmt.Println("⚠️ Failed to publish presence:", err)
+		} else {
+			fmt.Println("📣 Presence published to the directory")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func GetRelayAddr() ([]string, error) {
+	if Dir == nil {
+		return nil, errNoDirectory
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	relayList, err := Dir.Relays(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch relay addresses: %w", err)
+	}
+	return relayList, nil
+}
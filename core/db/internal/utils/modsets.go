@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/modset"
+	"github.com/libr-forum/Libr/core/db/config"
+)
+
+// ModSets holds the verified moderator sets; see SetupModSets
+var ModSets *modset.Registry
+
+// minModSetResync keeps certs from an unknown epoch from sending every
+// request to the directory.
+const minModSetResync = 30 * time.Second
+
+var (
+	modSetSyncMu   sync.Mutex
+	lastModSetSync time.Time
+)
+
+// SetupModSets creates ModSets, trusting config.ModRootKeys, loads the sets
+// cached by earlier runs and syncs with the directory. It fails when there
+// is neither a root key nor a cached set, since no store could validate.
+func SetupModSets() error {
+	ModSets = modset.NewRegistry(config.ModRootKeys())
+	if err := ModSets.Load(config.ModSetCachePath()); err != nil {
+		log.Println("⚠️ Some cached moderator sets were rejected:", err)
+	}
+	if err := ModSets.Anchored(); err != nil {
+		return fmt.Errorf("%w: set LIBR_MOD_ROOT_KEYS to the network's governance keys", err)
+	}
+	if err := SyncModSets(); err != nil {
+		log.Println("⚠️ Failed to sync moderator sets:", err)
+	}
+	if ModSets.Len() == 0 {
+		log.Println("❌ No moderator set known yet: every store and report is rejected until one signed by a root key is published")
+	}
+	log.Printf("✅ %d moderator sets known", ModSets.Len())
+	return nil
+}
+
+// SyncModSets adds the sets the directory lists to ModSets and caches them
+// when any were new.
+func SyncModSets() error {
+	if ModSets == nil {
+		return errors.New("moderator sets not set up")
+	}
+	if Dir == nil {
+		return errNoDirectory
+	}
+
+	modSetSyncMu.Lock()
+	lastModSetSync = time.Now()
+	modSetSyncMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sets, err := Dir.ModSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch moderator sets: %w", err)
+	}
+	added, errs := ModSets.AddAll(sets)
+	for _, err := range errs {
+		log.Println("⚠️ Rejected moderator set:", err)
+	}
+	if added > 0 {
+		fmt.Printf("🛡️ %d new moderator sets\n", added)
+		if err := ModSets.Save(config.ModSetCachePath()); err != nil {
+			return fmt.Errorf("failed to cache moderator sets: %w", err)
+		}
+	}
+	return nil
+}
+
+// RunModSetSync syncs the moderator sets now and every interval until ctx
+// is done.
+func RunModSetSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := SyncModSets(); err != nil {
+			fmt.Println("⚠️ Failed to sync moderator sets:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ModSetAt returns the moderator set active at unix time ts. When none is
+// known it syncs with the directory, at most once per minModSetResync, and
+// asks again.
+func ModSetAt(ts int64) (*modset.Set, error) {
+	if ModSets == nil {
+		return nil, errors.New("moderator sets not set up")
+	}
+	set, err := ModSets.ActiveAt(ts)
+	if !errors.Is(err, modset.ErrNoActiveSet) {
+		return set, err
+	}
+
+	modSetSyncMu.Lock()
+	recent := time.Since(lastModSetSync) < minModSetResync
+	modSetSyncMu.Unlock()
+	if recent {
+		return nil, err
+	}
+	if err := SyncModSets(); err != nil {
+		fmt.Println("⚠️ Failed to sync moderator sets:", err)
+	}
+	return ModSets.ActiveAt(ts)
+}
@@ -1,9 +1,9 @@
 package utils
 
 import (
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
 	"github.com/libr-forum/Libr/core/db/config"
 	"github.com/libr-forum/Libr/core/db/internal/models"
-	"github.com/libr-forum/Libr/core/db/internal/node"
 	"github.com/libr-forum/Libr/core/db/internal/routing"
 )
 
@@ -19,12 +19,10 @@ func CountModCerts(modCerts []models.ModCert) (approved, rejected int) {
 	return approved, rejected
 }
 
-func ShouldDelete(self *models.Node, key *[20]byte, rt *routing.RoutingTable) bool {
+func ShouldDelete(self *models.Node, key *keyspace.ID, rt *routing.RoutingTable) bool {
 	closest := rt.FindClosest(*key, config.K)
 	if len(closest) == 0 {
 		return false
 	}
-	selfDist := node.XORBigInt(self.NodeId, *key)
-	lastDist := node.XORBigInt(closest[len(closest)-1].NodeId, *key)
-	return selfDist.Cmp(lastDist) < 0
+	return keyspace.CompareDistance(*key, self.NodeId, closest[len(closest)-1].NodeId) < 0
 }
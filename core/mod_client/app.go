@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/directory"
 	"github.com/libr-forum/Libr/core/mod_client/alias"
 	"github.com/libr-forum/Libr/core/mod_client/avatar"
 	cache "github.com/libr-forum/Libr/core/mod_client/cache_handler"
@@ -37,8 +39,20 @@ type App struct {
 func NewApp() *App {
 	cache.InitCacheFile()
 	keycache.InitKeys()
-	config.LoadConfig()
-	util.SetupMongo(config.MongoURI)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Println("Failed to load config:", err)
+		cfg = &config.Config{}
+	}
+	if err := util.SetupDirectory(cfg.Directory); err != nil {
+		if errors.Is(err, directory.ErrNotConfigured) {
+			log.Fatal("❌ ", err)
+		}
+		log.Println("❌ Directory unavailable:", err)
+	}
+	if err := util.SetupModSets(cfg.ModRootKeys); err != nil {
+		log.Fatal("❌ ", err)
+	}
 	amImod, _ := util.AmIMod(base64.StdEncoding.EncodeToString(keycache.PubKey))
 	if amImod {
 		config.InitDB()
@@ -231,7 +245,7 @@ func (a *App) Report(msgcert types.MsgCert, reason *string) string {
 	defer cancel()
 
 	modChan := make(chan []types.ModCert, 1)
-	mods, _ := util.GetOnlineMods()
+	mods, _, _ := util.GetModsAt(msgcert.Msg.Ts)
 	go func() {
 		modcerts := core.ManualSendToMods(msgcert, mods, *reason, true)
 		modChan <- modcerts
@@ -281,6 +295,13 @@ func (a *App) FetchAll() []types.RetMsgCert {
 	return messages
 }
 
+// FetchMinute fetches the messages of the minute ts over
+// config.DisjointPaths disjoint lookups, so the UI can show how many paths
+// agreed on each message.
+func (a *App) FetchMinute(ts int64) types.FetchResult {
+	return core.FetchDisjoint(ts, config.DisjointPaths)
+}
+
 func (a *App) FetchMessageReports() []models.MsgCert {
 	reports, err := moddb.GetUnmoderatedMsgs()
 	if err != nil {
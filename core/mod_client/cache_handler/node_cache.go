@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/mod_client/config"
+	"github.com/libr-forum/Libr/core/mod_client/logger"
+	"github.com/libr-forum/Libr/core/mod_client/types"
+)
+
+const nodeCacheFileName = "node_cache.json"
+
+// NodeHealth is what the client remembers about a db node it talked to.
+type NodeHealth struct {
+	NodeId     string `json:"node_id"` // base64
+	PeerId     string `json:"peer_id"`
+	Successes  int    `json:"successes"`
+	Failures   int    `json:"failures"`
+	FailStreak int    `json:"fail_streak"`
+	LastSeen   int64  `json:"last_seen"` // unix time of the last answer, 0 if none
+}
+
+// Score rates h between 0 and 1: its smoothed success rate, halved for every
+// config.NodeHealthHalfLife since it last answered. Nodes never heard from
+// score the 0.5 prior.
+func (h *NodeHealth) Score(now time.Time) float64 {
+	rate := float64(h.Successes+1) / float64(h.Successes+h.Failures+2)
+	if h.LastSeen == 0 {
+		return rate
+	}
+	age := now.Sub(time.Unix(h.LastSeen, 0))
+	return rate * math.Exp2(-age.Hours()/config.NodeHealthHalfLife.Hours())
+}
+
+// usable reports whether h answered within config.NodeCacheTTL and has not
+// failed config.NodeMaxFailStreak times in a row since.
+func (h *NodeHealth) usable(now time.Time) bool {
+	return h.LastSeen != 0 &&
+		now.Sub(time.Unix(h.LastSeen, 0)) <= config.NodeCacheTTL &&
+		h.FailStreak < config.NodeMaxFailStreak
+}
+
+var (
+	nodeCache     map[string]*NodeHealth // by peer ID
+	nodeCacheOnce sync.Once
+	nodeCacheMu   sync.Mutex
+)
+
+func nodeCachePath() string {
+	return filepath.Join(GetCacheDir(), nodeCacheFileName)
+}
+
+// loadNodeCache reads the node cache on first use. A missing or corrupt file
+// starts an empty cache.
+func loadNodeCache() {
+	nodeCacheOnce.Do(func() {
+		nodeCache = make(map[string]*NodeHealth)
+		data, err := os.ReadFile(nodeCachePath())
+		if err != nil {
+			return
+		}
+		var entries []*NodeHealth
+		if err := json.Unmarshal(data, &entries); err != nil {
+			logger.LogToFile("[DEBUG]Ignoring corrupt node cache")
+			return
+		}
+		for _, h := range entries {
+			nodeCache[h.PeerId] = h
+		}
+	})
+}
+
+func saveNodeCache() error {
+	entries := make([]*NodeHealth, 0, len(nodeCache))
+	for _, h := range nodeCache {
+		entries = append(entries, h)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(GetCacheDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(nodeCachePath(), data, 0644)
+}
+
+// RecordLookup feeds the outcome of a lookup into the node cache: every node
+// in answered gets a success, every node in failed a failure. Stale and
+// hopeless entries are dropped, and the cache is cut to
+// config.NodeCacheSize by score before it is saved.
+func RecordLookup(answered, failed []*types.Node) error {
+	loadNodeCache()
+	nodeCacheMu.Lock()
+	defer nodeCacheMu.Unlock()
+
+	now := time.Now()
+	entry := func(n *types.Node) *NodeHealth {
+		h, ok := nodeCache[n.PeerId]
+		if !ok {
+			h = &NodeHealth{PeerId: n.PeerId}
+			nodeCache[n.PeerId] = h
+		}
+		h.NodeId = base64.StdEncoding.EncodeToString(n.NodeId[:])
+		return h
+	}
+	for _, n := range answered {
+		h := entry(n)
+		h.Successes++
+		h.FailStreak = 0
+		h.LastSeen = now.Unix()
+	}
+	for _, n := range failed {
+		h := entry(n)
+		h.Failures++
+		h.FailStreak++
+	}
+
+	var kept []*NodeHealth
+	for peerId, h := range nodeCache {
+		if h.FailStreak >= config.NodeMaxFailStreak || (h.LastSeen != 0 && !h.usable(now)) {
+			delete(nodeCache, peerId)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	if len(kept) > config.NodeCacheSize {
+		sort.Slice(kept, func(i, j int) bool { return kept[i].Score(now) > kept[j].Score(now) })
+		for _, h := range kept[config.NodeCacheSize:] {
+			delete(nodeCache, h.PeerId)
+		}
+	}
+	return saveNodeCache()
+}
+
+// CachedNodes returns the cached nodes that answered recently, best first.
+func CachedNodes() []*types.Node {
+	loadNodeCache()
+	nodeCacheMu.Lock()
+	defer nodeCacheMu.Unlock()
+
+	now := time.Now()
+	var nodes []*types.Node
+	for _, h := range nodeCache {
+		if !h.usable(now) {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(h.NodeId)
+		if err != nil || len(raw) != len(types.Node{}.NodeId) {
+			continue
+		}
+		n := &types.Node{PeerId: h.PeerId}
+		copy(n.NodeId[:], raw)
+		nodes = append(nodes, n)
+	}
+	rankNodes(nodes, now)
+	return nodes
+}
+
+// RankNodes orders nodes by their cached health, best first. Nodes the cache
+// knows nothing about rank as the 0.5 prior.
+func RankNodes(nodes []*types.Node) {
+	loadNodeCache()
+	nodeCacheMu.Lock()
+	defer nodeCacheMu.Unlock()
+	rankNodes(nodes, time.Now())
+}
+
+func rankNodes(nodes []*types.Node, now time.Time) {
+	score := func(n *types.Node) float64 {
+		if h, ok := nodeCache[n.PeerId]; ok {
+			return h.Score(now)
+		}
+		return 0.5
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return score(nodes[i]) > score(nodes[j]) })
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/mod_client/config"
+	"github.com/libr-forum/Libr/core/mod_client/types"
+)
+
+// useTempCache points the cache dir at a fresh directory and forgets the
+// node cache loaded so far.
+func useTempCache(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	resetNodeCache()
+}
+
+func resetNodeCache() {
+	nodeCache = nil
+	nodeCacheOnce = sync.Once{}
+}
+
+func testNode(i int) *types.Node {
+	return &types.Node{NodeId: keyspace.Sum(fmt.Sprintf("node-%d", i)), PeerId: fmt.Sprintf("peer-%d", i)}
+}
+
+func TestScore(t *testing.T) {
+	now := time.Now()
+
+	fresh := &NodeHealth{}
+	if got := fresh.Score(now); got != 0.5 {
+		t.Fatalf("node never heard from scores %v, want 0.5", got)
+	}
+
+	good := &NodeHealth{Successes: 8, Failures: 0, LastSeen: now.Unix()}
+	bad := &NodeHealth{Successes: 2, Failures: 6, LastSeen: now.Unix()}
+	if good.Score(now) <= bad.Score(now) {
+		t.Fatalf("reliable node scores %v, flaky one %v", good.Score(now), bad.Score(now))
+	}
+
+	later := now.Add(2 * config.NodeHealthHalfLife)
+	if got, want := good.Score(later), good.Score(now)/4; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("score after two half-lives is %v, want %v", got, want)
+	}
+}
+
+func TestRecordLookupEvictsFailingNodes(t *testing.T) {
+	useTempCache(t)
+	flaky, steady := testNode(1), testNode(2)
+
+	if err := RecordLookup([]*types.Node{flaky, steady}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < config.NodeMaxFailStreak-1; i++ {
+		if err := RecordLookup([]*types.Node{steady}, []*types.Node{flaky}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(CachedNodes()) != 2 {
+		t.Fatalf("node evicted before %d failures in a row", config.NodeMaxFailStreak)
+	}
+
+	// An answer resets the streak
+	if err := RecordLookup([]*types.Node{flaky}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < config.NodeMaxFailStreak; i++ {
+		if err := RecordLookup(nil, []*types.Node{flaky}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodes := CachedNodes()
+	if len(nodes) != 1 || nodes[0].PeerId != steady.PeerId {
+		t.Fatalf("cache holds %v, want only %s", nodes, steady.PeerId)
+	}
+}
+
+func TestRecordLookupEvictsStaleNodes(t *testing.T) {
+	useTempCache(t)
+	stale, fresh := testNode(1), testNode(2)
+
+	if err := RecordLookup([]*types.Node{stale, fresh}, nil); err != nil {
+		t.Fatal(err)
+	}
+	nodeCacheMu.Lock()
+	nodeCache[stale.PeerId].LastSeen = time.Now().Add(-config.NodeCacheTTL - time.Hour).Unix()
+	nodeCacheMu.Unlock()
+
+	if nodes := CachedNodes(); len(nodes) != 1 || nodes[0].PeerId != fresh.PeerId {
+		t.Fatalf("stale node listed: %v", nodes)
+	}
+	if err := RecordLookup(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nodeCache[stale.PeerId]; ok {
+		t.Fatal("stale node kept after the next lookup")
+	}
+}
+
+func TestRecordLookupCapsSize(t *testing.T) {
+	useTempCache(t)
+
+	var weak, strong []*types.Node
+	for i := 0; i < 10; i++ {
+		weak = append(weak, testNode(i))
+	}
+	for i := 10; i < 10+config.NodeCacheSize; i++ {
+		strong = append(strong, testNode(i))
+	}
+
+	// The weak nodes answered once and then failed, so they go first
+	if err := RecordLookup(weak, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordLookup(nil, weak); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordLookup(strong, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodeCache) != config.NodeCacheSize {
+		t.Fatalf("cache holds %d nodes, want %d", len(nodeCache), config.NodeCacheSize)
+	}
+	for _, n := range weak {
+		if _, ok := nodeCache[n.PeerId]; ok {
+			t.Fatalf("%s kept over nodes that never failed", n.PeerId)
+		}
+	}
+}
+
+func TestNodeCachePersists(t *testing.T) {
+	useTempCache(t)
+	best, worse := testNode(1), testNode(2)
+
+	if err := RecordLookup([]*types.Node{best, worse}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordLookup([]*types.Node{best}, []*types.Node{worse}); err != nil {
+		t.Fatal(err)
+	}
+
+	resetNodeCache()
+	nodes := CachedNodes()
+	if len(nodes) != 2 || *nodes[0] != *best || *nodes[1] != *worse {
+		t.Fatalf("reloaded cache lists %v, want %s then %s", nodes, best.PeerId, worse.PeerId)
+	}
+
+	unknown := testNode(3)
+	ranked := []*types.Node{unknown, worse, best}
+	RankNodes(ranked)
+	if ranked[0] != best || ranked[1] != unknown || ranked[2] != worse {
+		t.Fatalf("ranked %s, %s, %s; want %s, %s, %s", ranked[0].PeerId, ranked[1].PeerId, ranked[2].PeerId, best.PeerId, unknown.PeerId, worse.PeerId)
+	}
+}
@@ -2,13 +2,14 @@ package config
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 
 	"github.com/joho/godotenv"
+	"github.com/libr-forum/Libr/core/crypto/schema"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -31,9 +32,12 @@ func InitDB() {
 		log.Fatalf("Failed to connect to SQLite DB: %v", err)
 	}
 
-	err = createTables()
+	err = schema.Migrate(DB, schema.Question, Migrations)
+	if errors.Is(err, schema.ErrTooNew) {
+		log.Fatalf("Refusing to start: %v. Upgrade libr to open this moderator database.", err)
+	}
 	if err != nil {
-		log.Fatalf("Failed to create tables: %v", err)
+		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	log.Println("SQLite database initialized successfully.")
@@ -58,22 +62,3 @@ func getDBPath() string {
 	dbPath := filepath.Join(baseDir, "libr", "moddb", "mod.db")
 	return dbPath
 }
-
-func createTables() error {
-	createMsgModTable := `
-	CREATE TABLE IF NOT EXISTS msgresult (
-		sign TEXT PRIMARY KEY,
-		content TEXT NOT NULL,
-		reason TEXT,
-		moderated INTEGER,
-		modsign TEXT
-	);
-	CREATE INDEX IF NOT EXISTS indx_sign ON msgresult(sign);`
-
-	_, err := DB.Exec(createMsgModTable)
-	if err != nil {
-		return fmt.Errorf("creating msgmod table: %w", err)
-	}
-
-	return nil
-}
@@ -0,0 +1,20 @@
+package config
+
+import "github.com/libr-forum/Libr/core/crypto/schema"
+
+// Migrations is the schema history of mod.db. Append only.
+var Migrations = []schema.Migration{
+	{
+		Version:     1,
+		Description: "msgresult table",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS msgresult (
+		sign TEXT PRIMARY KEY,
+		content TEXT NOT NULL,
+		reason TEXT,
+		moderated INTEGER,
+		modsign TEXT
+	);
+	CREATE INDEX IF NOT EXISTS indx_sign ON msgresult(sign);`,
+	},
+}
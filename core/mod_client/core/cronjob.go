@@ -70,9 +70,6 @@ func RetryPendingModerations() {
 		return
 	}
 
-	// Get latest mod address book
-	allMods, _ := util.GetOnlineMods() // returns []types.Mod with IP, Port, PublicKey, etc.
-
 	for _, filePath := range files {
 		pending, err := cache.LoadPendingModeration(filePath)
 		if err != nil {
@@ -81,7 +78,9 @@ func RetryPendingModerations() {
 			continue
 		}
 
-		// Match AwaitingMods to latest IP/Port from OnlineMods
+		// Match AwaitingMods to the members of the message's epoch the
+		// directory lists a peer for
+		allMods, _, _ := util.GetModsAt(pending.MsgCert.Msg.Ts)
 		var retryMods []types.Mod
 		for _, pubKey := range pending.AwaitingMods {
 			for _, mod := range allMods {
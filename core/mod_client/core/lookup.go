@@ -0,0 +1,53 @@
+package core
+
+import (
+	"log"
+
+	"github.com/libr-forum/Libr/core/crypto/lookup"
+	cache "github.com/libr-forum/Libr/core/mod_client/cache_handler"
+	"github.com/libr-forum/Libr/core/mod_client/config"
+	"github.com/libr-forum/Libr/core/mod_client/types"
+)
+
+func lookupConfig() lookup.Config {
+	return lookup.Config{Alpha: config.Alpha, K: config.K}
+}
+
+func toContacts(nodes []*types.Node) []lookup.Contact {
+	contacts := make([]lookup.Contact, 0, len(nodes))
+	for _, n := range nodes {
+		if n != nil {
+			contacts = append(contacts, lookup.Contact{NodeID: n.NodeId, PeerID: n.PeerId})
+		}
+	}
+	return contacts
+}
+
+// nodeContacts converts the node lists db nodes send in redirect and
+// stored responses.
+func nodeContacts(nodes []types.Node) []lookup.Contact {
+	contacts := make([]lookup.Contact, 0, len(nodes))
+	for _, n := range nodes {
+		contacts = append(contacts, lookup.Contact{NodeID: n.NodeId, PeerID: n.PeerId})
+	}
+	return contacts
+}
+
+func contactNodes(contacts []lookup.Contact) []*types.Node {
+	nodes := make([]*types.Node, 0, len(contacts))
+	for _, c := range contacts {
+		nodes = append(nodes, &types.Node{NodeId: c.NodeID, PeerId: c.PeerID})
+	}
+	return nodes
+}
+
+// recordHealth feeds who answered and who failed in res to the node cache.
+func recordHealth(res *lookup.Result) {
+	failed := make([]lookup.Contact, 0, len(res.Failed))
+	for _, f := range res.Failed {
+		failed = append(failed, f.Contact)
+	}
+	if err := cache.RecordLookup(contactNodes(res.Responded), contactNodes(failed)); err != nil {
+		log.Println("Failed to save node cache:", err)
+	}
+}
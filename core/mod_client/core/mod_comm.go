@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strconv"
 	"sync"
@@ -135,11 +136,16 @@ func AutoSendToMods(message string, ts int64) ([]types.ModCert, error) {
 		Ts:      ts,
 	}
 
-	onlineMods, err := util.GetOnlineMods()
+	// Only members of the epoch active at ts count, and thresholds are taken
+	// over the whole set, reachable or not, as db nodes do
+	onlineMods, set, err := util.GetModsAt(ts)
+	if set == nil {
+		return nil, fmt.Errorf("no moderator set for %d: %w", ts, err)
+	}
 	if err != nil {
-		log.Fatalf("failed to get online mods: %v", err)
+		log.Printf("failed to get online mods: %v", err)
 	}
-	noOfMods := len(onlineMods)
+	noOfMods := len(set.Members)
 
 	var (
 		totalMods   = noOfMods
@@ -1,7 +1,6 @@
 package core
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,154 +10,139 @@ import (
 	"time"
 
 	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+	"github.com/libr-forum/Libr/core/crypto/lookup"
+	cache "github.com/libr-forum/Libr/core/mod_client/cache_handler"
 	"github.com/libr-forum/Libr/core/mod_client/config"
 	"github.com/libr-forum/Libr/core/mod_client/network"
 	"github.com/libr-forum/Libr/core/mod_client/types"
 	util "github.com/libr-forum/Libr/core/mod_client/util"
 )
 
+// findValuePageSize is the page size Fetch asks db nodes for.
+const findValuePageSize = 20
+
+// findRangePageSize is the page size FetchRange asks db nodes for, their
+// cap on a page.
+const findRangePageSize = 50
+
 func Fetch(ts int64) []types.RetMsgCert {
+	return FetchDisjoint(ts, 1).Certs
+}
+
+// FetchDisjoint is Fetch over paths disjoint lookups, which no db node takes
+// part in more than once. A few bad nodes can then only hide a message from
+// the paths they sit on, and the result counts the paths that returned each
+// cert.
+func FetchDisjoint(ts int64, paths int) types.FetchResult {
 	key := strconv.FormatInt(ts, 10)
 	keyBytes := util.GenerateNodeID(key)
 
-	startNodes, _ := util.GetStartNodes()
-	known := append([]*types.Node{}, startNodes...)
-	queried := make(map[string]bool)
+	startNodes, _ := getStartNodesWithFallback()
 
 	var allCerts []types.RetMsgCert
+	copies := make(map[string]int)
 	deleteCount := make(map[string]int)
+	certsFrom := make(map[string][]string) // peer ID -> signs it returned
 	mu := sync.Mutex{}
 
-	const maxRounds = 50
-	const alpha = 3
-	const k = config.K
-	const deleteThreshold = 2
-
-	for round := 0; round < maxRounds; round++ {
-		sort.Slice(known, func(i, j int) bool {
-			return util.XORBigInt(keyBytes, known[i].NodeId).Cmp(util.XORBigInt(keyBytes, known[j].NodeId)) < 0
-		})
-
-		currentClosest := []*types.Node{}
-		for _, n := range known {
-			if len(currentClosest) >= k {
-				break
-			}
-			currentClosest = append(currentClosest, n)
+	query := func(ctx context.Context, c lookup.Contact) (lookup.Reply, error) {
+		rawResp, err := network.GetFrom(c.PeerID, fmt.Sprintf("/route=find_value&&ts=%d&&limit=%d", ts, findValuePageSize), key)
+		if err != nil {
+			return lookup.Reply{}, err
 		}
-
-		toQuery := []*types.Node{}
-		for _, n := range currentClosest {
-			key := n.PeerId
-			if !queried[key] {
-				toQuery = append(toQuery, n)
-				queried[key] = true
-				if len(toQuery) >= alpha {
-					break
-				}
-			}
+		respBytes, ok := rawResp.([]byte)
+		if !ok {
+			return lookup.Reply{}, fmt.Errorf("unexpected response format from %s", c.PeerID)
 		}
-
-		if len(toQuery) == 0 {
-			break
+		var base BaseResponse
+		if err := json.Unmarshal(respBytes, &base); err != nil {
+			return lookup.Reply{}, err
 		}
 
-		var wg sync.WaitGroup
-		newNodes := []*types.Node{}
-
-		for _, n := range toQuery {
-			wg.Add(1)
-			go func(n *types.Node) {
-				defer wg.Done()
-				rawResp, err := network.GetFrom(n.PeerId, fmt.Sprintf("/route=find_value&&ts=%d", ts), key)
-				if err != nil {
-					return
-				}
-				respBytes, ok := rawResp.([]byte)
-				if !ok {
-					return
+		switch base.Type {
+		case "found":
+			next := ""
+			for page := respBytes; ctx.Err() == nil; {
+				var val struct {
+					Type   string             `json:"type"`
+					Values []types.RetMsgCert `json:"values"`
+					Next   string             `json:"next"`
 				}
-				fmt.Println("Received response from:", n.PeerId)
-				var base BaseResponse
-				if err := json.Unmarshal(respBytes, &base); err != nil {
-					return
+				if err := json.Unmarshal(page, &val); err != nil || val.Type != "found" {
+					break
 				}
 
-				switch base.Type {
-				case "found":
-					var val struct {
-						Type   string             `json:"type"`
-						Values []types.RetMsgCert `json:"values"`
-					}
-					if err := json.Unmarshal(respBytes, &val); err != nil {
-						return
+				for _, cert := range val.Values {
+					if cert.Sign == "" {
+						continue
 					}
 
-					for _, cert := range val.Values {
-						if cert.Sign == "" {
-							continue
-						}
-
-						sort.SliceStable(cert.ModCerts, func(i, j int) bool {
-							return cert.ModCerts[i].PublicKey < cert.ModCerts[j].PublicKey
-						})
-
-						dataToSign := types.DataToSign{
-							Content:   cert.Msg.Content,
-							Timestamp: cert.Msg.Ts,
-							ModCerts:  cert.ModCerts,
+					if verifyRetMsgCert(&cert) {
+						mu.Lock()
+						allCerts = append(allCerts, cert)
+						certsFrom[c.PeerID] = append(certsFrom[c.PeerID], cert.Sign)
+						copies[cert.Sign]++
+						if cert.Deleted == "1" {
+							deleteCount[cert.Sign]++
 						}
-						jsonBytes, _ := json.Marshal(dataToSign)
-
-						if cryptoutils.VerifySignature(cert.PublicKey, string(jsonBytes), cert.Sign) {
-							mu.Lock()
-							allCerts = append(allCerts, cert)
-							if cert.Deleted == "1" {
-								deleteCount[cert.Sign]++
-							}
-							mu.Unlock()
-						}
-					}
-
-				case "redirect":
-					var redir struct {
-						Type  string       `json:"type"`
-						Nodes []types.Node `json:"nodes"`
-					}
-					if err := json.Unmarshal(respBytes, &redir); err != nil {
-						return
+						mu.Unlock()
 					}
+				}
 
-					mu.Lock()
-					for _, node := range redir.Nodes {
-						exists := false
-						for _, kn := range known {
-							if bytes.Equal(kn.NodeId[:], node.NodeId[:]) {
-								exists = true
-								break
-							}
-						}
-						if !exists {
-							copy := node
-							newNodes = append(newNodes, &copy)
-						}
-					}
-					mu.Unlock()
+				// Follow the cursor until the bucket is exhausted
+				if val.Next == "" || val.Next == next {
+					break
 				}
-			}(n)
+				next = val.Next
+				rawPage, err := network.GetFrom(c.PeerID, fmt.Sprintf("/route=find_value&&ts=%d&&limit=%d&&cursor=%s", ts, findValuePageSize, next), key)
+				if err != nil {
+					break
+				}
+				if page, ok = rawPage.([]byte); !ok {
+					break
+				}
+			}
+			return lookup.Reply{Found: true}, nil
+
+		case "redirect":
+			var redir RedirectResponse
+			if err := json.Unmarshal(respBytes, &redir); err != nil {
+				return lookup.Reply{}, err
+			}
+			return lookup.Reply{Closer: nodeContacts(redir.Nodes)}, nil
+
+		default:
+			return lookup.Reply{}, fmt.Errorf("unexpected response type %q from %s", base.Type, c.PeerID)
 		}
-		wg.Wait()
+	}
 
-		if len(newNodes) == 0 {
-			break
+	dres := lookup.FindValueDisjoint(context.Background(), lookupConfig(), paths, keyBytes, toContacts(startNodes), query)
+	res := dres.Merged
+	recordHealth(res)
+	fmt.Printf("[Fetch] %d: %d replicas answered, %d failed, %d rounds, %d/%d paths reached a replica\n",
+		ts, len(res.Found), len(res.Failed), res.Rounds, dres.Reached, len(dres.Paths))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	pathsPerCert := make(map[string]int)
+	for _, path := range dres.Paths {
+		seen := make(map[string]bool)
+		for _, c := range path.Found {
+			for _, sign := range certsFrom[c.PeerID] {
+				if !seen[sign] {
+					seen[sign] = true
+					pathsPerCert[sign]++
+				}
+			}
 		}
-		known = append(known, newNodes...)
 	}
 
-	// Filter certs: keep only one per Sign, if Deleted == "0" and delete count ≤ threshold
+	// Keep one live copy per sign of every cert the replicas did not delete
 	unique := make(map[string]types.RetMsgCert)
 	for _, cert := range allCerts {
-		if cert.Deleted == "0" && deleteCount[cert.Sign] <= deleteThreshold {
+		if cert.Deleted == "0" && !deletedByReplicas(deleteCount[cert.Sign], copies[cert.Sign]) {
 			if _, exists := unique[cert.Sign]; !exists {
 				unique[cert.Sign] = cert
 			}
@@ -173,83 +157,221 @@ func Fetch(ts int64) []types.RetMsgCert {
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Msg.Ts > results[j].Msg.Ts
 	})
-	return results
+
+	for sign := range pathsPerCert {
+		if _, ok := unique[sign]; !ok {
+			delete(pathsPerCert, sign)
+		}
+	}
+	return types.FetchResult{
+		Certs:        results,
+		Paths:        len(dres.Paths),
+		Reached:      dres.Reached,
+		PathsPerCert: pathsPerCert,
+	}
+}
+
+// deletedByReplicas reports whether deleted of the total copies of a cert
+// are enough to drop it: more than config.DeleteThreshold of them.
+func deletedByReplicas(deleted, total int) bool {
+	return total > 0 && float64(deleted) > config.DeleteThreshold*float64(total)
 }
 
 func FetchRecent(ctx context.Context) []types.RetMsgCert {
-	deleteThreshold := config.DeleteThreshold
 	now := time.Now().Truncate(time.Minute).Unix()
 	start := now - 3600
 
-	tsChan := make(chan int64, 100)
 	rawCerts := []types.RetMsgCert{}
-	printed := sync.Map{}
-	var mu sync.Mutex
+	printed := make(map[string]bool)
 
 	signCounts := make(map[string]int)
 	deleteCounts := make(map[string]int)
 
-	go func() {
-		for ts := now; ts >= start; ts -= 60 {
-			select {
-			case <-ctx.Done():
-				close(tsChan)
-				return
-			case tsChan <- ts:
+	for _, cert := range FetchRange(ctx, start, now) {
+		if cert.Sign == "" || cert.Msg.Ts < start || cert.Msg.Ts > now {
+			continue
+		}
+		signCounts[cert.Sign]++
+		if cert.Deleted == "1" {
+			deleteCounts[cert.Sign]++
+		}
+		if key := cert.Sign + "#" + fmt.Sprint(cert.Msg.Ts); cert.Deleted == "0" && !printed[key] {
+			printed[key] = true
+			rawCerts = append(rawCerts, cert)
+		}
+	}
+
+	filtered := []types.RetMsgCert{}
+	for _, cert := range rawCerts {
+		if !deletedByReplicas(deleteCounts[cert.Sign], signCounts[cert.Sign]) {
+			filtered = append(filtered, cert)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].Msg.Ts > filtered[j].Msg.Ts
+	})
+	fmt.Printf("[FetchRecent] collected: %d certs after filtering\n", len(filtered))
+	return filtered
+}
+
+// FetchRange collects the certs of every minute in [from, to] with
+// find_range. Each round queries, in parallel, the not yet queried nodes
+// among the k closest known for any minute in the span, and every answer
+// extends the candidate set for all minutes at once. The result holds one
+// entry per cert per replica that returned it.
+func FetchRange(ctx context.Context, from, to int64) []types.RetMsgCert {
+	from = from - from%60
+	to = to - to%60
+
+	var all []types.RetMsgCert
+	for chunkStart := from; chunkStart <= to; chunkStart += config.MaxRangeMinutes * 60 {
+		chunkEnd := chunkStart + (config.MaxRangeMinutes-1)*60
+		if chunkEnd > to {
+			chunkEnd = to
+		}
+		all = append(all, fetchRangeChunk(ctx, chunkStart, chunkEnd)...)
+	}
+	return all
+}
+
+func fetchRangeChunk(ctx context.Context, from, to int64) []types.RetMsgCert {
+	var keys []keyspace.ID
+	for minute := from; minute <= to; minute += 60 {
+		keys = append(keys, util.GenerateNodeID(strconv.FormatInt(minute, 10)))
+	}
+
+	startNodes, _ := getStartNodesWithFallback()
+	known := make(map[keyspace.ID]*types.Node)
+	for _, n := range startNodes {
+		known[n.NodeId] = n
+	}
+	queried := make(map[string]bool)
+
+	var allCerts []types.RetMsgCert
+	var answered, failed []*types.Node
+	mu := sync.Mutex{}
+
+	const maxRounds = 10
+	const k = config.K
+	route := fmt.Sprintf("/route=find_range&&from=%d&&to=%d&&limit=%d", from, to, findRangePageSize)
+
+	for round := 0; round < maxRounds; round++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		nodes := make([]*types.Node, 0, len(known))
+		for _, n := range known {
+			nodes = append(nodes, n)
+		}
+
+		toQuery := []*types.Node{}
+		for _, key := range keys {
+			sort.Slice(nodes, func(i, j int) bool {
+				return keyspace.CompareDistance(key, nodes[i].NodeId, nodes[j].NodeId) < 0
+			})
+			for i := 0; i < len(nodes) && i < k; i++ {
+				if !queried[nodes[i].PeerId] {
+					queried[nodes[i].PeerId] = true
+					toQuery = append(toQuery, nodes[i])
+				}
 			}
 		}
-		close(tsChan)
-	}()
-
-	const workers = 30
-	var wg sync.WaitGroup
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for ts := range tsChan {
-				certs := Fetch(ts)
-				for _, cert := range certs {
-					if cert.Sign == "" || cert.Msg.Ts < start || cert.Msg.Ts > now {
-						continue
+
+		if len(toQuery) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		newNodes := []*types.Node{}
+
+		for _, n := range toQuery {
+			wg.Add(1)
+			go func(n *types.Node) {
+				defer wg.Done()
+				pageRoute, cursor := route, ""
+				for page := 0; ; page++ {
+					var val struct {
+						Type   string             `json:"type"`
+						Values []types.RetMsgCert `json:"values"`
+						Next   string             `json:"next"`
+						Nodes  []types.Node       `json:"nodes"`
 					}
+					rawResp, err := network.GetFrom(n.PeerId, pageRoute, "")
+					respBytes, ok := rawResp.([]byte)
+					if err != nil || !ok || json.Unmarshal(respBytes, &val) != nil || val.Type != "range" {
+						// A node that served the first page has answered;
+						// what it returned so far is kept
+						if page == 0 {
+							mu.Lock()
+							failed = append(failed, n)
+							mu.Unlock()
+						}
+						return
+					}
+
 					mu.Lock()
-					signCounts[cert.Sign]++
-					if cert.Deleted == "1" {
-						deleteCounts[cert.Sign]++
+					if page == 0 {
+						answered = append(answered, n)
+						for _, node := range val.Nodes {
+							peer := node
+							newNodes = append(newNodes, &peer)
+						}
 					}
-					if _, seen := printed.LoadOrStore(cert.Sign+"#"+fmt.Sprint(cert.Msg.Ts), true); !seen {
-						rawCerts = append(rawCerts, cert)
+					for _, cert := range val.Values {
+						if cert.Sign != "" && verifyRetMsgCert(&cert) {
+							allCerts = append(allCerts, cert)
+						}
 					}
 					mu.Unlock()
+
+					// Follow the cursor until the span is exhausted
+					if val.Next == "" || val.Next == cursor || ctx.Err() != nil {
+						return
+					}
+					cursor = val.Next
+					pageRoute = route + "&&cursor=" + cursor
 				}
+			}(n)
+		}
+		wg.Wait()
+
+		for _, n := range newNodes {
+			if _, exists := known[n.NodeId]; !exists {
+				known[n.NodeId] = n
 			}
-		}()
-		fmt.Println(rawCerts)
+		}
 	}
 
-	wg.Wait()
-
-	filtered := []types.RetMsgCert{}
-	for _, cert := range rawCerts {
-		mu.Lock()
-		delCount := deleteCounts[cert.Sign]
-		totalCount := signCounts[cert.Sign]
-		mu.Unlock()
+	fmt.Printf("[FetchRange] %d-%d: %d certs from %d nodes\n", from, to, len(allCerts), len(queried))
+	if err := cache.RecordLookup(answered, failed); err != nil {
+		fmt.Println("Failed to save node cache:", err)
+	}
+	return allCerts
+}
 
-		if totalCount == 0 {
-			continue
-		}
+// verifyRetMsgCert checks the sender's signature over the message and its
+// mod certs, and the mod certs against the moderator set active at the
+// message's timestamp. It sorts cert.ModCerts in place.
+func verifyRetMsgCert(cert *types.RetMsgCert) bool {
+	sort.SliceStable(cert.ModCerts, func(i, j int) bool {
+		return cert.ModCerts[i].PublicKey < cert.ModCerts[j].PublicKey
+	})
 
-		if float64(delCount)/float64(totalCount) <= deleteThreshold {
-			filtered = append(filtered, cert)
-		}
+	dataToSign := types.DataToSign{
+		Content:   cert.Msg.Content,
+		Timestamp: cert.Msg.Ts,
+		ModCerts:  cert.ModCerts,
 	}
+	jsonBytes, _ := json.Marshal(dataToSign)
 
-	sort.Slice(filtered, func(i, j int) bool {
-		return filtered[i].Msg.Ts > filtered[j].Msg.Ts
-	})
-	fmt.Printf("[FetchRecent] collected: %d certs after filtering\n", len(filtered))
-	fmt.Println(filtered)
-	return filtered
+	if !cryptoutils.VerifySignature(cert.PublicKey, string(jsonBytes), cert.Sign) {
+		return false
+	}
+	if err := util.VerifyModCerts(cert.Msg.Content, cert.Msg.Ts, cert.ModCerts); err != nil {
+		fmt.Println("Dropping cert with unverified mod certs:", err)
+		return false
+	}
+	return true
 }
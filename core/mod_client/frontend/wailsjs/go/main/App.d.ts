@@ -11,6 +11,8 @@ export function FetchAll():Promise<Array<types.RetMsgCert>>;
 
 export function FetchMessageReports():Promise<Array<models.MsgCert>>;
 
+export function FetchMinute(arg1:number):Promise<types.FetchResult>;
+
 export function FetchPubKey():Promise<string>;
 
 export function GenerateAlias(arg1:string):Promise<string>;
@@ -18,6 +18,10 @@ export function FetchMessageReports() {
   return window['go']['main']['App']['FetchMessageReports']();
 }
 
+export function FetchMinute(arg1) {
+  return window['go']['main']['App']['FetchMinute'](arg1);
+}
+
 export function FetchPubKey() {
   return window['go']['main']['App']['FetchPubKey']();
 }
@@ -105,6 +105,42 @@ export namespace models {
 
 export namespace types {
 	
+	export class FetchResult {
+	    certs: RetMsgCert[];
+	    paths: number;
+	    reached: number;
+	    paths_per_cert: Record<string, number>;
+	
+	    static createFrom(source: any = {}) {
+	        return new FetchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.certs = this.convertValues(source["certs"], RetMsgCert);
+	        this.paths = source["paths"];
+	        this.reached = source["reached"];
+	        this.paths_per_cert = source["paths_per_cert"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class ModCert {
 	    sign: string;
 	    public_key: string;
@@ -1,7 +1,6 @@
 package peer
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,7 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/libr-forum/Libr/core/crypto/framing"
+	"github.com/libr-forum/Libr/core/crypto/signedrpc"
 	"github.com/libr-forum/Libr/core/mod_client/internal/handlers"
+	"github.com/libr-forum/Libr/core/mod_client/keycache"
 )
 
 var Peer *ChatPeer
@@ -65,9 +67,9 @@ func GET(targetPeerID string, route string) ([]byte, error) { //"/ts=123&&id=123
 
 	GetResp, err := Peer.Send(ctx, targetPeerID, jsonReq, nil)
 	if err != nil {
-		fmt.Println("Error Sending trial get message")
+		fmt.Println("Error Sending trial get message:", err)
+		return nil, err
 	}
-	GetResp = bytes.TrimRight(GetResp, "\x00")
 	return GetResp, nil //this will be json bytes with resp encoded in form of resp from the server and can be used according to utility
 }
 
@@ -94,21 +96,28 @@ func POST(targetPeerID string, route string, body []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	// Db nodes only accept store and delete in an envelope signed by our key
+	if signedrpc.Mutating(reqparams["route"]) {
+		body, err = signedrpc.Seal(keycache.PrivKey, reqparams["route"], targetPeerID, body)
+		if err != nil {
+			fmt.Println("[DEBUG]Failed to sign request:", err)
+			return nil, err
+		}
+	}
+
 	GetResp, err := Peer.Send(timeoutCtx, targetPeerID, jsonReq, body)
 
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
+		if errors.Is(err, framing.ErrTimeout) {
 			fmt.Println("⏳ POST request timed out after 5s")
 		} else {
 			fmt.Println("❌ POST request failed:", err)
 		}
 		return nil, err
 	}
-	if bytes.Equal(GetResp, []byte("Target peer not found")) || GetResp == nil || len(GetResp) == 0 {
-		return nil, errors.New("ping failed: empty response")
+	if len(GetResp) == 0 {
+		return nil, errors.New("empty response")
 	}
-
-	GetResp = bytes.TrimRight(GetResp, "\x00")
 	return GetResp, nil
 }
 
@@ -2,13 +2,15 @@ package peer
 
 import (
 	"bufio"
-	"bytes"
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
+	"io"
 	"log"
 	"math/big"
 	"sort"
+	"sync"
 
 	"context"
 	"encoding/hex"
@@ -21,6 +23,7 @@ import (
 	"time"
 
 	"github.com/libp2p/go-libp2p"
+	libp2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -29,6 +32,8 @@ import (
 	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/client"
 	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
 	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
+	"github.com/libr-forum/Libr/core/crypto/framing"
+	"github.com/libr-forum/Libr/core/mod_client/keycache"
 	"github.com/libr-forum/Libr/core/mod_client/logger"
 	"github.com/multiformats/go-multiaddr"
 
@@ -39,7 +44,7 @@ import (
 	libp2ptls "github.com/libp2p/go-libp2p/p2p/security/tls"
 )
 
-const ChatProtocol = protocol.ID("/chat/1.0.0")
+const ChatProtocol = protocol.ID(framing.ProtocolID)
 
 var OwnPubIP string
 
@@ -48,10 +53,13 @@ type ChatPeer struct {
 	relayAddr multiaddr.Multiaddr
 	relayID   peer.ID
 	peers     map[peer.ID]string // peer ID to nickname mapping
+	mux       *framing.Mux
 }
 
 type reqFormat struct {
 	Type      string          `json:"type,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Version   int             `json:"version,omitempty"`
 	PeerID    string          `json:"peer_id,omitempty"`
 	ReqParams json.RawMessage `json:"reqparams,omitempty"`
 	Body      json.RawMessage `json:"body,omitempty"`
@@ -85,8 +93,17 @@ func NewChatPeer(relayMultiAddrList []string) (*ChatPeer, error) {
 		// Other TLS configurations like ClientAuth, InsecureSkipVerify, etc.
 	}
 
+	// The host runs under the signing key, so its peer ID embeds the key
+	// that signs its requests and receivers can tie the two together
+	identity, err := libp2pcrypto.UnmarshalEd25519PrivateKey(keycache.PrivKey)
+	if err != nil {
+		fmt.Println("[DEBUG] Failed to load host identity:", err)
+		return nil, err
+	}
+
 	fmt.Println("[DEBUG] Creating libp2p Host")
 	h, err := libp2p.New(
+		libp2p.Identity(identity),
 		libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0/ws"), // WebSocket
 		libp2p.Security(libp2ptls.ID, libp2ptls.New),
 		libp2p.ConnectionManager(connMgr),
@@ -185,6 +202,7 @@ func NewChatPeer(relayMultiAddrList []string) (*ChatPeer, error) {
 		relayID:   relayInfo.ID,
 		peers:     make(map[peer.ID]string),
 	}
+	cp.mux = framing.NewMux(cp.openRelayStream)
 
 	fmt.Println(h.ID().String())
 
@@ -258,7 +276,9 @@ func (cp *ChatPeer) Start(ctx context.Context) error {
 	if err != nil {
 		fmt.Println("[DEBUG]Error marshalling the req to be sent")
 	}
-	stream.Write([]byte(reqJson))
+	if err := framing.WriteMessage(stream, reqJson); err != nil {
+		fmt.Println("[DEBUG]Error writing register frame:", err)
+	}
 
 	time.Sleep(1 * time.Second)
 
@@ -289,98 +309,114 @@ func (cp *ChatPeer) handleChatStream(s network.Stream) {
 	fmt.Println("[DEBUG] Incoming chat stream from", s.Conn().RemotePeer())
 	defer s.Close()
 
+	// Requests on one stream are served concurrently; responses carry the
+	// request_id they answer and may go out in any order.
+	var writeMu sync.Mutex
+	respond := func(resp framing.Response) {
+		data, _ := json.Marshal(resp)
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := framing.WriteMessage(s, data); err != nil {
+			fmt.Println("[DEBUG]Error writing resp bytes to relay")
+		}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
 	reader := bufio.NewReader(s)
 	for {
-
-		line, err := reader.ReadBytes('\n')
+		msg, err := framing.ReadMessage(reader)
 		if err != nil {
-			fmt.Println("[DEBUG]Error reading the bytes from the stream")
+			if !errors.Is(err, io.EOF) {
+				fmt.Println("[DEBUG]Error reading request frame:", err)
+			}
+			return
 		}
-		line = bytes.TrimRight(line, "\n")
-		line = bytes.TrimRight(line, "\x00")
-		var reqStruct reqFormat
-		err = json.Unmarshal(line, &reqStruct)
-
-		fmt.Println("[DEBUG] Raw input:", string(line))
 
-		if err != nil {
+		var reqStruct reqFormat
+		if err := json.Unmarshal(msg, &reqStruct); err != nil {
 			fmt.Println("[DEBUG]Error unmarshalling to reqStruc")
+			respond(framing.Fail("", framing.StatusBadRequest, "malformed request"))
+			continue
 		}
 
-		var reqData map[string]interface{}
-		reqStruct.ReqParams = bytes.TrimRight(reqStruct.ReqParams, "\x00")
+		fmt.Println("[DEBUG] Raw input:", string(msg))
 
-		if err := json.Unmarshal(reqStruct.ReqParams, &reqData); err != nil {
-			fmt.Printf("[ERROR] Failed to unmarshal incoming request: %v\n", err)
-			return
-		}
+		wg.Add(1)
+		go func(reqStruct reqFormat) {
+			defer wg.Done()
+			respond(serveRequest(reqStruct))
+		}(reqStruct)
+	}
+}
 
-		fmt.Printf("[DEBUG]ReqData is : %+v \n", reqData)
+// serveRequest dispatches one request and wraps the handler's reply in a
+// response envelope.
+func serveRequest(reqStruct reqFormat) framing.Response {
+	if reqStruct.Version != framing.ProtocolVersion {
+		return framing.Fail(reqStruct.RequestID, framing.StatusUpgradeRequired,
+			fmt.Sprintf("protocol version %d not supported, want %d", reqStruct.Version, framing.ProtocolVersion))
+	}
 
-		if reqData["Method"] == "GET" {
-			resp := ServeGetReq(reqStruct.ReqParams)
-			resp = bytes.TrimRight(resp, "\x00")
-			_, err = s.Write(resp)
-			if err != nil {
-				fmt.Println("[DEBUG]Error writing resp bytes to relay")
-				return
-			}
-		}
+	var reqData map[string]interface{}
+	if err := json.Unmarshal(reqStruct.ReqParams, &reqData); err != nil {
+		fmt.Printf("[ERROR] Failed to unmarshal incoming request: %v\n", err)
+		return framing.Fail(reqStruct.RequestID, framing.StatusBadRequest, "malformed request params")
+	}
 
-		if reqData["Method"] == "POST" {
-			resp := ServePostReq(reqStruct.PeerID, reqStruct.ReqParams, reqStruct.Body) // have to set the new logic in serve post req now
-			resp = bytes.TrimRight(resp, "\x00")
-			_, err = s.Write(resp)
-			if err != nil {
-				fmt.Println("[DEBUG]Error writing resp bytes to relay")
-				return
-			}
-		}
+	fmt.Printf("[DEBUG]ReqData is : %+v \n", reqData)
+
+	var resp []byte
+	switch reqData["Method"] {
+	case "GET":
+		resp = ServeGetReq(reqStruct.ReqParams)
+	case "POST":
+		resp = ServePostReq(reqStruct.PeerID, reqStruct.ReqParams, reqStruct.Body) // have to set the new logic in serve post req now
+	default:
+		return framing.Fail(reqStruct.RequestID, framing.StatusBadRequest, "unknown method")
+	}
 
+	if resp == nil {
+		return framing.Fail(reqStruct.RequestID, framing.StatusNotFound, "no handler produced a response")
 	}
+	return framing.OK(reqStruct.RequestID, resp)
 }
 
+// Send forwards a request to targetPeerID through the relay and returns the
+// body of its response. Failures come back as typed errors: framing.ErrTimeout,
+// framing.ErrPeerUnreachable, framing.ErrStreamClosed or *framing.RemoteError.
 func (cp *ChatPeer) Send(ctx context.Context, targetPeerID string, jsonReq []byte, body []byte) ([]byte, error) {
-	//completeIP := TargetIP + ":" + targetPort
-
 	var req reqFormat
 	req.Type = "SendMsg"
-	//req.PeerID = completeIP
 	req.PeerID = targetPeerID
 	req.ReqParams = jsonReq
 	req.Body = body
-	stream, err := cp.Host.NewStream(ctx, cp.relayID, ChatProtocol)
-	if err != nil {
-		fmt.Println("[DEBUG]Error opneing a fetch ID stream to relay")
-		return nil, err
-	}
 
-	jsonReqRelay, err := json.Marshal(req)
+	return cp.roundTrip(ctx, req)
+}
 
+// roundTrip sends req over the shared relay stream and waits for the
+// response carrying its request ID.
+func (cp *ChatPeer) roundTrip(ctx context.Context, req reqFormat) ([]byte, error) {
+	req.RequestID = framing.NewRequestID()
+	req.Version = framing.ProtocolVersion
+	jsonReqRelay, err := json.Marshal(req)
 	if err != nil {
 		fmt.Println("[DEBUG]Error marshalling get req to be sent to relay")
 		return nil, err
 	}
+	return cp.mux.RoundTrip(ctx, req.RequestID, jsonReqRelay)
+}
 
-	stream.Write([]byte(jsonReqRelay))
-
-	fmt.Println("[DEBUG]Msg req sent to relay, waiting for ack")
-
-	reader := bufio.NewReader(stream)
-	// ack, err := reader.ReadString('\n')
-
-	// if err != nil {
-	// 	fmt.Println("[DEBUG]Error getting the acknowledgement")
-	// 	return nil, err
-	// }
-	// _ = ack //can be used if required
-
-	var resp = make([]byte, 1024*50)
-	reader.Read(resp)
-	resp = bytes.TrimRight(resp, "\x00")
-	defer stream.Close()
-
-	return resp, err
+// openRelayStream opens the stream to the relay that cp.mux multiplexes
+// requests over.
+func (cp *ChatPeer) openRelayStream(ctx context.Context) (framing.Stream, error) {
+	s, err := cp.Host.NewStream(ctx, cp.relayID, ChatProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening relay stream: %v", framing.ErrPeerUnreachable, err)
+	}
+	return s, nil
 }
 
 func (cp *ChatPeer) GetConnectedPeers() []peer.ID {
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/keyspace"
+)
 
 type Msg struct {
 	Content string `json:"content"`
@@ -39,8 +43,8 @@ type Mod struct {
 // }
 
 type Node struct {
-	NodeId [20]byte `json:"node_id"`
-	PeerId string   `json:"peer_id"`
+	NodeId keyspace.ID `json:"node_id"`
+	PeerId string      `json:"peer_id"`
 }
 
 type ReportMsg struct {
@@ -77,6 +81,18 @@ type RetMsgCert struct {
 	Deleted   string    `json:"deleted"`
 }
 
+// FetchResult is what a fetch over disjoint lookup paths returned.
+type FetchResult struct {
+	Certs []RetMsgCert `json:"certs"`
+	// Paths is the number of paths the lookup ran, Reached the number of
+	// them that reached a replica holding anything for the minute
+	Paths   int `json:"paths"`
+	Reached int `json:"reached"`
+	// PathsPerCert counts, per cert sign, the paths that returned the cert:
+	// this is how far the paths agree on each message
+	PathsPerCert map[string]int `json:"paths_per_cert"`
+}
+
 type PendingModeration struct {
 	MsgSign      string    `json:"msg_sign"`      // cert.Sign
 	MsgCert      MsgCert   `json:"msg_cert"`      // full original cert
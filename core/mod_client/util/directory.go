@@ -0,0 +1,128 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/directory"
+	"github.com/libr-forum/Libr/core/mod_client/types"
+)
+
+// Dir is the directory opened by SetupDirectory
+var Dir directory.Directory
+
+var errNoDirectory = errors.New("directory not set up")
+
+// SetupDirectory opens the directory at uri (see directory.Open)
+func SetupDirectory(uri string) error {
+	d, err := directory.Open(context.Background(), uri)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+
+	Dir = d
+	log.Printf("✅ Directory ready (%T)", d)
+	return nil
+}
+
+// CloseDirectory releases the directory's connection, if any
+func CloseDirectory() {
+	if Dir != nil {
+		if err := Dir.Close(); err != nil {
+			log.Println("⚠️ Error closing directory:", err)
+		} else {
+			log.Println("🛑 Directory closed")
+		}
+	}
+}
+
+// GetStartNodes fetches all known nodes from the directory
+func GetStartNodes() ([]*types.Node, error) {
+	if Dir == nil {
+		return nil, errNoDirectory
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entries, err := Dir.Nodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+
+	now := time.Now()
+	var nodeList []*types.Node
+	for _, e := range entries {
+		// Only signed presence records that have not expired
+		if err := e.Verify(now); err != nil {
+			log.Printf("Skipping node %s: %v", e.PeerID, err)
+			continue
+		}
+		nodeId, err := DecodeNodeID(e.NodeID)
+		if err != nil {
+			// Entries left over from the 160-bit keyspace no longer decode
+			log.Printf("Skipping node %s with invalid node_id: %v", e.PeerID, err)
+			continue
+		}
+		nodeList = append(nodeList, &types.Node{
+			NodeId: nodeId,
+			PeerId: e.PeerID,
+		})
+	}
+
+	return nodeList, nil
+}
+
+// GetOnlineMods returns the moderators of the current epoch the directory
+// lists a peer for
+func GetOnlineMods() ([]types.Mod, error) {
+	mods, _, err := GetModsAt(time.Now().Unix())
+	return mods, err
+}
+
+// GetListedMods fetches every moderator entry of the directory. The entries
+// only say where keys can be reached; GetModsAt keeps the ones that are
+// moderators.
+func GetListedMods() ([]types.Mod, error) {
+	fmt.Println("Fetching online mods from the directory...")
+	if Dir == nil {
+		return nil, errNoDirectory
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entries, err := Dir.Mods(ctx)
+	if err != nil {
+		fmt.Println("Error fetching mods:", err)
+		return nil, err
+	}
+
+	var mods []types.Mod
+	for _, e := range entries {
+		mods = append(mods, types.Mod{
+			PeerId:    e.PeerID,
+			PublicKey: e.PublicKey,
+		})
+	}
+
+	fmt.Println("✅ Mods fetched successfully")
+	return mods, nil
+}
+
+// GetRelayAddr fetches available relay multiaddresses from the directory
+func GetRelayAddr() ([]string, error) {
+	if Dir == nil {
+		return nil, errNoDirectory
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	relayList, err := Dir.Relays(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch relay addresses: %w", err)
+	}
+	return relayList, nil
+}
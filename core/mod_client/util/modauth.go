@@ -1,12 +1,12 @@
 package util
 
+import "time"
+
+// AmIMod reports whether myKey is a member of the current moderator set
 func AmIMod(myKey string) (bool, error) {
-	mods, _ := GetOnlineMods()
-	for _, mod := range mods {
-		if len(mod.PublicKey) > 0 && mod.PublicKey == myKey {
-			return true, nil
-		}
+	set, err := ModSetAt(time.Now().Unix())
+	if err != nil {
+		return false, err
 	}
-
-	return false, nil
+	return len(myKey) > 0 && set.Has(myKey), nil
 }
@@ -0,0 +1,171 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/libr-forum/Libr/core/crypto/cryptoutils"
+	"github.com/libr-forum/Libr/core/crypto/modset"
+	cache "github.com/libr-forum/Libr/core/mod_client/cache_handler"
+	"github.com/libr-forum/Libr/core/mod_client/config"
+	"github.com/libr-forum/Libr/core/mod_client/types"
+)
+
+const modSetCacheFileName = "mod_sets.json"
+
+// ModSets holds the verified moderator sets; see SetupModSets
+var ModSets *modset.Registry
+
+var errNoModSets = errors.New("moderator sets not set up")
+
+var (
+	modSetSyncMu   sync.Mutex
+	lastModSetSync time.Time
+)
+
+func modSetCachePath() string {
+	return filepath.Join(cache.GetCacheDir(), modSetCacheFileName)
+}
+
+// SetupModSets creates ModSets trusting rootKeys, loads the sets cached by
+// earlier runs and syncs with the directory. It fails when there is neither
+// a root key nor a cached set, since no mod cert could validate.
+func SetupModSets(rootKeys []string) error {
+	ModSets = modset.NewRegistry(rootKeys)
+	if err := ModSets.Load(modSetCachePath()); err != nil {
+		log.Println("⚠️ Some cached moderator sets were rejected:", err)
+	}
+	if err := ModSets.Anchored(); err != nil {
+		return fmt.Errorf("%w: set LIBR_MOD_ROOT_KEYS to the network's governance keys", err)
+	}
+	if err := SyncModSets(); err != nil {
+		log.Println("⚠️ Failed to sync moderator sets:", err)
+	}
+	if ModSets.Len() == 0 {
+		log.Println("❌ No moderator set known yet: messages cannot be sent or verified until one signed by a root key is published")
+	}
+	log.Printf("✅ %d moderator sets known", ModSets.Len())
+	return nil
+}
+
+// SyncModSets adds the sets the directory lists to ModSets and caches them
+// when any were new.
+func SyncModSets() error {
+	if ModSets == nil {
+		return errNoModSets
+	}
+	if Dir == nil {
+		return errNoDirectory
+	}
+
+	modSetSyncMu.Lock()
+	lastModSetSync = time.Now()
+	modSetSyncMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sets, err := Dir.ModSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch moderator sets: %w", err)
+	}
+	added, errs := ModSets.AddAll(sets)
+	for _, err := range errs {
+		log.Println("⚠️ Rejected moderator set:", err)
+	}
+	if added > 0 {
+		if err := os.MkdirAll(cache.GetCacheDir(), 0755); err != nil {
+			return err
+		}
+		if err := ModSets.Save(modSetCachePath()); err != nil {
+			return fmt.Errorf("failed to cache moderator sets: %w", err)
+		}
+	}
+	return nil
+}
+
+// ModSetAt returns the moderator set active at unix time ts. When none is
+// known it syncs with the directory, at most once per config.ModSetResync,
+// and asks again.
+func ModSetAt(ts int64) (*modset.Set, error) {
+	if ModSets == nil {
+		return nil, errNoModSets
+	}
+	set, err := ModSets.ActiveAt(ts)
+	if !errors.Is(err, modset.ErrNoActiveSet) {
+		return set, err
+	}
+
+	modSetSyncMu.Lock()
+	recent := time.Since(lastModSetSync) < config.ModSetResync
+	modSetSyncMu.Unlock()
+	if recent {
+		return nil, err
+	}
+	if err := SyncModSets(); err != nil {
+		log.Println("⚠️ Failed to sync moderator sets:", err)
+	}
+	return ModSets.ActiveAt(ts)
+}
+
+// GetModsAt returns the moderator set active at ts and the members the
+// directory lists a peer for.
+func GetModsAt(ts int64) ([]types.Mod, *modset.Set, error) {
+	set, err := ModSetAt(ts)
+	if err != nil {
+		return nil, nil, err
+	}
+	listed, err := GetListedMods()
+	if err != nil {
+		return nil, set, err
+	}
+
+	var mods []types.Mod
+	for _, mod := range listed {
+		if set.Has(mod.PublicKey) && mod.PeerId != "" {
+			mods = append(mods, mod)
+		}
+	}
+	return mods, set, nil
+}
+
+// VerifyModCerts checks the mod certs of a message sent at ts the way db
+// nodes do: every cert from a distinct member of the set active at ts, more
+// approvals than rejections, and approvals from more than 30% of the set.
+func VerifyModCerts(content string, ts int64, certs []types.ModCert) error {
+	set, err := ModSetAt(ts)
+	if err != nil {
+		return err
+	}
+
+	approvals, rejections := 0, 0
+	counted := make(map[string]bool)
+	for _, cert := range certs {
+		if !set.Has(cert.PublicKey) || counted[cert.PublicKey] {
+			return fmt.Errorf("unexpected mod cert from %s for epoch %d", cert.PublicKey, set.Epoch)
+		}
+		counted[cert.PublicKey] = true
+		if !cryptoutils.VerifySignature(cert.PublicKey, content+strconv.FormatInt(ts, 10)+cert.Status, cert.Sign) {
+			return fmt.Errorf("invalid mod cert signature from %s", cert.PublicKey)
+		}
+		switch cert.Status {
+		case "1":
+			approvals++
+		case "0":
+			rejections++
+		}
+	}
+
+	total := len(set.Members)
+	if rejections > total/2 || approvals <= rejections || float32(approvals)/float32(total) <= 0.3 {
+		return fmt.Errorf("not enough approvals from epoch %d: %d of %d", set.Epoch, approvals, total)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+pragma solidity ^0.8.20;
+
+/// @title LibrRegistry
+/// @notice Global configuration of a LIBR network: the moderator set of every
+/// epoch, the relay list and the protocol parameters. Only the governance
+/// address may change it, and every change emits an event db nodes and
+/// clients watch for.
+contract LibrRegistry {
+    struct ModSet {
+        uint64 activatesAt; // unix time
+        bytes32[] members; // ed25519 public keys
+    }
+
+    address public governance;
+
+    ModSet[] private modSets;
+    string[] private relayList;
+
+    uint32 private k;
+    uint32 private alpha;
+    // Moderation thresholds in basis points: approvals must exceed
+    // majorityBps of the mods that answered and minApprovalBps of the set.
+    uint16 private majorityBps;
+    uint16 private minApprovalBps;
+
+    event ModSetPublished(uint64 indexed epoch, uint64 activatesAt, bytes32[] members);
+    event RelaysUpdated(string[] relays);
+    event ParamsUpdated(uint32 k, uint32 alpha, uint16 majorityBps, uint16 minApprovalBps);
+    event GovernanceTransferred(address indexed previous, address indexed next);
+
+    modifier onlyGovernance() {
+        require(msg.sender == governance, "LibrRegistry: not governance");
+        _;
+    }
+
+    constructor(uint32 k_, uint32 alpha_, uint16 majorityBps_, uint16 minApprovalBps_) {
+        governance = msg.sender;
+        _setParams(k_, alpha_, majorityBps_, minApprovalBps_);
+    }
+
+    /// @notice Number of epochs published so far; epochs count from 0.
+    function epochCount() external view returns (uint64) {
+        return uint64(modSets.length);
+    }
+
+    function modSet(uint64 epoch) external view returns (uint64 activatesAt, bytes32[] memory members) {
+        require(epoch < modSets.length, "LibrRegistry: unknown epoch");
+        ModSet storage s = modSets[epoch];
+        return (s.activatesAt, s.members);
+    }
+
+    /// @notice Publishes the moderator set of the next epoch. Activation
+    /// times must grow with the epoch.
+    function publishModSet(uint64 activatesAt, bytes32[] calldata members)
+        external
+        onlyGovernance
+        returns (uint64 epoch)
+    {
+        require(members.length > 0, "LibrRegistry: empty moderator set");
+        if (modSets.length > 0) {
+            require(
+                activatesAt > modSets[modSets.length - 1].activatesAt,
+                "LibrRegistry: activation before previous epoch"
+            );
+        }
+
+        epoch = uint64(modSets.length);
+        ModSet storage s = modSets.push();
+        s.activatesAt = activatesAt;
+        s.members = members;
+        emit ModSetPublished(epoch, activatesAt, members);
+    }
+
+    function relays() external view returns (string[] memory) {
+        return relayList;
+    }
+
+    function setRelays(string[] calldata relays_) external onlyGovernance {
+        delete relayList;
+        for (uint256 i = 0; i < relays_.length; i++) {
+            relayList.push(relays_[i]);
+        }
+        emit RelaysUpdated(relays_);
+    }
+
+    function params()
+        external
+        view
+        returns (uint32, uint32, uint16, uint16)
+    {
+        return (k, alpha, majorityBps, minApprovalBps);
+    }
+
+    function setParams(uint32 k_, uint32 alpha_, uint16 majorityBps_, uint16 minApprovalBps_)
+        external
+        onlyGovernance
+    {
+        _setParams(k_, alpha_, majorityBps_, minApprovalBps_);
+    }
+
+    function transferGovernance(address next) external onlyGovernance {
+        require(next != address(0), "LibrRegistry: zero address");
+        emit GovernanceTransferred(governance, next);
+        governance = next;
+    }
+
+    function _setParams(uint32 k_, uint32 alpha_, uint16 majorityBps_, uint16 minApprovalBps_) private {
+        require(k_ > 0 && alpha_ > 0, "LibrRegistry: zero k or alpha");
+        require(majorityBps_ <= 10000 && minApprovalBps_ <= 10000, "LibrRegistry: threshold above 100%");
+        k = k_;
+        alpha = alpha_;
+        majorityBps = majorityBps_;
+        minApprovalBps = minApprovalBps_;
+        emit ParamsUpdated(k_, alpha_, majorityBps_, minApprovalBps_);
+    }
+}